@@ -0,0 +1,129 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// ClusterComputedNodeLabelPrefix is the prefix of the node labels the operator manages for
+	// ClusterDeviceConfigs with set-based node selectors.
+	ClusterComputedNodeLabelPrefix = "clusterdeviceconfig.example.com/"
+
+	// ClusterDeviceConfigOwnerLabel is set on the resources created for a ClusterDeviceConfig
+	// in the driver namespace, which are tracked through it instead of owner references.
+	ClusterDeviceConfigOwnerLabel = "example.com/cluster-device-config"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// ClusterDeviceConfig is the Schema for the clusterdeviceconfigs API. Its KMM Module is created
+// in the driver namespace configured for the operator.
+type ClusterDeviceConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceConfigSpec   `json:"spec,omitempty"`
+	Status DeviceConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterDeviceConfigList contains a list of ClusterDeviceConfig
+type ClusterDeviceConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDeviceConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDeviceConfig{}, &ClusterDeviceConfigList{})
+}
+
+var _ DeviceConfigObject = &ClusterDeviceConfig{}
+
+func (cdc *ClusterDeviceConfig) GetSpec() *DeviceConfigSpec {
+	return &cdc.Spec
+}
+
+func (cdc *ClusterDeviceConfig) GetStatus() *DeviceConfigStatus {
+	return &cdc.Status
+}
+
+func (cdc *ClusterDeviceConfig) GetNodeSelector() map[string]string {
+	return cdc.Spec.nodeSelector()
+}
+
+// GetLabelSelector returns the selector of the nodes targeted by the ClusterDeviceConfig,
+// combining NodeSelector and NodeLabelSelector.
+func (cdc *ClusterDeviceConfig) GetLabelSelector() (labels.Selector, error) {
+	return cdc.Spec.labelSelector()
+}
+
+// HasSetBasedSelector returns true if the ClusterDeviceConfig nodes cannot be selected by a
+// plain label map, e.g. the KMM Module selector.
+func (cdc *ClusterDeviceConfig) HasSetBasedSelector() bool {
+	return cdc.Spec.hasSetBasedSelector()
+}
+
+// GetComputedNodeLabel returns the key of the label the operator sets on the nodes matching a
+// set-based selector.
+func (cdc *ClusterDeviceConfig) GetComputedNodeLabel() string {
+	return ClusterComputedNodeLabelPrefix + TruncateLabelName(cdc.Name)
+}
+
+// GetModuleNodeSelector returns the label map used to select the ClusterDeviceConfig nodes
+// from the KMM Module. Set-based selectors are bridged through the computed node label, and
+// nodes are only selected once the firmware, if any, is installed and the replaced in-tree
+// kernel modules, if any, are unloaded.
+func (cdc *ClusterDeviceConfig) GetModuleNodeSelector() map[string]string {
+	return cdc.Spec.moduleNodeSelector(cdc.GetComputedNodeLabel())
+}
+
+// GetFirmwareNodeLabel returns the key of the label the operator sets on the nodes to the
+// version of the ClusterDeviceConfig firmware installed on them.
+func (cdc *ClusterDeviceConfig) GetFirmwareNodeLabel() string {
+	return FirmwareNodeLabelPrefix + cdc.GetComputedNodeLabel()
+}
+
+// GetInTreeNodeLabel returns the key of the label the operator sets on the nodes once the
+// in-tree kernel modules replaced by the ClusterDeviceConfig have been unloaded from them.
+func (cdc *ClusterDeviceConfig) GetInTreeNodeLabel() string {
+	return InTreeNodeLabelPrefix + cdc.GetComputedNodeLabel()
+}
+
+// GetNodeGroupLabel returns the key of the label the operator sets on the nodes to the node
+// group of the ClusterDeviceConfig they belong to.
+func (cdc *ClusterDeviceConfig) GetNodeGroupLabel() string {
+	return NodeGroupLabelPrefix + cdc.GetComputedNodeLabel()
+}
+
+// GetDriverIdentity returns the keys identifying the driver managed by the ClusterDeviceConfig,
+// i.e. its kernel module names and device IDs.
+func (cdc *ClusterDeviceConfig) GetDriverIdentity() []string {
+	return cdc.Spec.driverIdentity()
+}
+
+// GetOwnerLabelValue returns the value of the ClusterDeviceConfigOwnerLabel set on the
+// resources created for the ClusterDeviceConfig.
+func (cdc *ClusterDeviceConfig) GetOwnerLabelValue() string {
+	return TruncateLabelName(cdc.Name)
+}
@@ -17,15 +17,78 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
 	DeviceConfigDeletionFinalizer = "device-config-deletion-finalizer"
 
 	ExamplePCIVendorID = "1da3"
+
+	// ComputedNodeLabelPrefix is the prefix of the node labels the operator manages for
+	// DeviceConfigs with set-based node selectors.
+	ComputedNodeLabelPrefix = "deviceconfig.example.com/"
+	// ComputedNodeLabelValue is the value of the node labels the operator manages for
+	// DeviceConfigs with set-based node selectors.
+	ComputedNodeLabelValue = "true"
+
+	// ForceDeleteAnnotation skips the wait for the driver to be unloaded from the nodes when
+	// set to "true" on a deleted DeviceConfig.
+	ForceDeleteAnnotation = "example.com/force-delete"
+
+	// DeletionPolicyAnnotation is set on the driver resources left running by a deleted
+	// DeviceConfig to the deletion policy it was deleted with.
+	DeletionPolicyAnnotation = "example.com/deletion-policy"
+
+	// AdoptModuleAnnotation names an existing KMM Module, in the namespace of the Module of the
+	// DeviceConfig, which the DeviceConfig takes over instead of generating its own.
+	AdoptModuleAnnotation = "example.com/adopt-module"
+
+	// FirmwareNodeLabelPrefix is prepended to the computed node label key of a DeviceConfig to
+	// form the key of the node label set to the firmware version installed on the node.
+	FirmwareNodeLabelPrefix = "firmware."
+
+	// DefaultFirmwarePath is the node directory the firmware blobs are installed to by default.
+	DefaultFirmwarePath = "/lib/firmware"
+
+	// InTreeNodeLabelPrefix is prepended to the computed node label key of a DeviceConfig to
+	// form the key of the node label set once the in-tree kernel modules it replaces have been
+	// unloaded from the node.
+	InTreeNodeLabelPrefix = "intree."
+	// InTreeNodeLabelValue is the value of the in-tree node labels.
+	InTreeNodeLabelValue = "replaced"
+
+	// NodeGroupLabelPrefix is prepended to the computed node label key of a DeviceConfig to
+	// form the key of the node label set to the node group each of its nodes belongs to.
+	NodeGroupLabelPrefix = "nodegroup."
+	// DefaultNodeGroup is the value of the node group label of the nodes which belong to none
+	// of the node groups of their DeviceConfig. It cannot be used as a node group name.
+	DefaultNodeGroup = "default"
+)
+
+// DefaultPCIVendorID returns the PCI vendor of the devices selected by the DeviceConfigs without
+// a node selector. It returns ExamplePCIVendorID unless the operator replaces it on startup to
+// follow its configuration, so that every user of the selectors resolves the same vendor.
+var DefaultPCIVendorID = func() string {
+	return ExamplePCIVendorID
+}
+
+// Deletion policies of the driver of a deleted DeviceConfig.
+const (
+	// DeletionPolicyDelete removes the driver from the nodes.
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyOrphan leaves the driver running until a DeviceConfig of the same name
+	// adopts it.
+	DeletionPolicyOrphan = "Orphan"
+	// DeletionPolicyRetain leaves the driver running without reconciling it anymore.
+	DeletionPolicyRetain = "Retain"
 )
 
 // DeviceConfigSpec defines the desired state of DeviceConfig
@@ -39,12 +102,349 @@ type DeviceConfigSpec struct {
 	//+kubebuilder:validation:Optional
 	// NodeSelector specifies a selector for the DeviceConfig
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	//+kubebuilder:validation:Optional
+	// NodeLabelSelector specifies a set-based selector for the DeviceConfig, which must be
+	// matched by the selected nodes in addition to NodeSelector
+	NodeLabelSelector *metav1.LabelSelector `json:"nodeLabelSelector,omitempty"`
+	//+kubebuilder:validation:Optional
+	// ModuleName is the name of the kernel module loaded by modprobe
+	ModuleName string `json:"moduleName,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:MaxItems=16
+	//+listType=map
+	//+listMapKey=name
+	// KernelModules are the kernel modules loaded along with the ModuleName one, e.g. the RDMA
+	// or crypto companions of a core module. Every kernel module is loaded after the ones it
+	// depends on and unloaded before them
+	KernelModules []KernelModule `json:"kernelModules,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:MaxItems=16
+	//+kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9_-]+$`
+	// ReplaceInTree lists the in-tree kernel modules unloaded, in order, before the driver is
+	// loaded and blacklisted while the DeviceConfig exists. The ones which were loaded are
+	// loaded again once the DeviceConfig is deleted and its driver unloaded
+	ReplaceInTree []string `json:"replaceInTree,omitempty"`
+	//+kubebuilder:validation:Optional
+	// ModuleParameters is the list of parameters passed to modprobe when loading the kernel
+	// module, e.g. num_queues=8. It cannot be combined with KernelModules, since modprobe
+	// loads several kernel modules without parameters
+	ModuleParameters []string `json:"moduleParameters,omitempty"`
+	//+kubebuilder:validation:Optional
+	// DeviceIDs is the list of PCI device IDs (e.g. 1da3:0001) handled by the driver
+	DeviceIDs []string `json:"deviceIDs,omitempty"`
+	//+kubebuilder:validation:Optional
+	// Preflight enables the validation of the driver image of every selected node kernel, which
+	// blocks the rollout of a DriverVersion until all of its images are available
+	Preflight bool `json:"preflight,omitempty"`
+	//+kubebuilder:validation:Optional
+	// Alerts configures the alerts of the PrometheusRule generated for the DeviceConfig
+	Alerts *AlertsSpec `json:"alerts,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=KMM;Native
+	// Backend is the backend deploying the driver, i.e. KMM or a native DaemonSet rendered by the
+	// operator. Defaults to the backend configured on the operator
+	Backend string `json:"backend,omitempty"`
+	//+kubebuilder:validation:Optional
+	// Teardown configures the removal of the driver when the DeviceConfig is deleted
+	Teardown *TeardownSpec `json:"teardown,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=Delete;Orphan;Retain
+	// DeletionPolicy is what happens to the driver when the DeviceConfig is deleted: Delete
+	// removes it, Orphan leaves it running until a DeviceConfig of the same name adopts it and
+	// Retain leaves it running without reconciling it anymore. Defaults to Delete
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	//+kubebuilder:validation:Optional
+	// Firmware configures the firmware blobs installed on the nodes before the driver is loaded
+	Firmware *FirmwareSpec `json:"firmware,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:MaxItems=16
+	//+listType=map
+	//+listMapKey=name
+	// NodeGroups splits the selected nodes into groups loading the kernel module with their own
+	// parameters. A node belongs to the first group whose selector it matches, and the nodes
+	// matching none of them load the kernel module with ModuleParameters
+	NodeGroups []NodeGroup `json:"nodeGroups,omitempty"`
+	//+kubebuilder:validation:Optional
+	// ExtendedResource is the extended resource the device plugin advertises for the devices,
+	// which is compared with the allocatable resources of the nodes the driver is available on
+	ExtendedResource *ExtendedResourceSpec `json:"extendedResource,omitempty"`
+}
+
+// KernelModule is a kernel module loaded along with the ModuleName one
+type KernelModule struct {
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]+$`
+	// Name is the name of the kernel module loaded by modprobe
+	Name string `json:"name"`
+	//+kubebuilder:validation:Optional
+	// DependsOn lists the kernel modules which must be loaded before this one, i.e. ModuleName
+	// or other KernelModules. Defaults to ModuleName
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// NodeGroup is a subset of the selected nodes which loads the kernel module with its own
+// parameters
+type NodeGroup struct {
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:MaxLength=20
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// Name is the name of the node group, which suffixes the name of its KMM Module
+	Name string `json:"name"`
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:MinProperties=1
+	// NodeSelector selects the nodes of the group among the nodes selected by the DeviceConfig
+	NodeSelector map[string]string `json:"nodeSelector"`
+	//+kubebuilder:validation:Optional
+	// ModuleParameters overrides the parameters of ModuleParameters with the same name and adds
+	// the others
+	ModuleParameters []string `json:"moduleParameters,omitempty"`
+}
+
+// FirmwareSpec configures the firmware blobs installed on the selected nodes, which must be in
+// place before the kernel module is loaded
+type FirmwareSpec struct {
+	//+kubebuilder:validation:Required
+	// Image is the image shipping the firmware blobs in its /firmware directory
+	Image string `json:"image"`
+	//+kubebuilder:validation:Required
+	// Version is the firmware version, which must share its major version with DriverVersion
+	Version string `json:"version"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern=`^/`
+	// Path is the node directory the firmware blobs are copied to, defaults to /lib/firmware
+	Path string `json:"path,omitempty"`
+}
+
+// ExtendedResourceSpec describes the extended resource advertised by the device plugin for the
+// devices handled by the driver
+type ExtendedResourceSpec struct {
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?/[a-zA-Z0-9]([-a-zA-Z0-9_.]*[a-zA-Z0-9])?$`
+	// Name is the name of the extended resource, e.g. example.com/device
+	Name string `json:"name"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	// CountPerDevice is the number of extended resources advertised for every device, e.g. its
+	// number of virtual functions, defaults to 1
+	CountPerDevice *int32 `json:"countPerDevice,omitempty"`
+	//+kubebuilder:validation:Optional
+	// DeviceCountLabel is the node label set to the number of devices discovered on the node,
+	// e.g. by a NFD local feature. Nodes without it or with an invalid count are not verified,
+	// while every node is expected to have a single device when no label is set
+	DeviceCountLabel string `json:"deviceCountLabel,omitempty"`
+}
+
+// TeardownSpec configures the removal of the driver of a deleted DeviceConfig
+type TeardownSpec struct {
+	//+kubebuilder:validation:Optional
+	// Timeout is how long to wait for the driver to be unloaded from the nodes before the
+	// DeviceConfig is deleted anyway, defaults to 10m
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	//+kubebuilder:validation:Optional
+	// ConfirmUnload additionally waits for KMM to remove its module ready label from the nodes,
+	// confirming that the kernel module has been unloaded
+	ConfirmUnload bool `json:"confirmUnload,omitempty"`
+}
+
+// AlertsSpec configures the thresholds of the driver health alerts of a DeviceConfig
+type AlertsSpec struct {
+	//+kubebuilder:validation:Optional
+	// Disabled disables the PrometheusRule of the DeviceConfig
+	Disabled bool `json:"disabled,omitempty"`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=100
+	// UnavailableNodesPercent is the percentage of the desired nodes without an available
+	// driver above which an alert fires, defaults to 10
+	UnavailableNodesPercent *int32 `json:"unavailableNodesPercent,omitempty"`
+	//+kubebuilder:validation:Optional
+	// ReconcileFailingFor is how long the reconciliation must fail before an alert fires,
+	// defaults to 15m
+	ReconcileFailingFor *metav1.Duration `json:"reconcileFailingFor,omitempty"`
+	//+kubebuilder:validation:Optional
+	// RolloutStuckFor is how long a driver rollout may be in progress before an alert fires,
+	// defaults to 30m
+	RolloutStuckFor *metav1.Duration `json:"rolloutStuckFor,omitempty"`
+}
+
+// DeviceConfigConflict describes another DeviceConfig which manages the same driver on some of
+// the nodes selected by this DeviceConfig
+type DeviceConfigConflict struct {
+	// Namespace is the namespace of the conflicting DeviceConfig, empty for a ClusterDeviceConfig
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the conflicting DeviceConfig
+	Name string `json:"name"`
+	// Nodes is the list of nodes selected by both DeviceConfigs, truncated to a limited number of entries
+	Nodes []string `json:"nodes"`
+	// NodeCount is the total number of nodes selected by both DeviceConfigs
+	NodeCount int `json:"nodeCount"`
+}
+
+// NodeFailure describes a driver pod failing on a node
+type NodeFailure struct {
+	// Node is the name of the node the failing driver pod is scheduled on
+	Node string `json:"node"`
+	// KernelVersion is the kernel version of the node
+	KernelVersion string `json:"kernelVersion,omitempty"`
+	// Reason is the reason of the failure, e.g. ImagePullBackOff or CrashLoopBackOff
+	Reason string `json:"reason"`
+	// Message is a human readable description of the failure
+	Message string `json:"message,omitempty"`
+}
+
+// TeardownStatus reports the removal of the driver of a deleted DeviceConfig
+type TeardownStatus struct {
+	// RemainingNodes is the number of nodes the driver has not been unloaded from yet
+	RemainingNodes int `json:"remainingNodes"`
+	// Nodes is the list of the remaining nodes, truncated to a limited number of entries
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// ModuleDrift describes the changes of other field managers to the fields of the KMM Module
+// owned by the operator, which have been reverted
+type ModuleDrift struct {
+	// Fields is the list of changed fields
+	Fields []FieldDrift `json:"fields"`
+	// DetectedAt is the time the drift was detected and reverted
+	DetectedAt metav1.Time `json:"detectedAt"`
+}
+
+// FieldDrift describes a field owned by the operator which has been changed by another field
+// manager
+type FieldDrift struct {
+	// Field is the path of the field, e.g. .spec.selector.foo
+	Field string `json:"field"`
+	// Manager is the field manager which changed the field
+	Manager string `json:"manager,omitempty"`
+}
+
+// KernelPreflight describes the driver image validated for a kernel of the selected nodes
+type KernelPreflight struct {
+	// KernelVersion is the kernel version of the nodes
+	KernelVersion string `json:"kernelVersion"`
+	// Image is the driver image resolved for the kernel version
+	Image string `json:"image,omitempty"`
+	// Available is true if the driver image was found in its registry
+	Available bool `json:"available"`
+	// Message describes why the driver image is not available
+	Message string `json:"message,omitempty"`
+}
+
+// PreflightStatus describes the validation of the driver images of a DriverVersion
+type PreflightStatus struct {
+	// DriverImage is the validated driver image
+	DriverImage string `json:"driverImage"`
+	// DriverVersion is the validated driver version
+	DriverVersion string `json:"driverVersion"`
+	// Passed is true if the driver image is available for every kernel
+	Passed bool `json:"passed"`
+	// Kernels lists the validation results per kernel of the selected nodes
+	Kernels []KernelPreflight `json:"kernels,omitempty"`
+}
+
+// KernelCoverage describes the selected nodes running a kernel version
+type KernelCoverage struct {
+	// KernelVersion is the kernel version of the nodes
+	KernelVersion string `json:"kernelVersion"`
+	// Mapping is the regular expression of the kernel mapping matching the kernel version,
+	// empty if the kernel version is not supported
+	Mapping string `json:"mapping,omitempty"`
+	// Image is the driver image resolved for the kernel version
+	Image string `json:"image,omitempty"`
+	// NodeCount is the number of selected nodes running the kernel version
+	NodeCount int `json:"nodeCount"`
+}
+
+// NodeFirmware describes the firmware version installed on a node
+type NodeFirmware struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Version is the firmware version installed on the node
+	Version string `json:"version"`
+}
+
+// FirmwareStatus reports the firmware installed on the selected nodes
+type FirmwareStatus struct {
+	// Version is the desired firmware version
+	Version string `json:"version"`
+	// Nodes is the firmware version installed on each node
+	Nodes []NodeFirmware `json:"nodes,omitempty"`
+}
+
+// NodeInTreeModules reports the in-tree kernel modules replaced on a node
+type NodeInTreeModules struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Present lists the in-tree kernel modules which were loaded on the node before they were
+	// replaced
+	Present []string `json:"present,omitempty"`
+}
+
+// NodeResourceShortfall describes a node advertising fewer extended resources than devices
+// discovered on it
+type NodeResourceShortfall struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Reason is either DevicePluginNotRegistered, when the node does not advertise the extended
+	// resource at all, or InsufficientDevices
+	Reason string `json:"reason"`
+	// Expected is the number of extended resources expected from the devices discovered on the
+	// node
+	Expected int64 `json:"expected"`
+	// Allocatable is the number of extended resources allocatable on the node
+	Allocatable int64 `json:"allocatable"`
+}
+
+// NodeGroupStatus reports the rollout of the driver of a node group
+type NodeGroupStatus struct {
+	// Name is the name of the node group
+	Name string `json:"name"`
+	// Module is the name of the KMM Module of the node group
+	Module string `json:"module"`
+	// SelectedNodes is the number of nodes which belong to the node group
+	SelectedNodes int `json:"selectedNodes"`
+	// AvailableNodes is the number of nodes of the node group running an available driver
+	AvailableNodes int `json:"availableNodes"`
 }
 
 // DeviceConfigStatus defines the observed state of DeviceConfig
 type DeviceConfigStatus struct {
 	// Conditions is a list of conditions representing the DeviceConfig's current state.
 	Conditions []metav1.Condition `json:"conditions"`
+	// Conflicts is the list of DeviceConfigs conflicting with this DeviceConfig, truncated to a
+	// limited number of entries
+	Conflicts []DeviceConfigConflict `json:"conflicts,omitempty"`
+	// NodeFailures is the list of nodes with failing driver pods, truncated to a limited number
+	// of entries
+	NodeFailures []NodeFailure `json:"nodeFailures,omitempty"`
+	// FailedNodeCount is the total number of nodes with failing driver pods
+	FailedNodeCount int `json:"failedNodeCount,omitempty"`
+	// Preflight is the result of the latest driver image preflight validation
+	Preflight *PreflightStatus `json:"preflight,omitempty"`
+	// KernelCoverage is the breakdown of the selected nodes by kernel version
+	KernelCoverage []KernelCoverage `json:"kernelCoverage,omitempty"`
+	// UnmappedKernels lists the kernel versions of the selected nodes which are not matched by
+	// any kernel mapping, so that no driver is loaded on them
+	UnmappedKernels []string `json:"unmappedKernels,omitempty"`
+	// Backend is the backend the driver is currently deployed with
+	Backend string `json:"backend,omitempty"`
+	// ModuleDrift is the latest drift of the KMM Module from the fields rendered by the
+	// operator
+	ModuleDrift *ModuleDrift `json:"moduleDrift,omitempty"`
+	// Teardown is the progress of the removal of the driver once the DeviceConfig is deleted
+	Teardown *TeardownStatus `json:"teardown,omitempty"`
+	// Firmware reports the firmware installed on the selected nodes
+	Firmware *FirmwareStatus `json:"firmware,omitempty"`
+	// NodeGroups reports the rollout of the driver of every node group
+	NodeGroups []NodeGroupStatus `json:"nodeGroups,omitempty"`
+	// InTreeModules reports the in-tree kernel modules replaced on every node
+	InTreeModules []NodeInTreeModules `json:"inTreeModules,omitempty"`
+	// ResourceShortfalls is the list of nodes the driver is available on which advertise fewer
+	// extended resources than expected, truncated to a limited number of entries
+	ResourceShortfalls []NodeResourceShortfall `json:"resourceShortfalls,omitempty"`
+	// ResourceShortfallCount is the total number of nodes advertising fewer extended resources
+	// than expected
+	ResourceShortfallCount int `json:"resourceShortfallCount,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -72,13 +472,241 @@ func init() {
 	SchemeBuilder.Register(&DeviceConfig{}, &DeviceConfigList{})
 }
 
+// DeviceConfigObject is implemented by the DeviceConfig and ClusterDeviceConfig kinds, which
+// share their spec and status and are reconciled alike.
+// +kubebuilder:object:generate=false
+type DeviceConfigObject interface {
+	metav1.Object
+	runtime.Object
+
+	GetSpec() *DeviceConfigSpec
+	GetStatus() *DeviceConfigStatus
+	GetNodeSelector() map[string]string
+	GetLabelSelector() (labels.Selector, error)
+	HasSetBasedSelector() bool
+	GetComputedNodeLabel() string
+	GetModuleNodeSelector() map[string]string
+	GetFirmwareNodeLabel() string
+	GetInTreeNodeLabel() string
+	GetNodeGroupLabel() string
+	GetDriverIdentity() []string
+}
+
+var _ DeviceConfigObject = &DeviceConfig{}
+
+func (dc *DeviceConfig) GetSpec() *DeviceConfigSpec {
+	return &dc.Spec
+}
+
+func (dc *DeviceConfig) GetStatus() *DeviceConfigStatus {
+	return &dc.Status
+}
+
 func (dc *DeviceConfig) GetNodeSelector() map[string]string {
-	ns := dc.Spec.NodeSelector
+	return dc.Spec.nodeSelector()
+}
+
+// GetLabelSelector returns the selector of the nodes targeted by the DeviceConfig, combining
+// NodeSelector and NodeLabelSelector.
+func (dc *DeviceConfig) GetLabelSelector() (labels.Selector, error) {
+	return dc.Spec.labelSelector()
+}
+
+// HasSetBasedSelector returns true if the DeviceConfig nodes cannot be selected by a plain
+// label map, e.g. the KMM Module selector.
+func (dc *DeviceConfig) HasSetBasedSelector() bool {
+	return dc.Spec.hasSetBasedSelector()
+}
+
+// GetComputedNodeLabel returns the key of the label the operator sets on the nodes matching a
+// set-based selector.
+func (dc *DeviceConfig) GetComputedNodeLabel() string {
+	name := dc.Name
+	if dc.Namespace != "" {
+		name = fmt.Sprintf("%s.%s", dc.Namespace, dc.Name)
+	}
+	return ComputedNodeLabelPrefix + TruncateLabelName(name)
+}
+
+// GetModuleNodeSelector returns the label map used to select the DeviceConfig nodes from the
+// KMM Module. Set-based selectors are bridged through the computed node label, and nodes are
+// only selected once the firmware, if any, is installed and the replaced in-tree kernel
+// modules, if any, are unloaded.
+func (dc *DeviceConfig) GetModuleNodeSelector() map[string]string {
+	return dc.Spec.moduleNodeSelector(dc.GetComputedNodeLabel())
+}
+
+// GetFirmwareNodeLabel returns the key of the label the operator sets on the nodes to the
+// version of the DeviceConfig firmware installed on them.
+func (dc *DeviceConfig) GetFirmwareNodeLabel() string {
+	return FirmwareNodeLabelPrefix + dc.GetComputedNodeLabel()
+}
+
+// GetInTreeNodeLabel returns the key of the label the operator sets on the nodes once the
+// in-tree kernel modules replaced by the DeviceConfig have been unloaded from them.
+func (dc *DeviceConfig) GetInTreeNodeLabel() string {
+	return InTreeNodeLabelPrefix + dc.GetComputedNodeLabel()
+}
+
+// GetNodeGroupLabel returns the key of the label the operator sets on the nodes to the node
+// group of the DeviceConfig they belong to.
+func (dc *DeviceConfig) GetNodeGroupLabel() string {
+	return NodeGroupLabelPrefix + dc.GetComputedNodeLabel()
+}
+
+// GetDriverIdentity returns the keys identifying the driver managed by the DeviceConfig, i.e.
+// its kernel module names and device IDs. Two DeviceConfigs that share at least one key manage
+// the same driver and must not select the same nodes.
+func (dc *DeviceConfig) GetDriverIdentity() []string {
+	return dc.Spec.driverIdentity()
+}
+
+func (s *DeviceConfigSpec) nodeSelector() map[string]string {
+	ns := s.NodeSelector
 	if ns == nil {
 		ns = make(map[string]string, 0)
 		// If no DeviceConfig.NodeSelector is specified, let's try adding NFD labels, otherwise
 		// the daemonset would be deployed on every schedulable node.
-		ns[fmt.Sprintf("feature.node.kubernetes.io/pci-%s.present", ExamplePCIVendorID)] = "true"
+		ns[fmt.Sprintf("feature.node.kubernetes.io/pci-%s.present", DefaultPCIVendorID())] = "true"
+	}
+	return ns
+}
+
+func (s *DeviceConfigSpec) labelSelector() (labels.Selector, error) {
+	ls := &metav1.LabelSelector{}
+	if s.NodeLabelSelector != nil {
+		ls = s.NodeLabelSelector.DeepCopy()
+	}
+	// The default NFD NodeSelector only applies when no selector is specified at all.
+	if s.NodeSelector != nil || s.NodeLabelSelector == nil {
+		if ls.MatchLabels == nil {
+			ls.MatchLabels = make(map[string]string)
+		}
+		for k, v := range s.nodeSelector() {
+			ls.MatchLabels[k] = v
+		}
+	}
+	return metav1.LabelSelectorAsSelector(ls)
+}
+
+func (s *DeviceConfigSpec) hasSetBasedSelector() bool {
+	return s.NodeLabelSelector != nil && len(s.NodeLabelSelector.MatchExpressions) > 0
+}
+
+func (s *DeviceConfigSpec) moduleNodeSelector(computedNodeLabel string) map[string]string {
+	ns := make(map[string]string)
+	if s.Firmware != nil {
+		ns[FirmwareNodeLabelPrefix+computedNodeLabel] = s.Firmware.Version
+	}
+	if len(s.ReplaceInTree) > 0 {
+		ns[InTreeNodeLabelPrefix+computedNodeLabel] = InTreeNodeLabelValue
+	}
+
+	if s.hasSetBasedSelector() {
+		ns[computedNodeLabel] = ComputedNodeLabelValue
+		return ns
+	}
+
+	if s.NodeLabelSelector != nil {
+		for k, v := range s.NodeLabelSelector.MatchLabels {
+			ns[k] = v
+		}
+	}
+	if s.NodeSelector != nil || s.NodeLabelSelector == nil {
+		for k, v := range s.nodeSelector() {
+			ns[k] = v
+		}
 	}
 	return ns
 }
+
+func (s *DeviceConfigSpec) driverIdentity() []string {
+	identity := make([]string, 0, len(s.DeviceIDs)+len(s.KernelModules)+1)
+	if s.ModuleName != "" {
+		identity = append(identity, "module/"+s.ModuleName)
+	}
+	for _, km := range s.KernelModules {
+		identity = append(identity, "module/"+km.Name)
+	}
+	for _, id := range s.DeviceIDs {
+		identity = append(identity, "device/"+strings.ToLower(id))
+	}
+	return identity
+}
+
+// GetNodeGroup returns the name of the first node group whose selector matches the given node
+// labels, or DefaultNodeGroup.
+func (s *DeviceConfigSpec) GetNodeGroup(nodeLabels labels.Labels) string {
+	for _, g := range s.NodeGroups {
+		if labels.SelectorFromSet(g.NodeSelector).Matches(nodeLabels) {
+			return g.Name
+		}
+	}
+	return DefaultNodeGroup
+}
+
+// GetModuleParameters returns the modprobe parameters of the given node group, i.e.
+// ModuleParameters overridden by the parameters of the group. The parameters of the nodes
+// which belong to no group are returned for DefaultNodeGroup or an unknown group.
+func (s *DeviceConfigSpec) GetModuleParameters(group string) []string {
+	params := append([]string{}, s.ModuleParameters...)
+	for _, g := range s.NodeGroups {
+		if g.Name != group {
+			continue
+		}
+		for _, override := range g.ModuleParameters {
+			overridden := false
+			for i, p := range params {
+				if parameterName(p) == parameterName(override) {
+					params[i] = override
+					overridden = true
+				}
+			}
+			if !overridden {
+				params = append(params, override)
+			}
+		}
+	}
+	return params
+}
+
+// parameterName returns the name of a modprobe parameter formatted as name=value.
+func parameterName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	return name
+}
+
+// GetFirmwarePath returns the node directory the firmware blobs are copied to.
+func (s *FirmwareSpec) GetFirmwarePath() string {
+	if s.Path == "" {
+		return DefaultFirmwarePath
+	}
+	return s.Path
+}
+
+// GetCountPerDevice returns the number of extended resources advertised for every device,
+// defaulting to 1.
+func (s *ExtendedResourceSpec) GetCountPerDevice() int64 {
+	if s.CountPerDevice == nil {
+		return 1
+	}
+	return int64(*s.CountPerDevice)
+}
+
+// GetDeletionPolicy returns the deletion policy of the driver, defaulting to Delete.
+func (s *DeviceConfigSpec) GetDeletionPolicy() string {
+	if s.DeletionPolicy == "" {
+		return DeletionPolicyDelete
+	}
+	return s.DeletionPolicy
+}
+
+// TruncateLabelName keeps the given name within the 63 characters allowed for the name segment
+// of a label key and for label values, suffixing truncated names with a hash to keep them unique.
+func TruncateLabelName(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	return fmt.Sprintf("%s-%x", name[:validation.LabelValueMaxLength-17], sum[:8])
+}
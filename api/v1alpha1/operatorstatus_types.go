@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// OperatorStatusName is the name of the OperatorStatus singleton maintained by the operator.
+	OperatorStatusName = "he-sample-operator"
+
+	// KMMAvailableCondition reports whether the KMM Module CRD is served by the cluster.
+	KMMAvailableCondition = "KMMAvailable"
+)
+
+// OperatorStatusSpec defines the desired state of OperatorStatus. The OperatorStatus is
+// maintained by the operator and has no configuration.
+type OperatorStatusSpec struct {
+}
+
+// OperatorStatusStatus defines the observed state of the operator dependencies
+type OperatorStatusStatus struct {
+	// Conditions is a list of conditions representing the state of the operator dependencies.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="KMM Available",type=string,JSONPath=`.status.conditions[?(@.type=="KMMAvailable")].status`
+
+// OperatorStatus reports the state of the operator dependencies, e.g. whether KMM is installed.
+type OperatorStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorStatusSpec   `json:"spec,omitempty"`
+	Status OperatorStatusStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OperatorStatusList contains a list of OperatorStatus
+type OperatorStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorStatus{}, &OperatorStatusList{})
+}
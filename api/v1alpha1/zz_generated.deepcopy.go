@@ -23,9 +23,98 @@ package v1alpha1
 
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertsSpec) DeepCopyInto(out *AlertsSpec) {
+	*out = *in
+	if in.UnavailableNodesPercent != nil {
+		in, out := &in.UnavailableNodesPercent, &out.UnavailableNodesPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReconcileFailingFor != nil {
+		in, out := &in.ReconcileFailingFor, &out.ReconcileFailingFor
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RolloutStuckFor != nil {
+		in, out := &in.RolloutStuckFor, &out.RolloutStuckFor
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertsSpec.
+func (in *AlertsSpec) DeepCopy() *AlertsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDeviceConfig) DeepCopyInto(out *ClusterDeviceConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDeviceConfig.
+func (in *ClusterDeviceConfig) DeepCopy() *ClusterDeviceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDeviceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDeviceConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDeviceConfigList) DeepCopyInto(out *ClusterDeviceConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterDeviceConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDeviceConfigList.
+func (in *ClusterDeviceConfigList) DeepCopy() *ClusterDeviceConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDeviceConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDeviceConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceConfig) DeepCopyInto(out *DeviceConfig) {
 	*out = *in
@@ -53,6 +142,26 @@ func (in *DeviceConfig) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceConfigConflict) DeepCopyInto(out *DeviceConfigConflict) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigConflict.
+func (in *DeviceConfigConflict) DeepCopy() *DeviceConfigConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceConfigConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceConfigList) DeepCopyInto(out *DeviceConfigList) {
 	*out = *in
@@ -95,6 +204,60 @@ func (in *DeviceConfigSpec) DeepCopyInto(out *DeviceConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.NodeLabelSelector != nil {
+		in, out := &in.NodeLabelSelector, &out.NodeLabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KernelModules != nil {
+		in, out := &in.KernelModules, &out.KernelModules
+		*out = make([]KernelModule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReplaceInTree != nil {
+		in, out := &in.ReplaceInTree, &out.ReplaceInTree
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ModuleParameters != nil {
+		in, out := &in.ModuleParameters, &out.ModuleParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeviceIDs != nil {
+		in, out := &in.DeviceIDs, &out.DeviceIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = new(AlertsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = new(TeardownSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Firmware != nil {
+		in, out := &in.Firmware, &out.Firmware
+		*out = new(FirmwareSpec)
+		**out = **in
+	}
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]NodeGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtendedResource != nil {
+		in, out := &in.ExtendedResource, &out.ExtendedResource
+		*out = new(ExtendedResourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigSpec.
@@ -117,6 +280,65 @@ func (in *DeviceConfigStatus) DeepCopyInto(out *DeviceConfigStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]DeviceConfigConflict, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeFailures != nil {
+		in, out := &in.NodeFailures, &out.NodeFailures
+		*out = make([]NodeFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.Preflight != nil {
+		in, out := &in.Preflight, &out.Preflight
+		*out = new(PreflightStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KernelCoverage != nil {
+		in, out := &in.KernelCoverage, &out.KernelCoverage
+		*out = make([]KernelCoverage, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnmappedKernels != nil {
+		in, out := &in.UnmappedKernels, &out.UnmappedKernels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ModuleDrift != nil {
+		in, out := &in.ModuleDrift, &out.ModuleDrift
+		*out = new(ModuleDrift)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = new(TeardownStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Firmware != nil {
+		in, out := &in.Firmware, &out.Firmware
+		*out = new(FirmwareStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]NodeGroupStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.InTreeModules != nil {
+		in, out := &in.InTreeModules, &out.InTreeModules
+		*out = make([]NodeInTreeModules, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourceShortfalls != nil {
+		in, out := &in.ResourceShortfalls, &out.ResourceShortfalls
+		*out = make([]NodeResourceShortfall, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceConfigStatus.
@@ -128,3 +350,407 @@ func (in *DeviceConfigStatus) DeepCopy() *DeviceConfigStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtendedResourceSpec) DeepCopyInto(out *ExtendedResourceSpec) {
+	*out = *in
+	if in.CountPerDevice != nil {
+		in, out := &in.CountPerDevice, &out.CountPerDevice
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtendedResourceSpec.
+func (in *ExtendedResourceSpec) DeepCopy() *ExtendedResourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtendedResourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldDrift) DeepCopyInto(out *FieldDrift) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldDrift.
+func (in *FieldDrift) DeepCopy() *FieldDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareSpec) DeepCopyInto(out *FirmwareSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareSpec.
+func (in *FirmwareSpec) DeepCopy() *FirmwareSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareStatus) DeepCopyInto(out *FirmwareStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeFirmware, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareStatus.
+func (in *FirmwareStatus) DeepCopy() *FirmwareStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelCoverage) DeepCopyInto(out *KernelCoverage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelCoverage.
+func (in *KernelCoverage) DeepCopy() *KernelCoverage {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelCoverage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelModule) DeepCopyInto(out *KernelModule) {
+	*out = *in
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelModule.
+func (in *KernelModule) DeepCopy() *KernelModule {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelPreflight) DeepCopyInto(out *KernelPreflight) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelPreflight.
+func (in *KernelPreflight) DeepCopy() *KernelPreflight {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelPreflight)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModuleDrift) DeepCopyInto(out *ModuleDrift) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]FieldDrift, len(*in))
+		copy(*out, *in)
+	}
+	in.DetectedAt.DeepCopyInto(&out.DetectedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleDrift.
+func (in *ModuleDrift) DeepCopy() *ModuleDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFailure) DeepCopyInto(out *NodeFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFailure.
+func (in *NodeFailure) DeepCopy() *NodeFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFirmware) DeepCopyInto(out *NodeFirmware) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFirmware.
+func (in *NodeFirmware) DeepCopy() *NodeFirmware {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFirmware)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroup) DeepCopyInto(out *NodeGroup) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ModuleParameters != nil {
+		in, out := &in.ModuleParameters, &out.ModuleParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroup.
+func (in *NodeGroup) DeepCopy() *NodeGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupStatus.
+func (in *NodeGroupStatus) DeepCopy() *NodeGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeInTreeModules) DeepCopyInto(out *NodeInTreeModules) {
+	*out = *in
+	if in.Present != nil {
+		in, out := &in.Present, &out.Present
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeInTreeModules.
+func (in *NodeInTreeModules) DeepCopy() *NodeInTreeModules {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeInTreeModules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeResourceShortfall) DeepCopyInto(out *NodeResourceShortfall) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeResourceShortfall.
+func (in *NodeResourceShortfall) DeepCopy() *NodeResourceShortfall {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeResourceShortfall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatus) DeepCopyInto(out *OperatorStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatus.
+func (in *OperatorStatus) DeepCopy() *OperatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatusList) DeepCopyInto(out *OperatorStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatusList.
+func (in *OperatorStatusList) DeepCopy() *OperatorStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatusSpec) DeepCopyInto(out *OperatorStatusSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatusSpec.
+func (in *OperatorStatusSpec) DeepCopy() *OperatorStatusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatusStatus) DeepCopyInto(out *OperatorStatusStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatusStatus.
+func (in *OperatorStatusStatus) DeepCopy() *OperatorStatusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightStatus) DeepCopyInto(out *PreflightStatus) {
+	*out = *in
+	if in.Kernels != nil {
+		in, out := &in.Kernels, &out.Kernels
+		*out = make([]KernelPreflight, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightStatus.
+func (in *PreflightStatus) DeepCopy() *PreflightStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownSpec) DeepCopyInto(out *TeardownSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownSpec.
+func (in *TeardownSpec) DeepCopy() *TeardownSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownStatus) DeepCopyInto(out *TeardownStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownStatus.
+func (in *TeardownStatus) DeepCopy() *TeardownStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownStatus)
+	in.DeepCopyInto(out)
+	return out
+}
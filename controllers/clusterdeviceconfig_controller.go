@@ -0,0 +1,291 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/alerts"
+	"github.com/mresvanis/he-sample-operator/internal/backend"
+	"github.com/mresvanis/he-sample-operator/internal/conditions"
+	"github.com/mresvanis/he-sample-operator/internal/driverpods"
+	"github.com/mresvanis/he-sample-operator/internal/finalizers"
+	"github.com/mresvanis/he-sample-operator/internal/firmware"
+	"github.com/mresvanis/he-sample-operator/internal/intree"
+	"github.com/mresvanis/he-sample-operator/internal/kernels"
+	"github.com/mresvanis/he-sample-operator/internal/nodelabels"
+	"github.com/mresvanis/he-sample-operator/internal/nodeselector"
+	"github.com/mresvanis/he-sample-operator/internal/preflight"
+	"github.com/mresvanis/he-sample-operator/internal/resources"
+	"github.com/mresvanis/he-sample-operator/internal/rollout"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+// ClusterDeviceConfigReconciler reconciles a ClusterDeviceConfig object, sharing the
+// DeviceConfig reconciliation logic.
+type ClusterDeviceConfigReconciler struct {
+	*DeviceConfigReconciler
+}
+
+func NewClusterDeviceConfigReconciler(
+	client client.Client,
+	scheme *runtime.Scheme,
+	recorder record.EventRecorder,
+	be backend.Backend,
+	fu finalizers.Updater,
+	cu conditions.Updater,
+	nsv nodeselector.Validator,
+	nlu nodelabels.Updater,
+	dpi driverpods.Inspector,
+	pv preflight.Validator,
+	kcr kernels.CoverageReporter,
+	rt rollout.Tracker,
+	ar alerts.Reconciler,
+	fr firmware.Reconciler,
+	itr intree.Reconciler,
+	rv resources.Verifier,
+) *ClusterDeviceConfigReconciler {
+
+	return &ClusterDeviceConfigReconciler{
+		DeviceConfigReconciler: NewDeviceConfigReconciler(client, scheme, recorder, be, fu, cu, nsv, nlu, dpi, pv, kcr, rt, ar, fr, itr, rv),
+	}
+}
+
+//+kubebuilder:rbac:groups=example.com,resources=clusterdeviceconfigs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=example.com,resources=clusterdeviceconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=example.com,resources=clusterdeviceconfigs/finalizers,verbs=update
+
+// Reconcile reconciles the KMM Module of a ClusterDeviceConfig in the driver namespace.
+func (r *ClusterDeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	clusterDeviceConfig := &examplecomv1alpha1.ClusterDeviceConfig{}
+	err := r.Get(ctx, req.NamespacedName, clusterDeviceConfig)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.forget(req.NamespacedName)
+			logger.Info("ClusterDeviceConfig resource not found. Ignoring since object must be deleted.")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ClusterDeviceConfig", "resource", req.NamespacedName.Name)
+		return ctrl.Result{}, err
+	}
+
+	ctx, span := tracing.Start(ctx, "ClusterDeviceConfigReconciler.Reconcile", clusterDeviceConfig)
+	res, err := r.reconcileDeviceConfig(ctx, clusterDeviceConfig)
+	tracing.End(span, &err)
+
+	return res, err
+}
+
+// SetupWithManager sets up the controller with the Manager. The KMM Modules are watched
+// separately by WatchModules, since the native backend does not need KMM to be installed. The
+// given context is the one of the watch handlers.
+func (r *ClusterDeviceConfigReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		Named("cluster-device-config").
+		For(&examplecomv1alpha1.ClusterDeviceConfig{}).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findSetBasedClusterDeviceConfigs)),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findExtendedResourceClusterDeviceConfigs)),
+			builder.WithPredicates(allocatableChangedPredicate),
+		).
+		Watches(
+			&source.Kind{Type: &examplecomv1alpha1.DeviceConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.findConflictingClusterDeviceConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &examplecomv1alpha1.ClusterDeviceConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.findConflictingClusterDeviceConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &appsv1.DaemonSet{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findDriverPodsOwner)),
+			builder.WithPredicates(moduleLoaderPredicate),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findDriverPodsOwner)),
+			builder.WithPredicates(moduleLoaderPredicate),
+		).
+		Watches(
+			&source.Channel{Source: r.resync},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findAllClusterDeviceConfigs)),
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
+
+	r.controller = c
+	return nil
+}
+
+// WatchModules watches the KMM Modules of the ClusterDeviceConfigs, in the API version served
+// by the cluster. It must be called once the Module CRD is installed. The given context is the
+// one of the watch handler.
+func (r *ClusterDeviceConfigReconciler) WatchModules(ctx context.Context) error {
+	m, err := r.newModuleObject()
+	if err != nil {
+		return err
+	}
+
+	return r.controller.Watch(
+		&source.Kind{Type: m},
+		handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findModuleOwner)),
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := obj.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel]
+			return ok
+		}),
+	)
+}
+
+// findDriverPodsOwner maps a driver DaemonSet or pod to the ClusterDeviceConfig owning its
+// Module or native DaemonSet. The native backend, firmware and in-tree DaemonSets and pods carry
+// the owner label themselves, unlike the KMM ModuleLoader ones which are mapped through their
+// Module.
+func (r *ClusterDeviceConfigReconciler) findDriverPodsOwner(ctx context.Context, obj client.Object) []reconcile.Request {
+	if _, ok := obj.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel]; ok {
+		return r.findModuleOwner(ctx, obj)
+	}
+
+	o := r.getDriverPodsOwner(ctx, obj)
+	if o == nil {
+		return nil
+	}
+	if _, ok := o.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel]; !ok {
+		return nil
+	}
+	return r.findModuleOwner(ctx, o)
+}
+
+// findModuleOwner maps a Module or native DaemonSet to the ClusterDeviceConfig referenced by
+// its owner label.
+func (r *ClusterDeviceConfigReconciler) findModuleOwner(ctx context.Context, obj client.Object) []reconcile.Request {
+	owner := obj.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel]
+	if owner == "" {
+		return nil
+	}
+
+	// Only the names longer than a label value are truncated and hashed in the owner label,
+	// which is otherwise the name of the ClusterDeviceConfig.
+	if len(owner) < validation.LabelValueMaxLength {
+		cdc := &examplecomv1alpha1.ClusterDeviceConfig{}
+		if err := r.Get(ctx, types.NamespacedName{Name: owner}, cdc); err != nil {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: cdc.Name}}}
+	}
+
+	cdcs := &examplecomv1alpha1.ClusterDeviceConfigList{}
+	if err := r.List(ctx, cdcs); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cdc := range cdcs.Items {
+		if cdc.GetOwnerLabelValue() == owner {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cdc.Name}})
+		}
+	}
+	return requests
+}
+
+// findAllClusterDeviceConfigs maps a resync to every ClusterDeviceConfig.
+func (r *ClusterDeviceConfigReconciler) findAllClusterDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cdcs := &examplecomv1alpha1.ClusterDeviceConfigList{}
+	if err := r.List(ctx, cdcs); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(cdcs.Items))
+	for _, cdc := range cdcs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cdc.Name}})
+	}
+	return requests
+}
+
+// findConflictingClusterDeviceConfigs maps a DeviceConfig or ClusterDeviceConfig to the
+// ClusterDeviceConfigs reported conflicting with it, whose conflicts may have been resolved by
+// its update or deletion.
+func (r *ClusterDeviceConfigReconciler) findConflictingClusterDeviceConfigs(obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, peer := range r.nsv.GetConflictingPeers(client.ObjectKeyFromObject(obj)) {
+		if peer.Namespace == "" {
+			requests = append(requests, reconcile.Request{NamespacedName: peer})
+		}
+	}
+	return requests
+}
+
+// findExtendedResourceClusterDeviceConfigs maps a node allocatable change to the
+// ClusterDeviceConfigs with an extended resource, whose resource shortfalls may need to be
+// updated.
+func (r *ClusterDeviceConfigReconciler) findExtendedResourceClusterDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cdcs := &examplecomv1alpha1.ClusterDeviceConfigList{}
+	if err := r.List(ctx, cdcs); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cdc := range cdcs.Items {
+		if cdc.Spec.ExtendedResource != nil {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cdc.Name}})
+		}
+	}
+	return requests
+}
+
+// findSetBasedClusterDeviceConfigs maps a node label change to the ClusterDeviceConfigs with
+// set-based node selectors or node groups, whose computed and node group labels may need to be
+// updated.
+func (r *ClusterDeviceConfigReconciler) findSetBasedClusterDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cdcs := &examplecomv1alpha1.ClusterDeviceConfigList{}
+	if err := r.List(ctx, cdcs); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cdc := range cdcs.Items {
+		if cdc.HasSetBasedSelector() || len(cdc.Spec.NodeGroups) > 0 {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cdc.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	gomock "github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	record "k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/alerts"
+	"github.com/mresvanis/he-sample-operator/internal/backend"
+	"github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/companion"
+	"github.com/mresvanis/he-sample-operator/internal/conditions"
+	"github.com/mresvanis/he-sample-operator/internal/driverpods"
+	"github.com/mresvanis/he-sample-operator/internal/finalizers"
+	"github.com/mresvanis/he-sample-operator/internal/firmware"
+	"github.com/mresvanis/he-sample-operator/internal/intree"
+	"github.com/mresvanis/he-sample-operator/internal/kernels"
+	"github.com/mresvanis/he-sample-operator/internal/nodelabels"
+	"github.com/mresvanis/he-sample-operator/internal/nodeselector"
+	"github.com/mresvanis/he-sample-operator/internal/resources"
+	"github.com/mresvanis/he-sample-operator/internal/rollout"
+)
+
+var _ = Describe("ClusterDeviceConfigReconciler", func() {
+	var (
+		ctx   context.Context
+		gCtrl *gomock.Controller
+		be    *backend.MockBackend
+		fu    *finalizers.MockUpdater
+		cu    *conditions.MockUpdater
+		nsv   *nodeselector.MockValidator
+		nlu   *nodelabels.MockUpdater
+		dpi   *driverpods.MockInspector
+		kcr   *kernels.MockCoverageReporter
+		rt    *rollout.MockTracker
+		ar    *alerts.MockReconciler
+		fr    *firmware.MockReconciler
+		itr   *intree.MockReconciler
+		rv    *resources.MockVerifier
+		c     *client.MockClient
+		r     *ClusterDeviceConfigReconciler
+		req   reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		gCtrl = gomock.NewController(GinkgoT())
+		be = backend.NewMockBackend(gCtrl)
+		fu = finalizers.NewMockUpdater(gCtrl)
+		cu = conditions.NewMockUpdater(gCtrl)
+		nsv = nodeselector.NewMockValidator(gCtrl)
+		nlu = nodelabels.NewMockUpdater(gCtrl)
+		dpi = driverpods.NewMockInspector(gCtrl)
+		kcr = kernels.NewMockCoverageReporter(gCtrl)
+		rt = rollout.NewMockTracker(gCtrl)
+		ar = alerts.NewMockReconciler(gCtrl)
+		fr = firmware.NewMockReconciler(gCtrl)
+		itr = intree.NewMockReconciler(gCtrl)
+		rv = resources.NewMockVerifier(gCtrl)
+		c = client.NewMockClient(gCtrl)
+		req = reconcile.Request{NamespacedName: types.NamespacedName{Name: testDeviceConfigName}}
+
+		r = NewClusterDeviceConfigReconciler(c, scheme.Scheme, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+	})
+
+	Describe("Reconcile", func() {
+		It("should ignore a ClusterDeviceConfig that is not found", func() {
+			c.EXPECT().
+				Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).
+				Return(apierrors.NewNotFound(schema.GroupResource{Resource: "clusterdeviceconfigs"}, testDeviceConfigName))
+			rt.EXPECT().Forget(req.NamespacedName)
+
+			res, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Requeue).To(BeFalse())
+		})
+
+		It("should reconcile the ClusterDeviceConfig like a DeviceConfig", func() {
+			isClusterDeviceConfig := gomock.AssignableToTypeOf(&examplecomv1alpha1.ClusterDeviceConfig{})
+
+			gomock.InOrder(
+				c.EXPECT().Get(ctx, req.NamespacedName, isClusterDeviceConfig, gomock.Any()).DoAndReturn(
+					func(_ interface{}, _ interface{}, d *examplecomv1alpha1.ClusterDeviceConfig, _ ...interface{}) error {
+						d.Name = testDeviceConfigName
+						return nil
+					},
+				),
+				nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, isClusterDeviceConfig).Return(nil),
+				fu.EXPECT().ContainsDeletionFinalizer(isClusterDeviceConfig).Return(false),
+				fu.EXPECT().AddDeletionFinalizer(ctx, isClusterDeviceConfig).Return(nil),
+				nlu.EXPECT().SyncComputedNodeLabels(ctx, isClusterDeviceConfig).Return(nil),
+				kcr.EXPECT().GetKernelCoverage(ctx, isClusterDeviceConfig).Return(nil, nil),
+				fr.EXPECT().ReconcileFirmware(ctx, isClusterDeviceConfig).Return(nil, nil),
+				itr.EXPECT().ReconcileInTreeModules(ctx, isClusterDeviceConfig).Return(nil, nil),
+				be.EXPECT().ReconcileModule(ctx, isClusterDeviceConfig).Return(nil),
+				ar.EXPECT().ReconcilePrometheusRule(ctx, isClusterDeviceConfig).Return(nil),
+				rt.EXPECT().GetProgress(ctx, isClusterDeviceConfig).Return(&rollout.Progress{}, nil),
+				dpi.EXPECT().GetNodeFailures(ctx, isClusterDeviceConfig).Return(nil, nil),
+				rv.EXPECT().GetResourceShortfalls(ctx, isClusterDeviceConfig).Return(nil, nil),
+				cu.EXPECT().SetConditionsReady(ctx, isClusterDeviceConfig, "Reconciled", gomock.Any()).Return(nil),
+			)
+
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("findSetBasedClusterDeviceConfigs", func() {
+		It("should map a relabelled node to the ClusterDeviceConfigs with node groups", func() {
+			s := scheme.Scheme
+			Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+			grouped := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "grouped"},
+				Spec: examplecomv1alpha1.DeviceConfigSpec{
+					NodeSelector: map[string]string{"example.com/accelerator": "true"},
+					NodeGroups: []examplecomv1alpha1.NodeGroup{
+						{Name: "large", NodeSelector: map[string]string{"example.com/size": "large"}},
+					},
+				},
+			}
+			plain := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "plain"},
+				Spec: examplecomv1alpha1.DeviceConfigSpec{
+					NodeSelector: map[string]string{"example.com/accelerator": "true"},
+				},
+			}
+			r = NewClusterDeviceConfigReconciler(
+				fake.NewClientBuilder().WithScheme(s).WithObjects(grouped, plain).Build(),
+				s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv,
+			)
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "a-node",
+					Labels: map[string]string{"example.com/accelerator": "true", "example.com/size": "large"},
+				},
+			}
+
+			Expect(r.findSetBasedClusterDeviceConfigs(context.TODO(), node)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "grouped"}},
+			}))
+		})
+	})
+
+	Describe("findModuleOwner", func() {
+		It("should map a labelled Module to its ClusterDeviceConfig", func() {
+			s := scheme.Scheme
+			Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+			long := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 100)},
+			}
+			other := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			}
+			r = NewClusterDeviceConfigReconciler(
+				fake.NewClientBuilder().WithScheme(s).WithObjects(long, other).Build(),
+				s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv,
+			)
+
+			m := &kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "a-module",
+					Namespace: "driver-namespace",
+					Labels:    map[string]string{examplecomv1alpha1.ClusterDeviceConfigOwnerLabel: long.GetOwnerLabelValue()},
+				},
+			}
+
+			Expect(r.findModuleOwner(context.TODO(), m)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: long.Name}},
+			}))
+		})
+
+		It("should get the ClusterDeviceConfig named by the owner label", func() {
+			s := scheme.Scheme
+			Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-device-config"},
+			}
+			r = NewClusterDeviceConfigReconciler(
+				fake.NewClientBuilder().WithScheme(s).WithObjects(cdc).Build(),
+				s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv,
+			)
+
+			m := &kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "a-module",
+					Namespace: "driver-namespace",
+					Labels:    map[string]string{examplecomv1alpha1.ClusterDeviceConfigOwnerLabel: cdc.GetOwnerLabelValue()},
+				},
+			}
+			Expect(r.findModuleOwner(context.TODO(), m)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: cdc.Name}},
+			}))
+
+			m.Labels[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] = "a-deleted-cluster-device-config"
+			Expect(r.findModuleOwner(context.TODO(), m)).To(BeEmpty())
+		})
+	})
+
+	Describe("findDriverPodsOwner", func() {
+		It("should map a labelled firmware pod to its ClusterDeviceConfig", func() {
+			s := scheme.Scheme
+			Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-device-config"},
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(cdc).Build()
+			r = NewClusterDeviceConfigReconciler(
+				c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv,
+			)
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "a-firmware-pod",
+					Namespace: "driver-namespace",
+					Labels:    companion.GetManagedLabels(cdc, firmware.Role),
+				},
+			}
+			Expect(r.findDriverPodsOwner(context.TODO(), pod)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: cdc.Name}},
+			}))
+
+			// The DeviceConfig controller leaves it to the ClusterDeviceConfig one.
+			dcr := NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			Expect(dcr.findDriverPodsOwner(context.TODO(), pod)).To(BeEmpty())
+		})
+	})
+})
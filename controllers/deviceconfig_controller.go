@@ -18,23 +18,64 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/alerts"
+	"github.com/mresvanis/he-sample-operator/internal/backend"
 	"github.com/mresvanis/he-sample-operator/internal/conditions"
+	"github.com/mresvanis/he-sample-operator/internal/driverpods"
 	"github.com/mresvanis/he-sample-operator/internal/finalizers"
+	"github.com/mresvanis/he-sample-operator/internal/firmware"
+	"github.com/mresvanis/he-sample-operator/internal/intree"
+	"github.com/mresvanis/he-sample-operator/internal/kernels"
 	"github.com/mresvanis/he-sample-operator/internal/metrics"
 	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+	"github.com/mresvanis/he-sample-operator/internal/nodelabels"
 	"github.com/mresvanis/he-sample-operator/internal/nodeselector"
+	"github.com/mresvanis/he-sample-operator/internal/preflight"
+	"github.com/mresvanis/he-sample-operator/internal/resources"
+	"github.com/mresvanis/he-sample-operator/internal/rollout"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	// defaultTeardownTimeout is how long to wait for the driver of a deleted DeviceConfig to
+	// be unloaded when no timeout is configured.
+	defaultTeardownTimeout = 10 * time.Minute
+
+	// teardownPollInterval is how often the nodes are checked for the driver of a deleted
+	// DeviceConfig.
+	teardownPollInterval = 5 * time.Second
+
+	// conflictRecheckInterval is how often a DeviceConfig with conflicting node selectors is
+	// validated again, in case the update of a conflicting peer was missed.
+	conflictRecheckInterval = 5 * time.Minute
 )
 
 // DeviceConfigReconciler reconciles a DeviceConfig object
@@ -44,40 +85,88 @@ type DeviceConfigReconciler struct {
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
 
-	mr module.Reconciler
+	be backend.Backend
 
 	fu finalizers.Updater
 
 	cu conditions.Updater
 
 	nsv nodeselector.Validator
+
+	nlu nodelabels.Updater
+
+	dpi driverpods.Inspector
+
+	pv preflight.Validator
+
+	kcr kernels.CoverageReporter
+
+	rt rollout.Tracker
+
+	ar alerts.Reconciler
+
+	fr firmware.Reconciler
+
+	itr intree.Reconciler
+
+	rv resources.Verifier
+
+	// controller is kept to watch the KMM Modules once their CRD is installed.
+	controller controller.Controller
+
+	// resync requeues every resource of the controller, see Resync.
+	resync chan event.GenericEvent
 }
 
 func NewDeviceConfigReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
 	recorder record.EventRecorder,
-	mr module.Reconciler,
+	be backend.Backend,
 	fu finalizers.Updater,
 	cu conditions.Updater,
 	nsv nodeselector.Validator,
+	nlu nodelabels.Updater,
+	dpi driverpods.Inspector,
+	pv preflight.Validator,
+	kcr kernels.CoverageReporter,
+	rt rollout.Tracker,
+	ar alerts.Reconciler,
+	fr firmware.Reconciler,
+	itr intree.Reconciler,
+	rv resources.Verifier,
 ) *DeviceConfigReconciler {
 
 	return &DeviceConfigReconciler{
 		Client:   client,
 		Scheme:   scheme,
 		Recorder: recorder,
-		mr:       mr,
+		be:       be,
 		fu:       fu,
 		cu:       cu,
 		nsv:      nsv,
+		nlu:      nlu,
+		dpi:      dpi,
+		pv:       pv,
+		kcr:      kcr,
+		rt:       rt,
+		ar:       ar,
+		fr:       fr,
+		itr:      itr,
+		rv:       rv,
+		resync:   make(chan event.GenericEvent, 1),
 	}
 }
 
 //+kubebuilder:rbac:groups=example.com,resources=deviceconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=example.com,resources=deviceconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=example.com,resources=deviceconfigs/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups="kmm.sigs.k8s.io",resources=modules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -91,7 +180,7 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	err := r.Get(ctx, req.NamespacedName, deviceConfig)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			metrics.ReconciliationFailed.WithLabelValues(req.NamespacedName.Name).Set(0)
+			r.forget(req.NamespacedName)
 			logger.Info("DeviceConfig resource not found. Ignoring since object must be deleted.")
 			return ctrl.Result{}, nil
 		}
@@ -99,63 +188,653 @@ func (r *DeviceConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	if !deviceConfig.ObjectMeta.DeletionTimestamp.IsZero() {
-		metrics.ReconciliationFailed.WithLabelValues(deviceConfig.Name).Set(0)
+	ctx, span := tracing.Start(ctx, "DeviceConfigReconciler.Reconcile", deviceConfig)
+	res, err := r.reconcileDeviceConfig(ctx, deviceConfig)
+	tracing.End(span, &err)
 
-		if r.fu.ContainsDeletionFinalizer(deviceConfig) {
-			if err := r.mr.DeleteModule(ctx, deviceConfig); err != nil {
-				return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
-			}
-			if err := r.fu.RemoveDeletionFinalizer(ctx, deviceConfig); err != nil {
-				return ctrl.Result{}, err
-			}
+	return res, err
+}
+
+// reconcileDeviceConfig reconciles the resources of either a DeviceConfig or a
+// ClusterDeviceConfig, which share their spec and status.
+func (r *DeviceConfigReconciler) reconcileDeviceConfig(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !dc.GetDeletionTimestamp().IsZero() {
+		r.forget(client.ObjectKeyFromObject(dc))
+
+		if r.fu.ContainsDeletionFinalizer(dc) {
+			return r.teardown(ctx, dc)
 		}
 		return ctrl.Result{}, nil
 	}
 
-	if err := r.nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, deviceConfig); err != nil {
-		logger.Error(err, "Failed to validate DeviceConfig", "resource", deviceConfig.Name)
+	labels := metrics.DeviceConfigLabels(dc.GetNamespace(), dc.GetName())
+	start := time.Now()
+	defer func() {
+		metrics.ReconcileDuration.With(labels).Observe(time.Since(start).Seconds())
+	}()
+
+	if err := r.nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, dc); err != nil {
+		if goerrors.Is(err, nodeselector.ErrIndexNotSynced) {
+			return ctrl.Result{}, err
+		}
+		logger.Error(err, "Failed to validate DeviceConfig", "resource", dc.GetName())
 		r.Recorder.Event(
-			deviceConfig,
+			dc,
 			v1.EventTypeWarning,
 			"Error",
-			"Conflicting DeviceConfig NodeSelectors found. Please add or update this DeviceConfig's NodeSelector accordingly.",
+			fmt.Sprintf("Conflicting DeviceConfig NodeSelectors found (%s). Please add or update this DeviceConfig's NodeSelector accordingly.", err.Error()),
 		)
-		metrics.ReconciliationFailed.WithLabelValues(deviceConfig.Name).Set(1)
-		return ctrl.Result{}, nil
+		metrics.ReconciliationFailed.With(labels).Set(1)
+
+		dc.GetStatus().Conflicts = nil
+		var conflictErr *nodeselector.ConflictError
+		if goerrors.As(err, &conflictErr) {
+			dc.GetStatus().Conflicts = conflictErr.Conflicts
+		}
+		metrics.ConflictingDeviceConfigs.With(labels).Set(float64(len(dc.GetStatus().Conflicts)))
+		// The conflicting peers requeue the DeviceConfig once they change, see
+		// findConflictingDeviceConfigs.
+		return ctrl.Result{RequeueAfter: conflictRecheckInterval}, r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonConflictingNodeSelector, err.Error())
 	}
 
-	if !r.fu.ContainsDeletionFinalizer(deviceConfig) {
-		if err := r.fu.AddDeletionFinalizer(ctx, deviceConfig); err != nil {
+	if !r.fu.ContainsDeletionFinalizer(dc) {
+		if err := r.fu.AddDeletionFinalizer(ctx, dc); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	if err := r.mr.ReconcileModule(ctx, deviceConfig); err != nil {
-		if cerr := r.cu.SetConditionsErrored(ctx, deviceConfig, conditions.ReasonModuleFailed, err.Error()); cerr != nil {
+	// The node selector conflicts, if any, have been resolved.
+	dc.GetStatus().Conflicts = nil
+	metrics.ConflictingDeviceConfigs.With(labels).Set(0)
+
+	if err := r.nlu.SyncComputedNodeLabels(ctx, dc); err != nil {
+		if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonNodeLabelsFailed, err.Error()); cerr != nil {
+			err = fmt.Errorf("%s: %w", err.Error(), cerr)
+		}
+		metrics.ReconciliationFailed.With(labels).Set(1)
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileKernelCoverage(ctx, dc); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if dc.GetSpec().Preflight {
+		status, err := r.pv.ValidateDriverImages(ctx, dc)
+		if err != nil {
+			if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonPreflightFailed, err.Error()); cerr != nil {
+				err = fmt.Errorf("%s: %w", err.Error(), cerr)
+			}
+			metrics.ReconciliationFailed.With(labels).Set(1)
+			return ctrl.Result{}, err
+		}
+
+		dc.GetStatus().Preflight = status
+		if !status.Passed {
+			// The Module is left untouched, so that the nodes keep running the previous
+			// DriverVersion until its images are available.
+			msg := preflight.FormatFailures(status)
+			r.Recorder.Event(dc, v1.EventTypeWarning, conditions.ReasonPreflightFailed, msg)
+			metrics.ReconciliationFailed.With(labels).Set(1)
+			return ctrl.Result{}, r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonPreflightFailed, msg)
+		}
+	} else {
+		dc.GetStatus().Preflight = nil
+	}
+
+	firmwareStatus, err := r.fr.ReconcileFirmware(ctx, dc)
+	if err != nil {
+		metrics.ReconciliationFailed.With(labels).Set(1)
+		if goerrors.Is(err, firmware.ErrIncompatibleFirmware) {
+			// The Module is left untouched until the firmware matches the DriverVersion.
+			r.Recorder.Event(dc, v1.EventTypeWarning, conditions.ReasonFirmwareIncompatible, err.Error())
+			return ctrl.Result{}, r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonFirmwareIncompatible, err.Error())
+		}
+		if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonFirmwareFailed, err.Error()); cerr != nil {
+			err = fmt.Errorf("%s: %w", err.Error(), cerr)
+		}
+		return ctrl.Result{}, err
+	}
+	dc.GetStatus().Firmware = firmwareStatus
+
+	inTreeModules, err := r.itr.ReconcileInTreeModules(ctx, dc)
+	if err != nil {
+		if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonInTreeFailed, err.Error()); cerr != nil {
+			err = fmt.Errorf("%s: %w", err.Error(), cerr)
+		}
+		metrics.ReconciliationFailed.With(labels).Set(1)
+		return ctrl.Result{}, err
+	}
+	dc.GetStatus().InTreeModules = inTreeModules
+
+	if err := r.be.ReconcileModule(ctx, dc); err != nil {
+		if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonModuleFailed, err.Error()); cerr != nil {
+			err = fmt.Errorf("%s: %w", err.Error(), cerr)
+		}
+		metrics.ReconciliationFailed.With(labels).Set(1)
+		return ctrl.Result{}, err
+	}
+
+	if err := r.ar.ReconcilePrometheusRule(ctx, dc); err != nil {
+		if cerr := r.cu.SetConditionsErrored(ctx, dc, conditions.ReasonPrometheusRuleFailed, err.Error()); cerr != nil {
 			err = fmt.Errorf("%s: %w", err.Error(), cerr)
 		}
-		metrics.ReconciliationFailed.WithLabelValues(deviceConfig.Name).Set(1)
+		metrics.ReconciliationFailed.With(labels).Set(1)
 		return ctrl.Result{}, err
 	}
 
-	metrics.ReconciliationFailed.WithLabelValues(deviceConfig.Name).Set(0)
+	metrics.ReconciliationFailed.With(labels).Set(0)
+
+	if err := r.reconcileRolloutMetrics(ctx, dc, labels); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	failures, err := r.dpi.GetNodeFailures(ctx, dc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := dc.GetStatus()
+	previousFailures := status.NodeFailures
+	status.FailedNodeCount = len(failures)
+	status.NodeFailures = failures
+	if len(failures) > driverpods.MaxReportedNodeFailures {
+		status.NodeFailures = failures[:driverpods.MaxReportedNodeFailures]
+	}
+
+	shortfallMsg, err := r.reconcileResourceShortfalls(ctx, dc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(failures) > 0 {
+		msg := driverpods.FormatNodeFailures(failures)
+		// Only report changes, since every driver pod status update requeues the DeviceConfig.
+		if !equality.Semantic.DeepEqual(previousFailures, status.NodeFailures) {
+			r.Recorder.Event(dc, v1.EventTypeWarning, conditions.ReasonDriverPodsFailed, msg)
+		}
+		return ctrl.Result{}, r.cu.SetConditionsDegraded(ctx, dc, conditions.ReasonDriverPodsFailed, msg)
+	}
+
+	if shortfallMsg != "" {
+		return ctrl.Result{}, r.cu.SetConditionsDegraded(ctx, dc, conditions.ReasonResourceShortfall, shortfallMsg)
+	}
 
 	r.Recorder.Event(
-		deviceConfig,
+		dc,
 		v1.EventTypeNormal,
 		"Reconciled",
-		fmt.Sprintf("Succesfully reconciled DeviceConfig %s/%s", deviceConfig.Namespace, deviceConfig.Name),
+		fmt.Sprintf("Succesfully reconciled %s", objectRef(dc)),
+	)
+
+	return ctrl.Result{}, r.cu.SetConditionsReady(ctx, dc, "Reconciled", "All resources have been successfully reconciled")
+}
+
+// teardown deletes the resources of a deleted DeviceConfig and waits for its driver to be
+// unloaded from every node, up to the configured timeout, before its finalizer is removed.
+// The driver is left running instead when the DeviceConfig has the Orphan or Retain deletion
+// policy.
+func (r *DeviceConfigReconciler) teardown(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if dc.GetSpec().GetDeletionPolicy() != examplecomv1alpha1.DeletionPolicyDelete {
+		return ctrl.Result{}, r.releaseDriver(ctx, dc)
+	}
+
+	if err := r.be.DeleteModule(ctx, dc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+	if err := r.ar.DeletePrometheusRule(ctx, dc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+
+	if dc.GetAnnotations()[examplecomv1alpha1.ForceDeleteAnnotation] == "true" {
+		logger.Info("Skipping the wait for the driver to be unloaded", "annotation", examplecomv1alpha1.ForceDeleteAnnotation)
+	} else {
+		nodes, err := r.dpi.GetLoadedNodes(ctx, dc)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if len(nodes) > 0 {
+			remaining := getTeardownTimeout(dc) - time.Since(dc.GetDeletionTimestamp().Time)
+			if remaining > 0 {
+				return r.waitForUnload(ctx, dc, nodes, remaining)
+			}
+
+			r.Recorder.Event(
+				dc,
+				v1.EventTypeWarning,
+				"TeardownTimeout",
+				fmt.Sprintf("Timed out waiting for the driver to be unloaded from %d node(s), deleting anyway", len(nodes)),
+			)
+		}
+	}
+
+	// The firmware and in-tree labels are only removed once the driver is unloaded, since they
+	// select the nodes of the driver, and the in-tree kernel modules are only loaded again on
+	// the nodes the driver is no longer loaded on.
+	if err := r.fr.DeleteFirmware(ctx, dc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+	if err := r.itr.RestoreInTreeModules(ctx, dc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+	if err := r.nlu.RemoveComputedNodeLabels(ctx, dc); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+
+	r.recordDeletionPolicy(dc, "the driver has been removed from the nodes")
+	return ctrl.Result{}, r.fu.RemoveDeletionFinalizer(ctx, dc)
+}
+
+// releaseDriver leaves the driver of a deleted DeviceConfig running along with its firmware and
+// in-tree DaemonSets and removes its finalizer. The computed, firmware and in-tree node labels
+// are kept, since they still select the nodes of the released driver.
+func (r *DeviceConfigReconciler) releaseDriver(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) error {
+	if err := r.be.ReleaseModule(ctx, dc); err != nil {
+		return fmt.Errorf("failed to release DeviceConfig resources: %w", err)
+	}
+	if err := r.fr.ReleaseFirmware(ctx, dc); err != nil {
+		return fmt.Errorf("failed to release DeviceConfig resources: %w", err)
+	}
+	if err := r.itr.ReleaseInTreeModules(ctx, dc); err != nil {
+		return fmt.Errorf("failed to release DeviceConfig resources: %w", err)
+	}
+	if err := r.ar.DeletePrometheusRule(ctx, dc); err != nil {
+		return fmt.Errorf("failed to delete DeviceConfig resources: %w", err)
+	}
+
+	if dc.GetSpec().GetDeletionPolicy() == examplecomv1alpha1.DeletionPolicyRetain {
+		r.recordDeletionPolicy(dc, "the driver has been left running and is no longer reconciled")
+	} else {
+		r.recordDeletionPolicy(dc, "the driver has been left running until it is adopted by a DeviceConfig of the same name")
+	}
+	return r.fu.RemoveDeletionFinalizer(ctx, dc)
+}
+
+// recordDeletionPolicy records the deletion policy applied to the driver of a deleted
+// DeviceConfig.
+func (r *DeviceConfigReconciler) recordDeletionPolicy(dc examplecomv1alpha1.DeviceConfigObject, outcome string) {
+	r.Recorder.Event(
+		dc,
+		v1.EventTypeNormal,
+		"DeletionPolicy",
+		fmt.Sprintf("Applied the %s deletion policy: %s", dc.GetSpec().GetDeletionPolicy(), outcome),
 	)
+}
+
+// waitForUnload reports the nodes the driver of a deleted DeviceConfig is still loaded on
+// and requeues it until either they are all gone or the teardown times out.
+func (r *DeviceConfigReconciler) waitForUnload(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject, nodes []string, remaining time.Duration) (ctrl.Result, error) {
+	status := &examplecomv1alpha1.TeardownStatus{RemainingNodes: len(nodes), Nodes: nodes}
+	if len(nodes) > driverpods.MaxReportedNodeFailures {
+		status.Nodes = nodes[:driverpods.MaxReportedNodeFailures]
+	}
+	dc.GetStatus().Teardown = status
 
-	return ctrl.Result{}, r.cu.SetConditionsReady(ctx, deviceConfig, "Reconciled", "All resources have been successfully reconciled")
+	msg := fmt.Sprintf("Waiting for the driver to be unloaded from %d node(s)", len(nodes))
+	if err := r.cu.SetConditionsTerminating(ctx, dc, msg); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The driver pods of a deleted Module no longer requeue the DeviceConfig, so the nodes
+	// are polled instead.
+	requeueAfter := teardownPollInterval
+	if remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *DeviceConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+// getTeardownTimeout returns how long to wait for the driver of a deleted DeviceConfig to
+// be unloaded.
+func getTeardownTimeout(dc examplecomv1alpha1.DeviceConfigObject) time.Duration {
+	if t := dc.GetSpec().Teardown; t != nil && t.Timeout != nil {
+		return t.Timeout.Duration
+	}
+	return defaultTeardownTimeout
+}
+
+// reconcileKernelCoverage reports the kernel versions of the selected nodes in the
+// DeviceConfig status, warning about the ones which are not matched by any kernel mapping
+// before any node rolls to them.
+func (r *DeviceConfigReconciler) reconcileKernelCoverage(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) error {
+	coverage, err := r.kcr.GetKernelCoverage(ctx, dc)
+	if err != nil {
+		return err
+	}
+
+	status := dc.GetStatus()
+	previousUnmapped := status.UnmappedKernels
+	status.KernelCoverage = coverage
+	status.UnmappedKernels = kernels.GetUnmappedKernels(coverage)
+
+	metrics.UnmappedKernelNodes.DeletePartialMatch(metrics.DeviceConfigLabels(dc.GetNamespace(), dc.GetName()))
+	for _, kc := range coverage {
+		if kc.Mapping == "" {
+			metrics.UnmappedKernelNodes.WithLabelValues(dc.GetNamespace(), dc.GetName(), kc.KernelVersion).Set(float64(kc.NodeCount))
+		}
+	}
+
+	if len(status.UnmappedKernels) > 0 && !equality.Semantic.DeepEqual(previousUnmapped, status.UnmappedKernels) {
+		r.Recorder.Event(
+			dc,
+			v1.EventTypeWarning,
+			"UnmappedKernels",
+			fmt.Sprintf("No kernel mapping matches kernel(s) %s of the selected nodes", strings.Join(status.UnmappedKernels, ", ")),
+		)
+	}
+	return nil
+}
+
+// reconcileResourceShortfalls reports the nodes advertising fewer extended resources than
+// expected in the DeviceConfig status and metrics, and returns a description of them, if any.
+func (r *DeviceConfigReconciler) reconcileResourceShortfalls(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) (string, error) {
+	shortfalls, err := r.rv.GetResourceShortfalls(ctx, dc)
+	if err != nil {
+		return "", err
+	}
+
+	status := dc.GetStatus()
+	previousShortfalls := status.ResourceShortfalls
+	status.ResourceShortfallCount = len(shortfalls)
+	status.ResourceShortfalls = shortfalls
+	if len(shortfalls) > resources.MaxReportedShortfalls {
+		status.ResourceShortfalls = shortfalls[:resources.MaxReportedShortfalls]
+	}
+
+	metrics.ResourceShortfallNodes.DeletePartialMatch(metrics.DeviceConfigLabels(dc.GetNamespace(), dc.GetName()))
+	for reason, count := range resources.CountByReason(shortfalls) {
+		metrics.ResourceShortfallNodes.WithLabelValues(dc.GetNamespace(), dc.GetName(), reason).Set(float64(count))
+	}
+
+	if len(shortfalls) == 0 {
+		return "", nil
+	}
+
+	msg := resources.FormatShortfalls(dc.GetSpec().ExtendedResource.Name, shortfalls)
+	// Only report changes, since every node allocatable update requeues the DeviceConfig.
+	if !equality.Semantic.DeepEqual(previousShortfalls, status.ResourceShortfalls) {
+		r.Recorder.Event(dc, v1.EventTypeWarning, conditions.ReasonResourceShortfall, msg)
+	}
+	return msg, nil
+}
+
+// reconcileRolloutMetrics updates the node and upgrade progress metrics of the given
+// DeviceConfig and reports the progress of its node groups in its status.
+func (r *DeviceConfigReconciler) reconcileRolloutMetrics(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject, labels prometheus.Labels) error {
+	progress, err := r.rt.GetProgress(ctx, dc)
+	if err != nil {
+		return err
+	}
+
+	metrics.SelectedNodes.With(labels).Set(float64(progress.SelectedNodes))
+	metrics.DesiredNodes.With(labels).Set(float64(progress.DesiredNodes))
+	metrics.AvailableNodes.With(labels).Set(float64(progress.AvailableNodes))
+	metrics.UpgradeProgress.With(labels).Set(progress.UpgradeRatio())
+	for _, d := range progress.MatchToAvailable {
+		metrics.NodeMatchToDriverAvailable.With(labels).Observe(d.Seconds())
+	}
+	dc.GetStatus().NodeGroups = progress.NodeGroups
+	return nil
+}
+
+// forget deletes the metric series and the rollout state of a deleted DeviceConfig.
+func (r *DeviceConfigReconciler) forget(key types.NamespacedName) {
+	metrics.DeleteDeviceConfigSeries(key.Namespace, key.Name)
+	r.rt.Forget(key)
+}
+
+// objectRef returns the namespace/name reference of a namespaced object or the name of a
+// cluster-scoped one.
+func objectRef(obj client.Object) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// SetupWithManager sets up the controller with the Manager. The KMM Modules are watched
+// separately by WatchModules, since the native backend does not need KMM to be installed. The
+// given context is the one of the watch handlers.
+func (r *DeviceConfigReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		Named("device-config").
 		For(&examplecomv1alpha1.DeviceConfig{}).
-		Owns(&kmmv1beta1.Module{}).
-		Complete(r)
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findSetBasedDeviceConfigs)),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findExtendedResourceDeviceConfigs)),
+			builder.WithPredicates(allocatableChangedPredicate),
+		).
+		Watches(
+			&source.Kind{Type: &examplecomv1alpha1.DeviceConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.findConflictingDeviceConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &examplecomv1alpha1.ClusterDeviceConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.findConflictingDeviceConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &appsv1.DaemonSet{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findDriverPodsOwner)),
+			builder.WithPredicates(moduleLoaderPredicate),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findDriverPodsOwner)),
+			builder.WithPredicates(moduleLoaderPredicate),
+		).
+		Watches(
+			&source.Channel{Source: r.resync},
+			handler.EnqueueRequestsFromMapFunc(withContext(ctx, r.findAllDeviceConfigs)),
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
+
+	r.controller = c
+	return nil
+}
+
+// WatchModules watches the KMM Modules owned by the DeviceConfigs, in the API version served
+// by the cluster. It must be called once the Module CRD is installed.
+func (r *DeviceConfigReconciler) WatchModules() error {
+	m, err := r.newModuleObject()
+	if err != nil {
+		return err
+	}
+
+	return r.controller.Watch(
+		&source.Kind{Type: m},
+		&handler.EnqueueRequestForOwner{OwnerType: &examplecomv1alpha1.DeviceConfig{}, IsController: true},
+	)
+}
+
+// newModuleObject returns an empty KMM Module in the API version served by the cluster. Only
+// the Module metadata is used, which does not depend on its API version.
+func (r *DeviceConfigReconciler) newModuleObject() (*unstructured.Unstructured, error) {
+	gvk, err := module.GetServedGroupVersionKind(r.RESTMapper())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &unstructured.Unstructured{}
+	m.SetGroupVersionKind(gvk)
+	return m, nil
+}
+
+// moduleLoaderPredicate filters the KMM ModuleLoader, native backend, firmware and in-tree
+// DaemonSets and pods.
+var moduleLoaderPredicate = predicate.NewPredicateFuncs(func(obj client.Object) bool {
+	role := obj.GetLabels()[module.RoleLabel]
+	return role == module.ModuleLoaderRole || role == firmware.Role || role == intree.Role
+})
+
+// withContext binds the given context to a map function, since the map functions are not
+// passed the context of the watch handler.
+func withContext(ctx context.Context, fn func(context.Context, client.Object) []reconcile.Request) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		return fn(ctx, obj)
+	}
+}
+
+// allocatableChangedPredicate filters the node updates changing their allocatable resources,
+// e.g. once a device plugin registers, ignoring the frequent node status heartbeats.
+var allocatableChangedPredicate = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, ok := e.ObjectOld.(*v1.Node)
+		if !ok {
+			return false
+		}
+		newNode, ok := e.ObjectNew.(*v1.Node)
+		if !ok {
+			return false
+		}
+		return !equality.Semantic.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable)
+	},
+}
+
+// getDriverPodsOwner returns the object owned by a DeviceConfig of the given driver DaemonSet
+// or pod, i.e. the KMM Module of a ModuleLoader one or the DaemonSet of a native backend one.
+func (r *DeviceConfigReconciler) getDriverPodsOwner(ctx context.Context, obj client.Object) client.Object {
+	if obj.GetLabels()[native.ManagedByLabel] == native.ManagedByValue {
+		if _, ok := obj.(*appsv1.DaemonSet); ok {
+			return obj
+		}
+
+		owner := metav1.GetControllerOf(obj)
+		if owner == nil || owner.Kind != "DaemonSet" {
+			return nil
+		}
+		ds := &appsv1.DaemonSet{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}, ds); err != nil {
+			return nil
+		}
+		return ds
+	}
+
+	name := obj.GetLabels()[module.ModuleNameLabel]
+	if name == "" {
+		return nil
+	}
+
+	m, err := r.newModuleObject()
+	if err != nil {
+		return nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}, m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// findDriverPodsOwner maps a driver DaemonSet or pod to the DeviceConfig owning its Module or
+// native DaemonSet. The ones of a ClusterDeviceConfig are recognized by their owner label and
+// left to its controller.
+func (r *DeviceConfigReconciler) findDriverPodsOwner(ctx context.Context, obj client.Object) []reconcile.Request {
+	if _, ok := obj.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel]; ok {
+		return nil
+	}
+
+	o := r.getDriverPodsOwner(ctx, obj)
+	if o == nil {
+		return nil
+	}
+
+	owner := metav1.GetControllerOf(o)
+	if owner == nil || owner.Kind != "DeviceConfig" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: owner.Name}},
+	}
+}
+
+// Resync requeues every resource of the controller, e.g. once the operator defaults have
+// changed. It never blocks, a pending resync covering the later ones.
+func (r *DeviceConfigReconciler) Resync() {
+	select {
+	case r.resync <- event.GenericEvent{Object: &examplecomv1alpha1.DeviceConfig{}}:
+	default:
+	}
+}
+
+// findAllDeviceConfigs maps a resync to every DeviceConfig.
+func (r *DeviceConfigReconciler) findAllDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	dcs := &examplecomv1alpha1.DeviceConfigList{}
+	if err := r.List(ctx, dcs); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(dcs.Items))
+	for _, dc := range dcs.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name},
+		})
+	}
+	return requests
+}
+
+// findConflictingDeviceConfigs maps a DeviceConfig or ClusterDeviceConfig to the DeviceConfigs
+// reported conflicting with it, whose conflicts may have been resolved by its update or
+// deletion.
+func (r *DeviceConfigReconciler) findConflictingDeviceConfigs(obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, peer := range r.nsv.GetConflictingPeers(client.ObjectKeyFromObject(obj)) {
+		// ClusterDeviceConfigs have no namespace and are requeued by their own controller.
+		if peer.Namespace != "" {
+			requests = append(requests, reconcile.Request{NamespacedName: peer})
+		}
+	}
+	return requests
+}
+
+// findExtendedResourceDeviceConfigs maps a node allocatable change to the DeviceConfigs with
+// an extended resource, whose resource shortfalls may need to be updated.
+func (r *DeviceConfigReconciler) findExtendedResourceDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	dcs := &examplecomv1alpha1.DeviceConfigList{}
+	if err := r.List(ctx, dcs); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, dc := range dcs.Items {
+		if dc.Spec.ExtendedResource != nil {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// findSetBasedDeviceConfigs maps a node label change to the DeviceConfigs with set-based node
+// selectors or node groups, whose computed and node group labels may need to be updated.
+func (r *DeviceConfigReconciler) findSetBasedDeviceConfigs(ctx context.Context, _ client.Object) []reconcile.Request {
+	dcs := &examplecomv1alpha1.DeviceConfigList{}
+	if err := r.List(ctx, dcs); err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, dc := range dcs.Items {
+		if dc.HasSetBasedSelector() || len(dc.Spec.NodeGroups) > 0 {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name},
+			})
+		}
+	}
+	return requests
 }
@@ -23,12 +23,21 @@ import (
 	"time"
 
 	gomock "github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	record "k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -36,11 +45,23 @@ import (
 
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/alerts"
+	"github.com/mresvanis/he-sample-operator/internal/backend"
 	"github.com/mresvanis/he-sample-operator/internal/client"
 	"github.com/mresvanis/he-sample-operator/internal/conditions"
+	"github.com/mresvanis/he-sample-operator/internal/driverpods"
 	"github.com/mresvanis/he-sample-operator/internal/finalizers"
+	"github.com/mresvanis/he-sample-operator/internal/firmware"
+	"github.com/mresvanis/he-sample-operator/internal/intree"
+	"github.com/mresvanis/he-sample-operator/internal/kernels"
 	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+	"github.com/mresvanis/he-sample-operator/internal/nodelabels"
 	"github.com/mresvanis/he-sample-operator/internal/nodeselector"
+	"github.com/mresvanis/he-sample-operator/internal/preflight"
+	"github.com/mresvanis/he-sample-operator/internal/resources"
+	"github.com/mresvanis/he-sample-operator/internal/rollout"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 const (
@@ -61,20 +82,36 @@ var _ = Describe("DeviceConfigReconciler", func() {
 
 			var (
 				gCtrl *gomock.Controller
-				mr    *module.MockReconciler
+				be    *backend.MockBackend
 				fu    *finalizers.MockUpdater
 				cu    *conditions.MockUpdater
 				nsv   *nodeselector.MockValidator
+				nlu   *nodelabels.MockUpdater
+				dpi   *driverpods.MockInspector
+				kcr   *kernels.MockCoverageReporter
+				rt    *rollout.MockTracker
+				ar    *alerts.MockReconciler
+				fr    *firmware.MockReconciler
+				itr   *intree.MockReconciler
+				rv    *resources.MockVerifier
 				r     *DeviceConfigReconciler
 				c     *client.MockClient
 			)
 
 			BeforeEach(func() {
 				gCtrl = gomock.NewController(GinkgoT())
-				mr = module.NewMockReconciler(gCtrl)
+				be = backend.NewMockBackend(gCtrl)
 				fu = finalizers.NewMockUpdater(gCtrl)
 				cu = conditions.NewMockUpdater(gCtrl)
 				nsv = nodeselector.NewMockValidator(gCtrl)
+				nlu = nodelabels.NewMockUpdater(gCtrl)
+				dpi = driverpods.NewMockInspector(gCtrl)
+				kcr = kernels.NewMockCoverageReporter(gCtrl)
+				rt = rollout.NewMockTracker(gCtrl)
+				ar = alerts.NewMockReconciler(gCtrl)
+				fr = firmware.NewMockReconciler(gCtrl)
+				itr = intree.NewMockReconciler(gCtrl)
+				rv = resources.NewMockVerifier(gCtrl)
 				c = client.NewMockClient(gCtrl)
 			})
 
@@ -82,12 +119,13 @@ var _ = Describe("DeviceConfigReconciler", func() {
 				BeforeEach(func() {
 					s := scheme.Scheme
 
-					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, cu, nsv)
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
 
 					gomock.InOrder(
 						c.EXPECT().
 							Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).
 							Return(apierrors.NewNotFound(schema.GroupResource{Resource: "deviceconfigs"}, testDeviceConfigName)),
+						rt.EXPECT().Forget(req.NamespacedName),
 					)
 				})
 
@@ -102,7 +140,7 @@ var _ = Describe("DeviceConfigReconciler", func() {
 				BeforeEach(func() {
 					s := scheme.Scheme
 
-					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, cu, nsv)
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
 
 					gomock.InOrder(
 						c.EXPECT().
@@ -124,7 +162,7 @@ var _ = Describe("DeviceConfigReconciler", func() {
 					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
 					Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
 
-					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, cu, nsv)
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
 
 					gomock.InOrder(
 						c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
@@ -137,7 +175,15 @@ var _ = Describe("DeviceConfigReconciler", func() {
 						nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, dc).Return(nil),
 						fu.EXPECT().ContainsDeletionFinalizer(dc).Return(false),
 						fu.EXPECT().AddDeletionFinalizer(ctx, dc).Return(nil),
-						mr.EXPECT().ReconcileModule(ctx, dc).Return(nil),
+						nlu.EXPECT().SyncComputedNodeLabels(ctx, dc).Return(nil),
+						kcr.EXPECT().GetKernelCoverage(ctx, dc).Return(nil, nil),
+						fr.EXPECT().ReconcileFirmware(ctx, dc).Return(nil, nil),
+						itr.EXPECT().ReconcileInTreeModules(ctx, dc).Return(nil, nil),
+						be.EXPECT().ReconcileModule(ctx, dc).Return(nil),
+						ar.EXPECT().ReconcilePrometheusRule(ctx, dc).Return(nil),
+						rt.EXPECT().GetProgress(ctx, dc).Return(&rollout.Progress{}, nil),
+						dpi.EXPECT().GetNodeFailures(ctx, dc).Return(nil, nil),
+						rv.EXPECT().GetResourceShortfalls(ctx, dc).Return(nil, nil),
 						cu.EXPECT().SetConditionsReady(ctx, dc, "Reconciled", gomock.Any()).Return(nil),
 					)
 				})
@@ -155,7 +201,7 @@ var _ = Describe("DeviceConfigReconciler", func() {
 					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
 					Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
 
-					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, cu, nsv)
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
 
 					gomock.InOrder(
 						c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
@@ -168,7 +214,11 @@ var _ = Describe("DeviceConfigReconciler", func() {
 						nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, dc).Return(nil),
 						fu.EXPECT().ContainsDeletionFinalizer(dc).Return(false),
 						fu.EXPECT().AddDeletionFinalizer(ctx, dc).Return(nil),
-						mr.EXPECT().ReconcileModule(ctx, dc).Return(errors.New("some-error")),
+						nlu.EXPECT().SyncComputedNodeLabels(ctx, dc).Return(nil),
+						kcr.EXPECT().GetKernelCoverage(ctx, dc).Return(nil, nil),
+						fr.EXPECT().ReconcileFirmware(ctx, dc).Return(nil, nil),
+						itr.EXPECT().ReconcileInTreeModules(ctx, dc).Return(nil, nil),
+						be.EXPECT().ReconcileModule(ctx, dc).Return(errors.New("some-error")),
 						cu.EXPECT().SetConditionsErrored(ctx, dc, conditions.ReasonModuleFailed, gomock.Any()).Return(nil),
 					)
 				})
@@ -181,6 +231,74 @@ var _ = Describe("DeviceConfigReconciler", func() {
 				})
 			})
 
+			When("a reconcile PrometheusRule error occurs", func() {
+				BeforeEach(func() {
+					s := scheme.Scheme
+					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+					Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
+
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+					gomock.InOrder(
+						c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+							func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+								d.ObjectMeta = dc.ObjectMeta
+								d.Spec = dc.Spec
+								return nil
+							},
+						),
+						nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, dc).Return(nil),
+						fu.EXPECT().ContainsDeletionFinalizer(dc).Return(false),
+						fu.EXPECT().AddDeletionFinalizer(ctx, dc).Return(nil),
+						nlu.EXPECT().SyncComputedNodeLabels(ctx, dc).Return(nil),
+						kcr.EXPECT().GetKernelCoverage(ctx, dc).Return(nil, nil),
+						fr.EXPECT().ReconcileFirmware(ctx, dc).Return(nil, nil),
+						itr.EXPECT().ReconcileInTreeModules(ctx, dc).Return(nil, nil),
+						be.EXPECT().ReconcileModule(ctx, dc).Return(nil),
+						ar.EXPECT().ReconcilePrometheusRule(ctx, dc).Return(errors.New("some-error")),
+						cu.EXPECT().SetConditionsErrored(ctx, dc, conditions.ReasonPrometheusRuleFailed, gomock.Any()).Return(nil),
+					)
+				})
+
+				It("should return the respective error", func() {
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("some-error"))
+					Expect(res.Requeue).To(BeFalse())
+				})
+			})
+
+			When("a sync computed node labels error occurs", func() {
+				BeforeEach(func() {
+					s := scheme.Scheme
+					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+					Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
+
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+					gomock.InOrder(
+						c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+							func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+								d.ObjectMeta = dc.ObjectMeta
+								d.Spec = dc.Spec
+								return nil
+							},
+						),
+						nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, dc).Return(nil),
+						fu.EXPECT().ContainsDeletionFinalizer(dc).Return(true),
+						nlu.EXPECT().SyncComputedNodeLabels(ctx, dc).Return(errors.New("some-error")),
+						cu.EXPECT().SetConditionsErrored(ctx, dc, conditions.ReasonNodeLabelsFailed, gomock.Any()).Return(nil),
+					)
+				})
+
+				It("should return the respective error", func() {
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("some-error"))
+					Expect(res.Requeue).To(BeFalse())
+				})
+			})
+
 			Context("that does not contain a finalizer", func() {
 				When("an add finalizer error occurs", func() {
 					BeforeEach(func() {
@@ -188,7 +306,7 @@ var _ = Describe("DeviceConfigReconciler", func() {
 						Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
 						Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
 
-						r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, cu, nsv)
+						r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
 
 						gomock.InOrder(
 							c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
@@ -250,14 +368,24 @@ var _ = Describe("DeviceConfigReconciler", func() {
 							return nil
 						},
 					),
+					c.EXPECT().Update(ctx, gomock.Any()).Return(nil),
 				)
 
 				fakeRecorder = record.NewFakeRecorder(1)
 				r = NewDeviceConfigReconciler(c, s, fakeRecorder,
-					module.NewReconciler(c, s),
+					module.NewReconciler(c, s, fakeRecorder, ""),
 					finalizers.NewUpdater(c),
 					conditions.NewUpdater(c),
 					nsv,
+					nodelabels.NewUpdater(c),
+					driverpods.NewInspector(c, ""),
+					nil,
+					kernels.NewCoverageReporter(c),
+					rollout.NewTracker(c, ""),
+					alerts.NewReconciler(c, s, ""),
+					firmware.NewReconciler(c, s, ""),
+					intree.NewReconciler(c, s, ""),
+					resources.NewVerifier(c, ""),
 				)
 
 				res, err := r.Reconcile(ctx, req)
@@ -266,6 +394,755 @@ var _ = Describe("DeviceConfigReconciler", func() {
 				msg := <-fakeRecorder.Events
 				Expect(msg).To(ContainSubstring("Conflicting DeviceConfig NodeSelectors found"))
 			})
+
+			It("should report the conflicting DeviceConfigs in its status", func() {
+				conflicts := []examplecomv1alpha1.DeviceConfigConflict{
+					{Namespace: "other-namespace", Name: "other", Nodes: []string{"node-a"}, NodeCount: 1},
+				}
+				nsv.
+					EXPECT().
+					CheckDeviceConfigForConflictingNodeSelector(ctx, dc).
+					Return(&nodeselector.ConflictError{Name: dc.Name, Conflicts: conflicts})
+
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+				var updated *examplecomv1alpha1.DeviceConfig
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.ObjectMeta = dc.ObjectMeta
+							d.Spec = dc.Spec
+							return nil
+						},
+					),
+					c.EXPECT().Update(ctx, gomock.Any()).DoAndReturn(
+						func(_ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							updated = d
+							return nil
+						},
+					),
+				)
+
+				fakeRecorder = record.NewFakeRecorder(1)
+				r = NewDeviceConfigReconciler(c, s, fakeRecorder, nil, nil, conditions.NewUpdater(c), nsv, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(updated.Status.Conflicts).To(Equal(conflicts))
+				errored := meta.FindStatusCondition(updated.Status.Conditions, conditions.Errored)
+				Expect(errored).ToNot(BeNil())
+				Expect(errored.Reason).To(Equal(conditions.ReasonConflictingNodeSelector))
+				Expect(errored.Message).To(ContainSubstring("other-namespace/other on nodes node-a"))
+
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring("other-namespace/other"))
+			})
+
+			It("should clear a previously reported conflict once it is resolved", func() {
+				fu := finalizers.NewMockUpdater(gCtrl)
+				be := backend.NewMockBackend(gCtrl)
+				cu := conditions.NewMockUpdater(gCtrl)
+				nlu := nodelabels.NewMockUpdater(gCtrl)
+				dpi := driverpods.NewMockInspector(gCtrl)
+				kcr := kernels.NewMockCoverageReporter(gCtrl)
+				rt := rollout.NewMockTracker(gCtrl)
+				ar := alerts.NewMockReconciler(gCtrl)
+				fr := firmware.NewMockReconciler(gCtrl)
+				itr := intree.NewMockReconciler(gCtrl)
+				rv := resources.NewMockVerifier(gCtrl)
+
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.ObjectMeta = dc.ObjectMeta
+							d.Spec = dc.Spec
+							d.Status.Conflicts = []examplecomv1alpha1.DeviceConfigConflict{{Name: "other"}}
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(nil, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(nil, nil),
+					cu.EXPECT().SetConditionsReady(ctx, gomock.Any(), "Reconciled", gomock.Any()).DoAndReturn(
+						func(_ context.Context, d *examplecomv1alpha1.DeviceConfig, _, _ string) error {
+							Expect(d.Status.Conflicts).To(BeEmpty())
+							return nil
+						},
+					),
+				)
+
+				r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("with a DeviceConfig conflicting with a deleted peer", func() {
+			It("should clear the conflict once the peer is deleted", func() {
+				ctx := context.TODO()
+				gCtrl := gomock.NewController(GinkgoT())
+				c := client.NewMockClient(gCtrl)
+				fu := finalizers.NewMockUpdater(gCtrl)
+				be := backend.NewMockBackend(gCtrl)
+				nlu := nodelabels.NewMockUpdater(gCtrl)
+				dpi := driverpods.NewMockInspector(gCtrl)
+				kcr := kernels.NewMockCoverageReporter(gCtrl)
+				rt := rollout.NewMockTracker(gCtrl)
+				ar := alerts.NewMockReconciler(gCtrl)
+				fr := firmware.NewMockReconciler(gCtrl)
+				itr := intree.NewMockReconciler(gCtrl)
+				rv := resources.NewMockVerifier(gCtrl)
+
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+				dc := makeTestDeviceConfig()
+				dc.Namespace = "a-namespace"
+				dc.Spec.NodeSelector = map[string]string{"pool": "a"}
+				peer := makeTestDeviceConfig()
+				peer.Namespace = "other-namespace"
+				peer.Name = "other"
+				peer.Spec.NodeSelector = map[string]string{"pool": "a"}
+				dcReq := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name}}
+
+				idx := nodeselector.NewIndex()
+				idx.UpsertNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"pool": "a"}}})
+				idx.UpsertDeviceConfig(peer)
+
+				r := NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(10), be, fu, conditions.NewUpdater(c),
+					nodeselector.NewValidator(idx), nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+				var updated *examplecomv1alpha1.DeviceConfig
+				getDeviceConfig := func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+					d.ObjectMeta = dc.ObjectMeta
+					d.Spec = dc.Spec
+					if updated != nil {
+						d.Status = updated.Status
+					}
+					return nil
+				}
+				updateStatus := func(_ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+					updated = d.DeepCopy()
+					return nil
+				}
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, dcReq.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(getDeviceConfig),
+					c.EXPECT().Update(ctx, gomock.Any()).DoAndReturn(updateStatus),
+				)
+
+				res, err := r.Reconcile(ctx, dcReq)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.RequeueAfter).To(BeNumerically(">", 0))
+				Expect(updated.Status.Conflicts).To(HaveLen(1))
+				Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, conditions.Errored)).To(BeTrue())
+
+				// The deleted peer requeues the DeviceConfig it conflicted with.
+				idx.DeleteDeviceConfig(types.NamespacedName{Namespace: peer.Namespace, Name: peer.Name})
+				Expect(r.findConflictingDeviceConfigs(peer)).To(Equal([]reconcile.Request{dcReq}))
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, dcReq.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(getDeviceConfig),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(nil, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(nil, nil),
+					c.EXPECT().Update(ctx, gomock.Any()).DoAndReturn(updateStatus),
+				)
+
+				res, err = r.Reconcile(ctx, dcReq)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.RequeueAfter).To(BeZero())
+				Expect(updated.Status.Conflicts).To(BeEmpty())
+				Expect(meta.IsStatusConditionFalse(updated.Status.Conditions, conditions.Errored)).To(BeTrue())
+				Expect(r.findConflictingDeviceConfigs(peer)).To(BeEmpty())
+			})
+		})
+
+		Context("with a node selector index that has not synced yet", func() {
+			It("should return an error so that the request is retried", func() {
+				ctx := context.TODO()
+				dc := makeTestDeviceConfig()
+				gCtrl := gomock.NewController(GinkgoT())
+				nsv := nodeselector.NewMockValidator(gCtrl)
+				c := client.NewMockClient(gCtrl)
+				fakeRecorder := record.NewFakeRecorder(1)
+
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.ObjectMeta = dc.ObjectMeta
+							d.Spec = dc.Spec
+							return nil
+						},
+					),
+					nsv.EXPECT().
+						CheckDeviceConfigForConflictingNodeSelector(ctx, dc).
+						Return(nodeselector.ErrIndexNotSynced),
+				)
+
+				r := NewDeviceConfigReconciler(c, s, fakeRecorder, nil, nil, nil, nsv, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).To(MatchError(nodeselector.ErrIndexNotSynced))
+				Expect(fakeRecorder.Events).To(BeEmpty())
+			})
+		})
+
+		Context("with failing driver pods", func() {
+			var (
+				ctx          context.Context
+				gCtrl        *gomock.Controller
+				c            *client.MockClient
+				fu           *finalizers.MockUpdater
+				nsv          *nodeselector.MockValidator
+				nlu          *nodelabels.MockUpdater
+				be           *backend.MockBackend
+				dpi          *driverpods.MockInspector
+				kcr          *kernels.MockCoverageReporter
+				rt           *rollout.MockTracker
+				ar           *alerts.MockReconciler
+				fr           *firmware.MockReconciler
+				itr          *intree.MockReconciler
+				rv           *resources.MockVerifier
+				cu           *conditions.MockUpdater
+				fakeRecorder *record.FakeRecorder
+				r            *DeviceConfigReconciler
+				failures     []examplecomv1alpha1.NodeFailure
+			)
+
+			BeforeEach(func() {
+				ctx = context.TODO()
+				gCtrl = gomock.NewController(GinkgoT())
+				c = client.NewMockClient(gCtrl)
+				fu = finalizers.NewMockUpdater(gCtrl)
+				nsv = nodeselector.NewMockValidator(gCtrl)
+				nlu = nodelabels.NewMockUpdater(gCtrl)
+				be = backend.NewMockBackend(gCtrl)
+				dpi = driverpods.NewMockInspector(gCtrl)
+				kcr = kernels.NewMockCoverageReporter(gCtrl)
+				rt = rollout.NewMockTracker(gCtrl)
+				ar = alerts.NewMockReconciler(gCtrl)
+				fr = firmware.NewMockReconciler(gCtrl)
+				itr = intree.NewMockReconciler(gCtrl)
+				rv = resources.NewMockVerifier(gCtrl)
+				cu = conditions.NewMockUpdater(gCtrl)
+				fakeRecorder = record.NewFakeRecorder(10)
+				r = NewDeviceConfigReconciler(c, scheme.Scheme, fakeRecorder, be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+				failures = []examplecomv1alpha1.NodeFailure{}
+				for i := 0; i < driverpods.MaxReportedNodeFailures+1; i++ {
+					failures = append(failures, examplecomv1alpha1.NodeFailure{
+						Node:          fmt.Sprintf("node-%02d", i),
+						KernelVersion: "5.14.0-70.el9.x86_64",
+						Reason:        "ImagePullBackOff",
+					})
+				}
+			})
+
+			expectReconcile := func(previous []examplecomv1alpha1.NodeFailure, updated **examplecomv1alpha1.DeviceConfig) {
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.Name = testDeviceConfigName
+							d.Status.NodeFailures = previous
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(failures, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(nil, nil),
+					cu.EXPECT().SetConditionsDegraded(ctx, gomock.Any(), conditions.ReasonDriverPodsFailed, gomock.Any()).DoAndReturn(
+						func(_ context.Context, d *examplecomv1alpha1.DeviceConfig, _, _ string) error {
+							*updated = d
+							return nil
+						},
+					),
+				)
+			}
+
+			It("should report the failing nodes and set the Degraded condition", func() {
+				var updated *examplecomv1alpha1.DeviceConfig
+				expectReconcile(nil, &updated)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(updated.Status.NodeFailures).To(Equal(failures[:driverpods.MaxReportedNodeFailures]))
+				Expect(updated.Status.FailedNodeCount).To(Equal(driverpods.MaxReportedNodeFailures + 1))
+
+				Expect(fakeRecorder.Events).To(HaveLen(1))
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring(conditions.ReasonDriverPodsFailed))
+				Expect(msg).To(ContainSubstring("node-00 (ImagePullBackOff, kernel 5.14.0-70.el9.x86_64)"))
+			})
+
+			It("should not emit an event for already reported failures", func() {
+				var updated *examplecomv1alpha1.DeviceConfig
+				expectReconcile(failures[:driverpods.MaxReportedNodeFailures], &updated)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeRecorder.Events).To(BeEmpty())
+			})
+		})
+
+		Context("with nodes running a kernel without a kernel mapping", func() {
+			It("should report the unmapped kernels and keep reconciling", func() {
+				ctx := context.TODO()
+				gCtrl := gomock.NewController(GinkgoT())
+				c := client.NewMockClient(gCtrl)
+				fu := finalizers.NewMockUpdater(gCtrl)
+				nsv := nodeselector.NewMockValidator(gCtrl)
+				nlu := nodelabels.NewMockUpdater(gCtrl)
+				be := backend.NewMockBackend(gCtrl)
+				dpi := driverpods.NewMockInspector(gCtrl)
+				kcr := kernels.NewMockCoverageReporter(gCtrl)
+				rt := rollout.NewMockTracker(gCtrl)
+				ar := alerts.NewMockReconciler(gCtrl)
+				fr := firmware.NewMockReconciler(gCtrl)
+				itr := intree.NewMockReconciler(gCtrl)
+				rv := resources.NewMockVerifier(gCtrl)
+				cu := conditions.NewMockUpdater(gCtrl)
+				fakeRecorder := record.NewFakeRecorder(10)
+
+				coverage := []examplecomv1alpha1.KernelCoverage{
+					{KernelVersion: "5.14.0-70.el9.x86_64", Mapping: "^.*$", Image: "driver:1.0-5.14.0-70.el9.x86_64", NodeCount: 2},
+					{KernelVersion: "5.15.0-48-generic", NodeCount: 1},
+				}
+				var updated *examplecomv1alpha1.DeviceConfig
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.Name = testDeviceConfigName
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(coverage, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(nil, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(nil, nil),
+					cu.EXPECT().SetConditionsReady(ctx, gomock.Any(), "Reconciled", gomock.Any()).DoAndReturn(
+						func(_ context.Context, d *examplecomv1alpha1.DeviceConfig, _, _ string) error {
+							updated = d
+							return nil
+						},
+					),
+				)
+
+				r := NewDeviceConfigReconciler(c, scheme.Scheme, fakeRecorder, be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updated.Status.KernelCoverage).To(Equal(coverage))
+				Expect(updated.Status.UnmappedKernels).To(Equal([]string{"5.15.0-48-generic"}))
+
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring("UnmappedKernels"))
+				Expect(msg).To(ContainSubstring("5.15.0-48-generic"))
+			})
+		})
+
+		Context("with nodes advertising fewer extended resources than expected", func() {
+			It("should report the nodes and set the Degraded condition", func() {
+				ctx := context.TODO()
+				gCtrl := gomock.NewController(GinkgoT())
+				c := client.NewMockClient(gCtrl)
+				fu := finalizers.NewMockUpdater(gCtrl)
+				nsv := nodeselector.NewMockValidator(gCtrl)
+				nlu := nodelabels.NewMockUpdater(gCtrl)
+				be := backend.NewMockBackend(gCtrl)
+				dpi := driverpods.NewMockInspector(gCtrl)
+				kcr := kernels.NewMockCoverageReporter(gCtrl)
+				rt := rollout.NewMockTracker(gCtrl)
+				ar := alerts.NewMockReconciler(gCtrl)
+				fr := firmware.NewMockReconciler(gCtrl)
+				itr := intree.NewMockReconciler(gCtrl)
+				rv := resources.NewMockVerifier(gCtrl)
+				cu := conditions.NewMockUpdater(gCtrl)
+				fakeRecorder := record.NewFakeRecorder(10)
+
+				shortfalls := []examplecomv1alpha1.NodeResourceShortfall{
+					{Node: "node-a", Reason: resources.ReasonNotRegistered, Expected: 2},
+					{Node: "node-b", Reason: resources.ReasonInsufficient, Expected: 2, Allocatable: 1},
+				}
+				var updated *examplecomv1alpha1.DeviceConfig
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.Name = testDeviceConfigName
+							d.Spec.ExtendedResource = &examplecomv1alpha1.ExtendedResourceSpec{Name: "example.com/device"}
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(nil, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(shortfalls, nil),
+					cu.EXPECT().SetConditionsDegraded(ctx, gomock.Any(), conditions.ReasonResourceShortfall, gomock.Any()).DoAndReturn(
+						func(_ context.Context, d *examplecomv1alpha1.DeviceConfig, _, _ string) error {
+							updated = d
+							return nil
+						},
+					),
+				)
+
+				r := NewDeviceConfigReconciler(c, scheme.Scheme, fakeRecorder, be, fu, cu, nsv, nlu, dpi, nil, kcr, rt, ar, fr, itr, rv)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updated.Status.ResourceShortfalls).To(Equal(shortfalls))
+				Expect(updated.Status.ResourceShortfallCount).To(Equal(2))
+
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring(conditions.ReasonResourceShortfall))
+				Expect(msg).To(ContainSubstring("node-a (DevicePluginNotRegistered), node-b (InsufficientDevices, 1/2)"))
+			})
+		})
+
+		Context("with preflight enabled", func() {
+			var (
+				ctx          context.Context
+				gCtrl        *gomock.Controller
+				c            *client.MockClient
+				fu           *finalizers.MockUpdater
+				nsv          *nodeselector.MockValidator
+				nlu          *nodelabels.MockUpdater
+				be           *backend.MockBackend
+				dpi          *driverpods.MockInspector
+				kcr          *kernels.MockCoverageReporter
+				rt           *rollout.MockTracker
+				ar           *alerts.MockReconciler
+				fr           *firmware.MockReconciler
+				itr          *intree.MockReconciler
+				rv           *resources.MockVerifier
+				cu           *conditions.MockUpdater
+				pv           *preflight.MockValidator
+				fakeRecorder *record.FakeRecorder
+				r            *DeviceConfigReconciler
+			)
+
+			BeforeEach(func() {
+				ctx = context.TODO()
+				gCtrl = gomock.NewController(GinkgoT())
+				c = client.NewMockClient(gCtrl)
+				fu = finalizers.NewMockUpdater(gCtrl)
+				nsv = nodeselector.NewMockValidator(gCtrl)
+				nlu = nodelabels.NewMockUpdater(gCtrl)
+				be = backend.NewMockBackend(gCtrl)
+				dpi = driverpods.NewMockInspector(gCtrl)
+				kcr = kernels.NewMockCoverageReporter(gCtrl)
+				rt = rollout.NewMockTracker(gCtrl)
+				ar = alerts.NewMockReconciler(gCtrl)
+				fr = firmware.NewMockReconciler(gCtrl)
+				itr = intree.NewMockReconciler(gCtrl)
+				rv = resources.NewMockVerifier(gCtrl)
+				cu = conditions.NewMockUpdater(gCtrl)
+				pv = preflight.NewMockValidator(gCtrl)
+				fakeRecorder = record.NewFakeRecorder(10)
+				r = NewDeviceConfigReconciler(c, scheme.Scheme, fakeRecorder, be, fu, cu, nsv, nlu, dpi, pv, kcr, rt, ar, fr, itr, rv)
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.Name = testDeviceConfigName
+							d.Spec.Preflight = true
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+				)
+			})
+
+			It("should not roll out a driver version which failed the preflight", func() {
+				status := &examplecomv1alpha1.PreflightStatus{
+					DriverVersion: "2.0",
+					Kernels: []examplecomv1alpha1.KernelPreflight{
+						{KernelVersion: "5.14.0-70.el9.x86_64", Image: "driver:2.0-5.14.0-70.el9.x86_64", Message: "image not found"},
+					},
+				}
+				var updated *examplecomv1alpha1.DeviceConfig
+				gomock.InOrder(
+					pv.EXPECT().ValidateDriverImages(ctx, gomock.Any()).Return(status, nil),
+					cu.EXPECT().SetConditionsErrored(ctx, gomock.Any(), conditions.ReasonPreflightFailed, gomock.Any()).DoAndReturn(
+						func(_ context.Context, d *examplecomv1alpha1.DeviceConfig, _, _ string) error {
+							updated = d
+							return nil
+						},
+					),
+				)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updated.Status.Preflight).To(Equal(status))
+
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring("driver version 2.0 is not available for kernel(s) 5.14.0-70.el9.x86_64"))
+			})
+
+			It("should roll out a driver version which passed the preflight", func() {
+				gomock.InOrder(
+					pv.EXPECT().ValidateDriverImages(ctx, gomock.Any()).Return(&examplecomv1alpha1.PreflightStatus{Passed: true}, nil),
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, nil),
+					itr.EXPECT().ReconcileInTreeModules(ctx, gomock.Any()).Return(nil, nil),
+					be.EXPECT().ReconcileModule(ctx, gomock.Any()).Return(nil),
+					ar.EXPECT().ReconcilePrometheusRule(ctx, gomock.Any()).Return(nil),
+					rt.EXPECT().GetProgress(ctx, gomock.Any()).Return(&rollout.Progress{}, nil),
+					dpi.EXPECT().GetNodeFailures(ctx, gomock.Any()).Return(nil, nil),
+					rv.EXPECT().GetResourceShortfalls(ctx, gomock.Any()).Return(nil, nil),
+					cu.EXPECT().SetConditionsReady(ctx, gomock.Any(), "Reconciled", gomock.Any()).Return(nil),
+				)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("with an incompatible firmware", func() {
+			var (
+				ctx          context.Context
+				gCtrl        *gomock.Controller
+				c            *client.MockClient
+				fu           *finalizers.MockUpdater
+				nsv          *nodeselector.MockValidator
+				nlu          *nodelabels.MockUpdater
+				be           *backend.MockBackend
+				dpi          *driverpods.MockInspector
+				kcr          *kernels.MockCoverageReporter
+				rt           *rollout.MockTracker
+				ar           *alerts.MockReconciler
+				fr           *firmware.MockReconciler
+				itr          *intree.MockReconciler
+				rv           *resources.MockVerifier
+				cu           *conditions.MockUpdater
+				pv           *preflight.MockValidator
+				fakeRecorder *record.FakeRecorder
+				r            *DeviceConfigReconciler
+			)
+
+			BeforeEach(func() {
+				ctx = context.TODO()
+				gCtrl = gomock.NewController(GinkgoT())
+				c = client.NewMockClient(gCtrl)
+				fu = finalizers.NewMockUpdater(gCtrl)
+				nsv = nodeselector.NewMockValidator(gCtrl)
+				nlu = nodelabels.NewMockUpdater(gCtrl)
+				be = backend.NewMockBackend(gCtrl)
+				dpi = driverpods.NewMockInspector(gCtrl)
+				kcr = kernels.NewMockCoverageReporter(gCtrl)
+				rt = rollout.NewMockTracker(gCtrl)
+				ar = alerts.NewMockReconciler(gCtrl)
+				fr = firmware.NewMockReconciler(gCtrl)
+				itr = intree.NewMockReconciler(gCtrl)
+				rv = resources.NewMockVerifier(gCtrl)
+				cu = conditions.NewMockUpdater(gCtrl)
+				pv = preflight.NewMockValidator(gCtrl)
+				fakeRecorder = record.NewFakeRecorder(10)
+				r = NewDeviceConfigReconciler(c, scheme.Scheme, fakeRecorder, be, fu, cu, nsv, nlu, dpi, pv, kcr, rt, ar, fr, itr, rv)
+
+				gomock.InOrder(
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.Name = testDeviceConfigName
+							d.Spec.DriverVersion = "2.0"
+							d.Spec.Firmware = &examplecomv1alpha1.FirmwareSpec{Version: "3.0"}
+							return nil
+						},
+					),
+					nsv.EXPECT().CheckDeviceConfigForConflictingNodeSelector(ctx, gomock.Any()).Return(nil),
+					fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+					nlu.EXPECT().SyncComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+					kcr.EXPECT().GetKernelCoverage(ctx, gomock.Any()).Return(nil, nil),
+				)
+			})
+
+			It("should not roll out the driver and report the incompatibility", func() {
+				gomock.InOrder(
+					fr.EXPECT().ReconcileFirmware(ctx, gomock.Any()).Return(nil, fmt.Errorf("firmware 3.0 requires driver 3: %w", firmware.ErrIncompatibleFirmware)),
+					cu.EXPECT().SetConditionsErrored(ctx, gomock.Any(), conditions.ReasonFirmwareIncompatible, gomock.Any()).Return(nil),
+				)
+
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+
+				msg := <-fakeRecorder.Events
+				Expect(msg).To(ContainSubstring("firmware 3.0 requires driver 3"))
+			})
+		})
+
+		Context("with a KMM ModuleLoader pod", func() {
+			It("should map it to the DeviceConfig owning its Module", func() {
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+				Expect(kmmv1beta1.AddToScheme(s)).To(Succeed())
+
+				dc := makeTestDeviceConfig()
+				dc.Namespace = "a-namespace"
+				dc.UID = "a-uid"
+				m := &kmmv1beta1.Module{
+					ObjectMeta: metav1.ObjectMeta{Name: module.GetModuleName(dc), Namespace: dc.Namespace},
+				}
+				Expect(ctrl.SetControllerReference(dc, m, s)).To(Succeed())
+
+				mapper := meta.NewDefaultRESTMapper(nil)
+				mapper.Add(kmmv1beta1.GroupVersion.WithKind("Module"), meta.RESTScopeNamespace)
+
+				r := NewDeviceConfigReconciler(
+					fake.NewClientBuilder().WithScheme(s).WithRESTMapper(mapper).WithObjects(m).Build(),
+					s, record.NewFakeRecorder(1), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				)
+
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "a-pod",
+						Namespace: dc.Namespace,
+						Labels: map[string]string{
+							module.ModuleNameLabel: m.Name,
+							module.RoleLabel:       module.ModuleLoaderRole,
+						},
+					},
+				}
+
+				Expect(r.findDriverPodsOwner(context.TODO(), pod)).To(Equal([]reconcile.Request{
+					{NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name}},
+				}))
+			})
+		})
+
+		Context("with a relabelled node", func() {
+			It("should map it to the DeviceConfigs with node groups or set-based selectors", func() {
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+				grouped := makeTestDeviceConfig()
+				grouped.Name = "grouped"
+				grouped.Namespace = "a-namespace"
+				grouped.Spec.NodeSelector = map[string]string{"example.com/accelerator": "true"}
+				grouped.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+					{Name: "large", NodeSelector: map[string]string{"example.com/size": "large"}},
+				}
+				setBased := makeTestDeviceConfig()
+				setBased.Name = "set-based"
+				setBased.Namespace = "a-namespace"
+				setBased.Spec.NodeLabelSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "example.com/accelerator", Operator: metav1.LabelSelectorOpExists},
+					},
+				}
+				plain := makeTestDeviceConfig()
+				plain.Name = "plain"
+				plain.Namespace = "a-namespace"
+				plain.Spec.NodeSelector = map[string]string{"example.com/accelerator": "true"}
+
+				r := NewDeviceConfigReconciler(
+					fake.NewClientBuilder().WithScheme(s).WithObjects(grouped, setBased, plain).Build(),
+					s, record.NewFakeRecorder(1), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				)
+
+				node := &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "a-node",
+						Labels: map[string]string{
+							"example.com/accelerator": "true",
+							"example.com/size":        "large",
+						},
+					},
+				}
+
+				Expect(r.findSetBasedDeviceConfigs(context.TODO(), node)).To(ConsistOf(
+					reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a-namespace", Name: "grouped"}},
+					reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "a-namespace", Name: "set-based"}},
+				))
+			})
+		})
+
+		Context("with a native backend pod", func() {
+			It("should map it to the DeviceConfig owning its DaemonSet", func() {
+				s := scheme.Scheme
+				Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+				dc := makeTestDeviceConfig()
+				dc.Namespace = "a-namespace"
+				dc.UID = "a-uid"
+				ds := &appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      native.GetDaemonSetName(dc, "5.14.0-70.13.1.el9_0.x86_64"),
+						Namespace: dc.Namespace,
+						UID:       "a-daemonset-uid",
+					},
+				}
+				Expect(ctrl.SetControllerReference(dc, ds, s)).To(Succeed())
+
+				r := NewDeviceConfigReconciler(
+					fake.NewClientBuilder().WithScheme(s).WithObjects(ds).Build(),
+					s, record.NewFakeRecorder(1), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				)
+
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "a-pod",
+						Namespace: dc.Namespace,
+						Labels: map[string]string{
+							module.ModuleNameLabel: module.GetModuleName(dc),
+							module.RoleLabel:       module.ModuleLoaderRole,
+							native.ManagedByLabel:  native.ManagedByValue,
+						},
+					},
+				}
+				Expect(ctrl.SetControllerReference(ds, pod, s)).To(Succeed())
+
+				Expect(r.findDriverPodsOwner(context.TODO(), pod)).To(Equal([]reconcile.Request{
+					{NamespacedName: types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name}},
+				}))
+			})
 		})
 
 		Context("with a deleted DeviceConfig", func() {
@@ -274,17 +1151,35 @@ var _ = Describe("DeviceConfigReconciler", func() {
 
 			var (
 				gCtrl *gomock.Controller
-				mr    *module.MockReconciler
+				be    *backend.MockBackend
 				fu    *finalizers.MockUpdater
+				cu    *conditions.MockUpdater
+				nlu   *nodelabels.MockUpdater
+				dpi   *driverpods.MockInspector
+				rt    *rollout.MockTracker
+				ar    *alerts.MockReconciler
+				fr    *firmware.MockReconciler
+				itr   *intree.MockReconciler
+				rv    *resources.MockVerifier
 				r     *DeviceConfigReconciler
 				c     *client.MockClient
 			)
 
 			BeforeEach(func() {
 				gCtrl = gomock.NewController(GinkgoT())
-				mr = module.NewMockReconciler(gCtrl)
+				be = backend.NewMockBackend(gCtrl)
 				fu = finalizers.NewMockUpdater(gCtrl)
+				cu = conditions.NewMockUpdater(gCtrl)
+				nlu = nodelabels.NewMockUpdater(gCtrl)
+				dpi = driverpods.NewMockInspector(gCtrl)
+				rt = rollout.NewMockTracker(gCtrl)
+				ar = alerts.NewMockReconciler(gCtrl)
+				fr = firmware.NewMockReconciler(gCtrl)
+				itr = intree.NewMockReconciler(gCtrl)
+				rv = resources.NewMockVerifier(gCtrl)
 				c = client.NewMockClient(gCtrl)
+
+				rt.EXPECT().Forget(req.NamespacedName)
 			})
 
 			Context("which contains a deletion finalizer", func() {
@@ -304,11 +1199,11 @@ var _ = Describe("DeviceConfigReconciler", func() {
 							),
 						)
 
-						r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, nil, nil)
+						r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nil, nlu, dpi, nil, nil, rt, ar, fr, itr, rv)
 
 						gomock.InOrder(
 							fu.EXPECT().ContainsDeletionFinalizer(dc).Return(true),
-							mr.EXPECT().DeleteModule(ctx, dc).Return(errors.New("something went wrong")),
+							be.EXPECT().DeleteModule(ctx, dc).Return(errors.New("something went wrong")),
 						)
 
 						res, err := r.Reconcile(ctx, req)
@@ -337,11 +1232,16 @@ var _ = Describe("DeviceConfigReconciler", func() {
 								),
 							)
 
-							r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, nil, nil)
+							r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nil, nlu, dpi, nil, nil, rt, ar, fr, itr, rv)
 
 							gomock.InOrder(
 								fu.EXPECT().ContainsDeletionFinalizer(dc).Return(true),
-								mr.EXPECT().DeleteModule(ctx, dc).Return(nil),
+								be.EXPECT().DeleteModule(ctx, dc).Return(nil),
+								ar.EXPECT().DeletePrometheusRule(ctx, dc).Return(nil),
+								dpi.EXPECT().GetLoadedNodes(ctx, dc).Return(nil, nil),
+								fr.EXPECT().DeleteFirmware(ctx, dc).Return(nil),
+								itr.EXPECT().RestoreInTreeModules(ctx, dc).Return(nil),
+								nlu.EXPECT().RemoveComputedNodeLabels(ctx, dc).Return(nil),
 								fu.EXPECT().RemoveDeletionFinalizer(ctx, dc).Return(nil),
 							)
 
@@ -367,11 +1267,16 @@ var _ = Describe("DeviceConfigReconciler", func() {
 								),
 							)
 
-							r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), mr, fu, nil, nil)
+							r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nil, nlu, dpi, nil, nil, rt, ar, fr, itr, rv)
 
 							gomock.InOrder(
 								fu.EXPECT().ContainsDeletionFinalizer(dc).Return(true),
-								mr.EXPECT().DeleteModule(ctx, dc).Return(nil),
+								be.EXPECT().DeleteModule(ctx, dc).Return(nil),
+								ar.EXPECT().DeletePrometheusRule(ctx, dc).Return(nil),
+								dpi.EXPECT().GetLoadedNodes(ctx, dc).Return(nil, nil),
+								fr.EXPECT().DeleteFirmware(ctx, dc).Return(nil),
+								itr.EXPECT().RestoreInTreeModules(ctx, dc).Return(nil),
+								nlu.EXPECT().RemoveComputedNodeLabels(ctx, dc).Return(nil),
 								fu.EXPECT().RemoveDeletionFinalizer(ctx, dc).Return(errors.New("some error")),
 							)
 
@@ -381,6 +1286,175 @@ var _ = Describe("DeviceConfigReconciler", func() {
 						})
 					})
 				})
+
+				Context("and tracing is enabled", func() {
+					var exporter *tracetest.InMemoryExporter
+
+					BeforeEach(func() {
+						exporter = tracetest.NewInMemoryExporter()
+						tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+					})
+
+					AfterEach(func() {
+						tracing.SetTracerProvider(nil)
+					})
+
+					It("should trace the reconcile of the DeviceConfig and record its error", func() {
+						s := scheme.Scheme
+						Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+						c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+							func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+								d.ObjectMeta = dc.ObjectMeta
+								d.Spec = dc.Spec
+								return nil
+							},
+						)
+
+						r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), be, fu, cu, nil, nlu, dpi, nil, nil, rt, ar, fr, itr, rv)
+
+						// The steps receive the context of the Reconcile span.
+						gomock.InOrder(
+							fu.EXPECT().ContainsDeletionFinalizer(dc).Return(true),
+							be.EXPECT().DeleteModule(gomock.Any(), dc).DoAndReturn(
+								func(ctx context.Context, _ interface{}) error {
+									Expect(trace.SpanFromContext(ctx).IsRecording()).To(BeTrue())
+									return errors.New("something went wrong")
+								},
+							),
+						)
+
+						_, err := r.Reconcile(ctx, req)
+						Expect(err).To(HaveOccurred())
+
+						spans := exporter.GetSpans()
+						Expect(spans).To(HaveLen(1))
+						Expect(spans[0].Name).To(Equal("DeviceConfigReconciler.Reconcile"))
+						Expect(spans[0].Status.Code).To(Equal(codes.Error))
+						Expect(spans[0].Attributes).To(ContainElement(tracing.DeviceConfigNameKey.String(testDeviceConfigName)))
+					})
+				})
+			})
+
+			Context("whose driver is deployed on some nodes", func() {
+				var fakeRecorder *record.FakeRecorder
+
+				getDeviceConfig := func(deleted *examplecomv1alpha1.DeviceConfig) {
+					c.EXPECT().Get(ctx, req.NamespacedName, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ interface{}, _ interface{}, d *examplecomv1alpha1.DeviceConfig, _ ...interface{}) error {
+							d.ObjectMeta = deleted.ObjectMeta
+							d.Spec = deleted.Spec
+							return nil
+						},
+					)
+				}
+
+				BeforeEach(func() {
+					s := scheme.Scheme
+					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+
+					fakeRecorder = record.NewFakeRecorder(2)
+					r = NewDeviceConfigReconciler(c, s, fakeRecorder, be, fu, cu, nil, nlu, dpi, nil, nil, rt, ar, fr, itr, rv)
+				})
+
+				It("should report the remaining nodes and requeue", func() {
+					getDeviceConfig(dc)
+
+					gomock.InOrder(
+						fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+						be.EXPECT().DeleteModule(ctx, gomock.Any()).Return(nil),
+						ar.EXPECT().DeletePrometheusRule(ctx, gomock.Any()).Return(nil),
+						dpi.EXPECT().GetLoadedNodes(ctx, gomock.Any()).Return([]string{"node-a", "node-b"}, nil),
+						cu.EXPECT().SetConditionsTerminating(ctx, gomock.Any(), "Waiting for the driver to be unloaded from 2 node(s)").
+							DoAndReturn(func(_ context.Context, d examplecomv1alpha1.DeviceConfigObject, _ string) error {
+								Expect(d.GetStatus().Teardown).To(Equal(&examplecomv1alpha1.TeardownStatus{
+									RemainingNodes: 2,
+									Nodes:          []string{"node-a", "node-b"},
+								}))
+								return nil
+							}),
+					)
+
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.RequeueAfter).To(Equal(teardownPollInterval))
+				})
+
+				It("should remove the finalizer once the teardown times out", func() {
+					expired := makeTestDeviceConfig(deletedAt(time.Now().Add(-2 * time.Minute)))
+					expired.Spec.Teardown = &examplecomv1alpha1.TeardownSpec{Timeout: &metav1.Duration{Duration: time.Minute}}
+					getDeviceConfig(expired)
+
+					gomock.InOrder(
+						fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+						be.EXPECT().DeleteModule(ctx, gomock.Any()).Return(nil),
+						ar.EXPECT().DeletePrometheusRule(ctx, gomock.Any()).Return(nil),
+						dpi.EXPECT().GetLoadedNodes(ctx, gomock.Any()).Return([]string{"node-a"}, nil),
+						fr.EXPECT().DeleteFirmware(ctx, gomock.Any()).Return(nil),
+						itr.EXPECT().RestoreInTreeModules(ctx, gomock.Any()).Return(nil),
+						nlu.EXPECT().RemoveComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+						fu.EXPECT().RemoveDeletionFinalizer(ctx, gomock.Any()).Return(nil),
+					)
+
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.RequeueAfter).To(BeZero())
+					Expect(fakeRecorder.Events).To(Receive(ContainSubstring("TeardownTimeout")))
+				})
+
+				It("should leave the driver running with the Orphan deletion policy", func() {
+					orphaned := makeTestDeviceConfig(deletedAt(time.Now()))
+					orphaned.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyOrphan
+					getDeviceConfig(orphaned)
+
+					gomock.InOrder(
+						fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+						be.EXPECT().ReleaseModule(ctx, gomock.Any()).Return(nil),
+						fr.EXPECT().ReleaseFirmware(ctx, gomock.Any()).Return(nil),
+						itr.EXPECT().ReleaseInTreeModules(ctx, gomock.Any()).Return(nil),
+						ar.EXPECT().DeletePrometheusRule(ctx, gomock.Any()).Return(nil),
+						fu.EXPECT().RemoveDeletionFinalizer(ctx, gomock.Any()).Return(nil),
+					)
+
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.RequeueAfter).To(BeZero())
+					Expect(fakeRecorder.Events).To(Receive(ContainSubstring("Applied the Orphan deletion policy")))
+				})
+
+				It("should return an error when the driver cannot be retained", func() {
+					retained := makeTestDeviceConfig(deletedAt(time.Now()))
+					retained.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyRetain
+					getDeviceConfig(retained)
+
+					gomock.InOrder(
+						fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+						be.EXPECT().ReleaseModule(ctx, gomock.Any()).Return(errors.New("something went wrong")),
+					)
+
+					_, err := r.Reconcile(ctx, req)
+					Expect(err).To(MatchError(ContainSubstring("failed to release DeviceConfig resources")))
+				})
+
+				It("should not wait for the driver to be unloaded when force deleted", func() {
+					forced := makeTestDeviceConfig(deletedAt(time.Now()))
+					forced.Annotations = map[string]string{examplecomv1alpha1.ForceDeleteAnnotation: "true"}
+					getDeviceConfig(forced)
+
+					gomock.InOrder(
+						fu.EXPECT().ContainsDeletionFinalizer(gomock.Any()).Return(true),
+						be.EXPECT().DeleteModule(ctx, gomock.Any()).Return(nil),
+						ar.EXPECT().DeletePrometheusRule(ctx, gomock.Any()).Return(nil),
+						fr.EXPECT().DeleteFirmware(ctx, gomock.Any()).Return(nil),
+						itr.EXPECT().RestoreInTreeModules(ctx, gomock.Any()).Return(nil),
+						nlu.EXPECT().RemoveComputedNodeLabels(ctx, gomock.Any()).Return(nil),
+						fu.EXPECT().RemoveDeletionFinalizer(ctx, gomock.Any()).Return(nil),
+					)
+
+					res, err := r.Reconcile(ctx, req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.RequeueAfter).To(BeZero())
+				})
 			})
 
 			Context("which does not contain a deletion finalizer", func() {
@@ -400,7 +1474,7 @@ var _ = Describe("DeviceConfigReconciler", func() {
 					Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
 					Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
 
-					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), nil, fu, nil, nil)
+					r = NewDeviceConfigReconciler(c, s, record.NewFakeRecorder(1), nil, fu, nil, nil, nil, nil, nil, nil, rt, ar, fr, itr, rv)
 
 					res, err := r.Reconcile(ctx, req)
 					Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,276 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	ruleSuffix        = "alerts"
+	clusterRuleSuffix = "cluster-alerts"
+
+	defaultUnavailableNodesPercent = 10
+	defaultReconcileFailingFor     = 15 * time.Minute
+	defaultRolloutStuckFor         = 30 * time.Minute
+
+	// driverUnavailableFor smooths out the driver pods restarting on a node.
+	driverUnavailableFor = 10 * time.Minute
+)
+
+// PrometheusRuleGVK is the kind of the prometheus-operator alerting rules. The operator does
+// not depend on the prometheus-operator API, so the rules are handled as unstructured objects.
+var PrometheusRuleGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusRule",
+}
+
+//go:generate mockgen -source=alerts.go -package=alerts -destination=mock_alerts.go
+
+// Reconciler manages the PrometheusRule with the driver health alerts of a DeviceConfig.
+type Reconciler interface {
+	ReconcilePrometheusRule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	DeletePrometheusRule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+}
+
+type alertsReconciler struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// driverNamespace is the namespace of the ClusterDeviceConfig PrometheusRules.
+	driverNamespace string
+}
+
+func NewReconciler(c client.Client, s *runtime.Scheme, driverNamespace string) Reconciler {
+	return &alertsReconciler{
+		client:          c,
+		scheme:          s,
+		driverNamespace: driverNamespace,
+	}
+}
+
+// GetPrometheusRuleName returns the name of the PrometheusRule of the given resource, with
+// the same suffix convention as its Module.
+func GetPrometheusRuleName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	if cr.GetNamespace() == "" {
+		return fmt.Sprintf("%s-%s", cr.GetName(), clusterRuleSuffix)
+	}
+	return fmt.Sprintf("%s-%s", cr.GetName(), ruleSuffix)
+}
+
+// ReconcilePrometheusRule creates or updates the PrometheusRule of the given DeviceConfig, or
+// deletes it if its alerts are disabled. Nothing is done if the prometheus-operator CRDs are
+// not installed, so that the rule is created once they are.
+func (r *alertsReconciler) ReconcilePrometheusRule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "alerts.ReconcilePrometheusRule", cr)
+	defer tracing.End(span, &err)
+
+	logger := log.FromContext(ctx)
+
+	if alerts := cr.GetSpec().Alerts; alerts != nil && alerts.Disabled {
+		return r.DeletePrometheusRule(ctx, cr)
+	}
+
+	installed, err := r.isPrometheusRuleInstalled()
+	if err != nil {
+		return err
+	}
+	if !installed {
+		logger.V(1).Info("PrometheusRule CRD not installed, skipping the DeviceConfig alerts")
+		return nil
+	}
+
+	rule, err := r.newPrometheusRule(cr)
+	if err != nil {
+		return err
+	}
+
+	res, err := controllerutil.CreateOrUpdate(ctx, r.client, rule, func() error {
+		return r.setDesiredPrometheusRule(rule, cr)
+	})
+	if err != nil {
+		return fmt.Errorf("could not create or update PrometheusRule: %w", err)
+	}
+
+	logger.Info("Reconciled PrometheusRule", "resource", rule.GetName(), "result", res)
+
+	return nil
+}
+
+func (r *alertsReconciler) DeletePrometheusRule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "alerts.DeletePrometheusRule", cr)
+	defer tracing.End(span, &err)
+
+	installed, err := r.isPrometheusRuleInstalled()
+	if err != nil || !installed {
+		return err
+	}
+
+	rule, err := r.newPrometheusRule(cr)
+	if err != nil {
+		return err
+	}
+
+	err = r.client.Delete(ctx, rule)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete PrometheusRule %s: %w", rule.GetName(), err)
+	}
+
+	return nil
+}
+
+// isPrometheusRuleInstalled returns true if the PrometheusRule CRD is served by the cluster.
+func (r *alertsReconciler) isPrometheusRuleInstalled() (bool, error) {
+	_, err := r.client.RESTMapper().RESTMapping(PrometheusRuleGVK.GroupKind(), PrometheusRuleGVK.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up the PrometheusRule CRD: %w", err)
+	}
+	return true, nil
+}
+
+func (r *alertsReconciler) newPrometheusRule(cr examplecomv1alpha1.DeviceConfigObject) (*unstructured.Unstructured, error) {
+	namespace, err := module.GetModuleNamespace(cr, r.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(PrometheusRuleGVK)
+	rule.SetNamespace(namespace)
+	rule.SetName(GetPrometheusRuleName(cr))
+	return rule, nil
+}
+
+func (r *alertsReconciler) setDesiredPrometheusRule(rule *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject) error {
+	if err := unstructured.SetNestedSlice(rule.Object, makeRuleGroups(cr), "spec", "groups"); err != nil {
+		return err
+	}
+
+	// The PrometheusRule of a ClusterDeviceConfig lives in the driver namespace, so it is
+	// tracked by label instead of an owner reference, like its Module.
+	if cdc, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		labels := rule.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] = cdc.GetOwnerLabelValue()
+		rule.SetLabels(labels)
+		return nil
+	}
+
+	return ctrl.SetControllerReference(cr, rule, r.scheme)
+}
+
+// makeRuleGroups returns the rule groups of the PrometheusRule of the given DeviceConfig,
+// built from the operator metrics of the DeviceConfig.
+func makeRuleGroups(cr examplecomv1alpha1.DeviceConfigObject) []interface{} {
+	unavailableNodesPercent := int32(defaultUnavailableNodesPercent)
+	reconcileFailingFor := defaultReconcileFailingFor
+	rolloutStuckFor := defaultRolloutStuckFor
+	if alerts := cr.GetSpec().Alerts; alerts != nil {
+		if alerts.UnavailableNodesPercent != nil {
+			unavailableNodesPercent = *alerts.UnavailableNodesPercent
+		}
+		if alerts.ReconcileFailingFor != nil {
+			reconcileFailingFor = alerts.ReconcileFailingFor.Duration
+		}
+		if alerts.RolloutStuckFor != nil {
+			rolloutStuckFor = alerts.RolloutStuckFor.Duration
+		}
+	}
+
+	selector := fmt.Sprintf(`namespace=%q,device_config=%q`, cr.GetNamespace(), cr.GetName())
+	ref := cr.GetName()
+	if cr.GetNamespace() != "" {
+		ref = cr.GetNamespace() + "/" + cr.GetName()
+	}
+
+	rules := []interface{}{
+		makeRule(
+			"DeviceConfigReconcileFailing",
+			fmt.Sprintf(`he_sample_operator_reconciliation_failed{%s} == 1`, selector),
+			reconcileFailingFor,
+			"warning",
+			fmt.Sprintf("The reconciliation of %s is failing.", ref),
+		),
+		makeRule(
+			"DeviceConfigDriverUnavailable",
+			fmt.Sprintf(
+				`100 * (1 - he_sample_operator_available_nodes{%[1]s} / he_sample_operator_desired_nodes{%[1]s}) > %d`,
+				selector, unavailableNodesPercent,
+			),
+			driverUnavailableFor,
+			"critical",
+			fmt.Sprintf("The driver of %s is unavailable on more than %d%% of its nodes.", ref, unavailableNodesPercent),
+		),
+		makeRule(
+			"DeviceConfigKernelWithoutMapping",
+			fmt.Sprintf(`he_sample_operator_unmapped_kernel_nodes{%s} > 0`, selector),
+			0,
+			"warning",
+			fmt.Sprintf("Nodes selected by %s run kernel {{ $labels.kernel_version }}, which no kernel mapping matches.", ref),
+		),
+		makeRule(
+			"DeviceConfigRolloutStuck",
+			fmt.Sprintf(`he_sample_operator_upgrade_progress_ratio{%s} < 1`, selector),
+			rolloutStuckFor,
+			"warning",
+			fmt.Sprintf("The driver rollout of %s has not completed for %s.", ref, rolloutStuckFor),
+		),
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"name":  "he-sample-operator." + GetPrometheusRuleName(cr),
+			"rules": rules,
+		},
+	}
+}
+
+func makeRule(alert, expr string, forDuration time.Duration, severity, description string) interface{} {
+	rule := map[string]interface{}{
+		"alert": alert,
+		"expr":  expr,
+		"labels": map[string]interface{}{
+			"severity": severity,
+		},
+		"annotations": map[string]interface{}{
+			"description": description,
+		},
+	}
+	if forDuration > 0 {
+		// Prometheus durations do not support fractions, e.g. 1.5m.
+		rule["for"] = fmt.Sprintf("%ds", int64(forDuration.Seconds()))
+	}
+	return rule
+}
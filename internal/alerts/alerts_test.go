@@ -0,0 +1,167 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+)
+
+var _ = Describe("AlertsReconciler", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace, UID: "a-uid"},
+		}
+	})
+
+	newTestClient := func(installed bool, objs ...client.Object) client.Client {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		if installed {
+			mapper.Add(PrometheusRuleGVK, meta.RESTScopeNamespace)
+		}
+		return fake.NewClientBuilder().WithScheme(s).WithRESTMapper(mapper).WithObjects(objs...).Build()
+	}
+
+	getPrometheusRule := func(c client.Client, namespace, name string) (*unstructured.Unstructured, error) {
+		rule := &unstructured.Unstructured{}
+		rule.SetGroupVersionKind(PrometheusRuleGVK)
+		return rule, c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, rule)
+	}
+
+	getRules := func(rule *unstructured.Unstructured) map[string]map[string]interface{} {
+		groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+
+		rules := make(map[string]map[string]interface{})
+		for _, r := range groups[0].(map[string]interface{})["rules"].([]interface{}) {
+			rule := r.(map[string]interface{})
+			rules[rule["alert"].(string)] = rule
+		}
+		return rules
+	}
+
+	Describe("ReconcilePrometheusRule", func() {
+		It("should create the PrometheusRule owned by the DeviceConfig with the default thresholds", func() {
+			c := newTestClient(true)
+
+			Expect(NewReconciler(c, s, "").ReconcilePrometheusRule(ctx, dc)).To(Succeed())
+
+			rule, err := getPrometheusRule(c, testNamespace, "a-device-config-alerts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rule.GetOwnerReferences()).To(HaveLen(1))
+			Expect(rule.GetOwnerReferences()[0].Name).To(Equal(dc.Name))
+
+			rules := getRules(rule)
+			Expect(rules).To(HaveLen(4))
+			Expect(rules["DeviceConfigReconcileFailing"]).To(HaveKeyWithValue("for", "900s"))
+			Expect(rules["DeviceConfigReconcileFailing"]).To(HaveKeyWithValue("expr",
+				`he_sample_operator_reconciliation_failed{namespace="a-namespace",device_config="a-device-config"} == 1`))
+			Expect(rules["DeviceConfigDriverUnavailable"]["expr"]).To(HaveSuffix("> 10"))
+			Expect(rules["DeviceConfigKernelWithoutMapping"]).ToNot(HaveKey("for"))
+			Expect(rules["DeviceConfigRolloutStuck"]).To(HaveKeyWithValue("for", "1800s"))
+		})
+
+		It("should apply the DeviceConfig thresholds", func() {
+			c := newTestClient(true)
+			percent := int32(25)
+			dc.Spec.Alerts = &examplecomv1alpha1.AlertsSpec{
+				UnavailableNodesPercent: &percent,
+				RolloutStuckFor:         &metav1.Duration{Duration: time.Hour},
+			}
+
+			Expect(NewReconciler(c, s, "").ReconcilePrometheusRule(ctx, dc)).To(Succeed())
+
+			rule, err := getPrometheusRule(c, testNamespace, "a-device-config-alerts")
+			Expect(err).ToNot(HaveOccurred())
+
+			rules := getRules(rule)
+			Expect(rules["DeviceConfigDriverUnavailable"]["expr"]).To(HaveSuffix("> 25"))
+			Expect(rules["DeviceConfigRolloutStuck"]).To(HaveKeyWithValue("for", "3600s"))
+		})
+
+		It("should label the PrometheusRule of a ClusterDeviceConfig in the driver namespace", func() {
+			c := newTestClient(true)
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"},
+			}
+
+			Expect(NewReconciler(c, s, testDriverNamespace).ReconcilePrometheusRule(ctx, cdc)).To(Succeed())
+
+			rule, err := getPrometheusRule(c, testDriverNamespace, "a-device-config-cluster-alerts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rule.GetOwnerReferences()).To(BeEmpty())
+			Expect(rule.GetLabels()).To(HaveKeyWithValue(examplecomv1alpha1.ClusterDeviceConfigOwnerLabel, cdc.GetOwnerLabelValue()))
+			Expect(getRules(rule)["DeviceConfigReconcileFailing"]["expr"]).To(ContainSubstring(`namespace="",device_config="a-device-config"`))
+		})
+
+		It("should delete the PrometheusRule of a DeviceConfig with disabled alerts", func() {
+			c := newTestClient(true)
+			r := NewReconciler(c, s, "")
+			Expect(r.ReconcilePrometheusRule(ctx, dc)).To(Succeed())
+
+			dc.Spec.Alerts = &examplecomv1alpha1.AlertsSpec{Disabled: true}
+			Expect(r.ReconcilePrometheusRule(ctx, dc)).To(Succeed())
+
+			_, err := getPrometheusRule(c, testNamespace, "a-device-config-alerts")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should do nothing if the PrometheusRule CRD is not installed", func() {
+			c := newTestClient(false)
+
+			Expect(NewReconciler(c, s, "").ReconcilePrometheusRule(ctx, dc)).To(Succeed())
+			Expect(NewReconciler(c, s, "").DeletePrometheusRule(ctx, dc)).To(Succeed())
+		})
+	})
+
+	Describe("DeletePrometheusRule", func() {
+		It("should ignore a PrometheusRule that does not exist", func() {
+			c := newTestClient(true)
+
+			Expect(NewReconciler(c, s, "").DeletePrometheusRule(ctx, dc)).To(Succeed())
+		})
+	})
+})
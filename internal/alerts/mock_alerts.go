@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: alerts.go
+
+// Package alerts is a generated GoMock package.
+package alerts
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockReconciler is a mock of Reconciler interface.
+type MockReconciler struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconcilerMockRecorder
+}
+
+// MockReconcilerMockRecorder is the mock recorder for MockReconciler.
+type MockReconcilerMockRecorder struct {
+	mock *MockReconciler
+}
+
+// NewMockReconciler creates a new mock instance.
+func NewMockReconciler(ctrl *gomock.Controller) *MockReconciler {
+	mock := &MockReconciler{ctrl: ctrl}
+	mock.recorder = &MockReconcilerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciler) EXPECT() *MockReconcilerMockRecorder {
+	return m.recorder
+}
+
+// DeletePrometheusRule mocks base method.
+func (m *MockReconciler) DeletePrometheusRule(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePrometheusRule", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePrometheusRule indicates an expected call of DeletePrometheusRule.
+func (mr *MockReconcilerMockRecorder) DeletePrometheusRule(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePrometheusRule", reflect.TypeOf((*MockReconciler)(nil).DeletePrometheusRule), ctx, cr)
+}
+
+// ReconcilePrometheusRule mocks base method.
+func (m *MockReconciler) ReconcilePrometheusRule(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcilePrometheusRule", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcilePrometheusRule indicates an expected call of ReconcilePrometheusRule.
+func (mr *MockReconcilerMockRecorder) ReconcilePrometheusRule(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcilePrometheusRule", reflect.TypeOf((*MockReconciler)(nil).ReconcilePrometheusRule), ctx, cr)
+}
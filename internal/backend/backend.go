@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+// Names of the driver deployment backends.
+const (
+	// KMM deploys the driver with a KMM Module.
+	KMM = "KMM"
+	// Native deploys the driver with privileged DaemonSets rendered by the operator.
+	Native = "Native"
+)
+
+//go:generate mockgen -source=backend.go -package=backend -destination=mock_backend.go
+
+// Backend deploys the driver of a DeviceConfig on its selected nodes.
+type Backend interface {
+	ReconcileModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	DeleteModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	// ReleaseModule leaves the driver of a deleted DeviceConfig running, according to its
+	// Orphan or Retain deletion policy.
+	ReleaseModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+}
+
+type selector struct {
+	defaultBackend string
+	backends       map[string]Backend
+}
+
+// NewSelector returns a Backend dispatching every DeviceConfig to the backend set in its spec,
+// or to the given default backend.
+func NewSelector(defaultBackend string, backends map[string]Backend) (Backend, error) {
+	if _, ok := backends[defaultBackend]; !ok {
+		return nil, fmt.Errorf("unknown default backend %q", defaultBackend)
+	}
+	return &selector{
+		defaultBackend: defaultBackend,
+		backends:       backends,
+	}, nil
+}
+
+// ReconcileModule deploys the driver with the backend of the DeviceConfig and records it in
+// its status. When the DeviceConfig switches backend, the driver deployed by the previous one
+// is removed once the new one has been reconciled.
+func (s *selector) ReconcileModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "backend.ReconcileModule", cr)
+	defer tracing.End(span, &err)
+
+	name := s.getBackendName(cr)
+	b, ok := s.backends[name]
+	if !ok {
+		return fmt.Errorf("unknown backend %q", name)
+	}
+
+	if err := b.ReconcileModule(ctx, cr); err != nil {
+		return err
+	}
+
+	status := cr.GetStatus()
+	if previous, ok := s.backends[status.Backend]; ok && status.Backend != name {
+		if err := deleteModule(ctx, previous, cr); err != nil {
+			return fmt.Errorf("failed to remove the %s driver: %w", status.Backend, err)
+		}
+	}
+	status.Backend = name
+
+	return nil
+}
+
+// DeleteModule removes the driver from every backend, since the backend recorded in the
+// status may be stale.
+func (s *selector) DeleteModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "backend.DeleteModule", cr)
+	defer tracing.End(span, &err)
+
+	names := make([]string, 0, len(s.backends))
+	for name := range s.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := deleteModule(ctx, s.backends[name], cr); err != nil {
+			return fmt.Errorf("failed to remove the %s driver: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ReleaseModule leaves the driver deployed by the backend recorded in the status running.
+func (s *selector) ReleaseModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "backend.ReleaseModule", cr)
+	defer tracing.End(span, &err)
+
+	name := cr.GetStatus().Backend
+	if name == "" {
+		name = s.getBackendName(cr)
+	}
+
+	b, ok := s.backends[name]
+	if !ok {
+		return fmt.Errorf("unknown backend %q", name)
+	}
+
+	if err := b.ReleaseModule(ctx, cr); err != nil && !isNoMatchError(err) {
+		return fmt.Errorf("failed to release the %s driver: %w", name, err)
+	}
+
+	return nil
+}
+
+// getBackendName returns the name of the backend set in the spec of the DeviceConfig, or the
+// default one.
+func (s *selector) getBackendName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	if name := cr.GetSpec().Backend; name != "" {
+		return name
+	}
+	return s.defaultBackend
+}
+
+// deleteModule removes the driver deployed by the given backend. A backend whose API is not
+// installed, e.g. KMM, has nothing to remove.
+func deleteModule(ctx context.Context, b Backend, cr examplecomv1alpha1.DeviceConfigObject) error {
+	if err := b.DeleteModule(ctx, cr); err != nil && !isNoMatchError(err) {
+		return err
+	}
+	return nil
+}
+
+func isNoMatchError(err error) bool {
+	var noKindMatch *meta.NoKindMatchError
+	var noResourceMatch *meta.NoResourceMatchError
+	return errors.As(err, &noKindMatch) || errors.As(err, &noResourceMatch)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gomock "github.com/golang/mock/gomock"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+var _ = Describe("Selector", func() {
+	var (
+		ctx    context.Context
+		gCtrl  *gomock.Controller
+		kmm    *MockBackend
+		native *MockBackend
+		b      Backend
+		dc     *examplecomv1alpha1.DeviceConfig
+	)
+
+	noMatchErr := fmt.Errorf("failed to delete Module: %w", &meta.NoKindMatchError{
+		GroupKind: schema.GroupKind{Group: "kmm.sigs.k8s.io", Kind: "Module"},
+	})
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		gCtrl = gomock.NewController(GinkgoT())
+		kmm = NewMockBackend(gCtrl)
+		native = NewMockBackend(gCtrl)
+
+		var err error
+		b, err = NewSelector(KMM, map[string]Backend{KMM: kmm, Native: native})
+		Expect(err).ToNot(HaveOccurred())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+		}
+	})
+
+	It("should reject an unknown default backend", func() {
+		_, err := NewSelector("Unknown", map[string]Backend{KMM: kmm})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("ReconcileModule", func() {
+		It("should use the default backend", func() {
+			kmm.EXPECT().ReconcileModule(ctx, dc).Return(nil)
+
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+			Expect(dc.Status.Backend).To(Equal(KMM))
+		})
+
+		It("should use the backend of the DeviceConfig", func() {
+			dc.Spec.Backend = Native
+			native.EXPECT().ReconcileModule(ctx, dc).Return(nil)
+
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+			Expect(dc.Status.Backend).To(Equal(Native))
+		})
+
+		It("should return an error for an unknown backend", func() {
+			dc.Spec.Backend = "Unknown"
+
+			Expect(b.ReconcileModule(ctx, dc)).To(MatchError(ContainSubstring("unknown backend")))
+		})
+
+		It("should return the error of the backend and keep the previous one", func() {
+			dc.Spec.Backend = Native
+			dc.Status.Backend = KMM
+			native.EXPECT().ReconcileModule(ctx, dc).Return(errors.New("something went wrong"))
+
+			Expect(b.ReconcileModule(ctx, dc)).To(MatchError("something went wrong"))
+			Expect(dc.Status.Backend).To(Equal(KMM))
+		})
+
+		It("should remove the driver of the previous backend", func() {
+			dc.Spec.Backend = Native
+			dc.Status.Backend = KMM
+			gomock.InOrder(
+				native.EXPECT().ReconcileModule(ctx, dc).Return(nil),
+				kmm.EXPECT().DeleteModule(ctx, dc).Return(nil),
+			)
+
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+			Expect(dc.Status.Backend).To(Equal(Native))
+		})
+
+		It("should ignore a previous backend whose API is not installed", func() {
+			dc.Spec.Backend = Native
+			dc.Status.Backend = KMM
+			gomock.InOrder(
+				native.EXPECT().ReconcileModule(ctx, dc).Return(nil),
+				kmm.EXPECT().DeleteModule(ctx, dc).Return(noMatchErr),
+			)
+
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+			Expect(dc.Status.Backend).To(Equal(Native))
+		})
+	})
+
+	Context("DeleteModule", func() {
+		It("should remove the driver from every backend", func() {
+			kmm.EXPECT().DeleteModule(ctx, dc).Return(noMatchErr)
+			native.EXPECT().DeleteModule(ctx, dc).Return(nil)
+
+			Expect(b.DeleteModule(ctx, dc)).To(Succeed())
+		})
+
+		It("should return the error of a backend", func() {
+			kmm.EXPECT().DeleteModule(ctx, dc).Return(errors.New("something went wrong"))
+
+			Expect(b.DeleteModule(ctx, dc)).To(MatchError(ContainSubstring("something went wrong")))
+		})
+	})
+
+	Context("ReleaseModule", func() {
+		It("should release the driver of the backend recorded in the status", func() {
+			dc.Status.Backend = Native
+			native.EXPECT().ReleaseModule(ctx, dc).Return(nil)
+
+			Expect(b.ReleaseModule(ctx, dc)).To(Succeed())
+		})
+
+		It("should ignore a backend whose API is not installed", func() {
+			kmm.EXPECT().ReleaseModule(ctx, dc).Return(noMatchErr)
+
+			Expect(b.ReleaseModule(ctx, dc)).To(Succeed())
+		})
+	})
+})
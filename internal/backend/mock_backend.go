@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: backend.go
+
+// Package backend is a generated GoMock package.
+package backend
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockBackend is a mock of Backend interface.
+type MockBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockBackendMockRecorder
+}
+
+// MockBackendMockRecorder is the mock recorder for MockBackend.
+type MockBackendMockRecorder struct {
+	mock *MockBackend
+}
+
+// NewMockBackend creates a new mock instance.
+func NewMockBackend(ctrl *gomock.Controller) *MockBackend {
+	mock := &MockBackend{ctrl: ctrl}
+	mock.recorder = &MockBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBackend) EXPECT() *MockBackendMockRecorder {
+	return m.recorder
+}
+
+// DeleteModule mocks base method.
+func (m *MockBackend) DeleteModule(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteModule", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteModule indicates an expected call of DeleteModule.
+func (mr *MockBackendMockRecorder) DeleteModule(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteModule", reflect.TypeOf((*MockBackend)(nil).DeleteModule), ctx, cr)
+}
+
+// ReconcileModule mocks base method.
+func (m *MockBackend) ReconcileModule(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileModule", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileModule indicates an expected call of ReconcileModule.
+func (mr *MockBackendMockRecorder) ReconcileModule(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileModule", reflect.TypeOf((*MockBackend)(nil).ReconcileModule), ctx, cr)
+}
+
+// ReleaseModule mocks base method.
+func (m *MockBackend) ReleaseModule(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseModule", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseModule indicates an expected call of ReleaseModule.
+func (mr *MockBackendMockRecorder) ReleaseModule(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseModule", reflect.TypeOf((*MockBackend)(nil).ReleaseModule), ctx, cr)
+}
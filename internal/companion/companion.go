@@ -0,0 +1,307 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package companion
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+)
+
+// DaemonSet manages a companion DaemonSet of a DeviceConfig, which prepares the nodes selected
+// for the driver before it is loaded, e.g. by installing its firmware. The nodes are labelled
+// once their companion pod is ready, and the driver node selector requires the label, so that
+// the driver is only loaded on the prepared nodes.
+//
+// The companion DaemonSets and pods carry the labels of the native backend ones with their own
+// role, so that they are watched the same way. The DaemonSet of a ClusterDeviceConfig lives in
+// the driver namespace, so it is tracked by label instead of an owner reference.
+type DaemonSet struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// driverNamespace is the namespace of the ClusterDeviceConfig DaemonSets.
+	driverNamespace string
+	// role is the value of the KMM role label of the DaemonSet and its pods, and the suffix of
+	// its name.
+	role string
+	// nodeLabel returns the key of the node label the driver node selector requires.
+	nodeLabel func(examplecomv1alpha1.DeviceConfigObject) string
+}
+
+func NewDaemonSet(c client.Client, s *runtime.Scheme, driverNamespace, role string, nodeLabel func(examplecomv1alpha1.DeviceConfigObject) string) *DaemonSet {
+	return &DaemonSet{
+		client:          c,
+		scheme:          s,
+		driverNamespace: driverNamespace,
+		role:            role,
+		nodeLabel:       nodeLabel,
+	}
+}
+
+// GetName returns the name of the companion DaemonSet of the given resource.
+func (d *DaemonSet) GetName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	return module.GetModuleName(cr) + "-" + d.role
+}
+
+// GetManagedLabels returns the labels of the companion DaemonSet and pods of the given resource
+// with the given role.
+func GetManagedLabels(cr examplecomv1alpha1.DeviceConfigObject, role string) map[string]string {
+	managed := map[string]string{
+		module.ModuleNameLabel: module.GetModuleName(cr),
+		module.RoleLabel:       role,
+		native.ManagedByLabel:  native.ManagedByValue,
+	}
+	if cdc, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		managed[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] = cdc.GetOwnerLabelValue()
+	}
+	return managed
+}
+
+// getNodeSelector returns the nodes selected for the driver of the given resource, without the
+// labels set by the companion DaemonSets, which are reconciled independently.
+func getNodeSelector(cr examplecomv1alpha1.DeviceConfigObject) map[string]string {
+	nodeSelector := cr.GetModuleNodeSelector()
+	delete(nodeSelector, cr.GetFirmwareNodeLabel())
+	delete(nodeSelector, cr.GetInTreeNodeLabel())
+	return nodeSelector
+}
+
+// Reconcile creates or patches the companion DaemonSet of the given resource, running pods
+// with the given labels and spec on the nodes selected for the driver, and returns its
+// namespace.
+func (d *DaemonSet) Reconcile(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, podLabels map[string]string, podSpec corev1.PodSpec) (string, error) {
+	namespace, err := module.GetModuleNamespace(cr, d.driverNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	templateLabels := GetManagedLabels(cr, d.role)
+	for k, v := range podLabels {
+		templateLabels[k] = v
+	}
+	podSpec.NodeSelector = getNodeSelector(cr)
+	podSpec.ServiceAccountName = config.GetDefaults().DriverServiceAccount
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      d.GetName(cr),
+			Namespace: namespace,
+		},
+	}
+	res, err := controllerutil.CreateOrPatch(ctx, d.client, ds, func() error {
+		// The DaemonSet released by a deleted resource of the same name is adopted again.
+		if _, err := module.Adopt(ds); err != nil {
+			return err
+		}
+		ds.Labels = GetManagedLabels(cr, d.role)
+		ds.Spec = appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: GetManagedLabels(cr, d.role)},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: templateLabels},
+				Spec:       podSpec,
+			},
+		}
+
+		if _, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+			return nil
+		}
+		return ctrl.SetControllerReference(cr, ds, d.scheme)
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create or patch %s DaemonSet %s: %w", d.role, ds.Name, err)
+	}
+	log.FromContext(ctx).Info("Reconciled companion DaemonSet", "resource", ds.Name, "role", d.role, "result", res)
+
+	return namespace, nil
+}
+
+// SyncNodeLabels sets the node label of the nodes whose companion pod is ready to the value
+// returned for the pod, removes it from the nodes no longer selected for the driver and returns
+// the label value of every labelled node along with the companion pods. The label of a selected
+// node whose companion pod is not ready yet, or missing while the DaemonSet replaces it during a
+// rolling update, is left untouched, so that a restarting pod does not unload the driver.
+func (d *DaemonSet) SyncNodeLabels(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, namespace string, value func(*corev1.Pod) string) (map[string]string, []corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := d.client.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels(GetManagedLabels(cr, d.role))); err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s pods: %w", d.role, err)
+	}
+
+	ready := make(map[string]string)
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Spec.NodeName != "" && isReady(p) {
+			ready[p.Spec.NodeName] = value(p)
+		}
+	}
+
+	key := d.nodeLabel(cr)
+	labelled := &corev1.NodeList{}
+	if err := d.client.List(ctx, labelled, client.HasLabels{key}); err != nil {
+		return nil, nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	selector := labels.SelectorFromSet(getNodeSelector(cr))
+	values := make(map[string]string)
+	for i := range labelled.Items {
+		n := &labelled.Items[i]
+		if !selector.Matches(labels.Set(n.Labels)) {
+			if err := d.patchNodeLabel(ctx, n, key, ""); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		values[n.Name] = n.Labels[key]
+	}
+
+	for name, v := range ready {
+		values[name] = v
+
+		n := &corev1.Node{}
+		if err := d.client.Get(ctx, client.ObjectKey{Name: name}, n); err != nil {
+			return nil, nil, fmt.Errorf("failed to get node %s: %w", name, err)
+		}
+		if n.Labels[key] == v {
+			continue
+		}
+		if err := d.patchNodeLabel(ctx, n, key, v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return values, pods.Items, nil
+}
+
+// Exists returns true if the given resource has a companion DaemonSet or labelled nodes left to
+// delete. Both are looked up in the cache, so that the resources which never had a companion
+// DaemonSet do not hit the API server on every reconcile.
+func (d *DaemonSet) Exists(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (bool, error) {
+	namespace, err := module.GetModuleNamespace(cr, d.driverNamespace)
+	if err != nil {
+		return false, err
+	}
+
+	dss := &appsv1.DaemonSetList{}
+	if err := d.client.List(ctx, dss, client.InNamespace(namespace), client.MatchingLabels(GetManagedLabels(cr, d.role))); err != nil {
+		return false, fmt.Errorf("failed to list %s DaemonSets: %w", d.role, err)
+	}
+	if len(dss.Items) > 0 {
+		return true, nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := d.client.List(ctx, nodes, client.HasLabels{d.nodeLabel(cr)}); err != nil {
+		return false, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return len(nodes.Items) > 0, nil
+}
+
+// Release detaches the companion DaemonSet of the given deleted resource from it, so that it
+// keeps running along with the released driver, and leaves the node labels it set.
+func (d *DaemonSet) Release(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error {
+	namespace, err := module.GetModuleNamespace(cr, d.driverNamespace)
+	if err != nil {
+		return err
+	}
+
+	dss := &appsv1.DaemonSetList{}
+	if err := d.client.List(ctx, dss, client.InNamespace(namespace), client.MatchingLabels(GetManagedLabels(cr, d.role))); err != nil {
+		return fmt.Errorf("failed to list %s DaemonSets: %w", d.role, err)
+	}
+
+	for i := range dss.Items {
+		ds := &dss.Items[i]
+		patch := client.MergeFrom(ds.DeepCopy())
+		module.Release(ds, cr)
+		if err := d.client.Patch(ctx, ds, patch); err != nil {
+			return fmt.Errorf("failed to release %s DaemonSet %s: %w", d.role, ds.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the companion DaemonSet of the given resource and the node labels it set.
+func (d *DaemonSet) Delete(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error {
+	namespace, err := module.GetModuleNamespace(cr, d.driverNamespace)
+	if err != nil {
+		return err
+	}
+
+	err = d.client.DeleteAllOf(ctx, &appsv1.DaemonSet{},
+		client.InNamespace(namespace),
+		client.MatchingLabels(GetManagedLabels(cr, d.role)),
+		client.PropagationPolicy(metav1.DeletePropagationBackground),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s DaemonSets: %w", d.role, err)
+	}
+
+	key := d.nodeLabel(cr)
+	nodes := &corev1.NodeList{}
+	if err := d.client.List(ctx, nodes, client.HasLabels{key}); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range nodes.Items {
+		if err := d.patchNodeLabel(ctx, &nodes.Items[i], key, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patchNodeLabel sets the given label of the node to the given value, or removes it if the
+// value is empty.
+func (d *DaemonSet) patchNodeLabel(ctx context.Context, n *corev1.Node, key, value string) error {
+	patch := client.MergeFrom(n.DeepCopy())
+	if value == "" {
+		delete(n.Labels, key)
+	} else {
+		if n.Labels == nil {
+			n.Labels = make(map[string]string)
+		}
+		n.Labels[key] = value
+	}
+
+	if err := d.client.Patch(ctx, n, patch); err != nil {
+		return fmt.Errorf("failed to update label %s of node %s: %w", key, n.Name, err)
+	}
+
+	log.FromContext(ctx).Info("Updated companion node label", "node", n.Name, "label", key, "value", value)
+
+	return nil
+}
+
+func isReady(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package companion
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+	testRole            = "firmware"
+)
+
+var _ = Describe("DaemonSet", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	nodeLabel := examplecomv1alpha1.DeviceConfigObject.GetFirmwareNodeLabel
+
+	makePod := func(name, node string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: GetManagedLabels(dc, testRole)},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	getNodeLabels := func(c client.Client, name string) map[string]string {
+		n := &corev1.Node{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: name}, n)).To(Succeed())
+		return n.Labels
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace, UID: "a-uid"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				DriverImage:   "quay.io/example/driver",
+				DriverVersion: "2.1.0",
+				ModuleName:    "example-driver",
+			},
+		}
+	})
+
+	Context("Reconcile", func() {
+		It("should run the pods on the nodes selected for the driver with an owner reference", func() {
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			namespace, err := d.Reconcile(ctx, dc, map[string]string{"a-label": "a-value"}, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespace).To(Equal(testNamespace))
+
+			ds := &appsv1.DaemonSet{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: d.GetName(dc), Namespace: testNamespace}, ds)).To(Succeed())
+			Expect(ds.Labels).To(Equal(GetManagedLabels(dc, testRole)))
+			Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue("a-label", "a-value"))
+			Expect(ds.Spec.Template.Spec.NodeSelector).NotTo(HaveKey(dc.GetFirmwareNodeLabel()))
+			Expect(ds.OwnerReferences).To(HaveLen(1))
+			Expect(ds.OwnerReferences[0].UID).To(Equal(dc.UID))
+		})
+
+		It("should track the DaemonSet of a ClusterDeviceConfig by label", func() {
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-device-config", UID: "a-uid"},
+				Spec:       dc.Spec,
+			}
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			namespace, err := d.Reconcile(ctx, cdc, nil, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespace).To(Equal(testDriverNamespace))
+
+			ds := &appsv1.DaemonSet{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: d.GetName(cdc), Namespace: testDriverNamespace}, ds)).To(Succeed())
+			Expect(ds.Labels).To(HaveKeyWithValue(examplecomv1alpha1.ClusterDeviceConfigOwnerLabel, cdc.GetOwnerLabelValue()))
+			Expect(ds.OwnerReferences).To(BeEmpty())
+		})
+	})
+
+	Context("SyncNodeLabels", func() {
+		It("should label the nodes once their pod is ready", func() {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+				makePod("pod-a", "node-a", true),
+				makePod("pod-b", "node-b", false),
+			).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			values, pods, err := d.SyncNodeLabels(ctx, dc, testNamespace, func(*corev1.Pod) string { return "a-value" })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal(map[string]string{"node-a": "a-value"}))
+			Expect(pods).To(HaveLen(2))
+			Expect(getNodeLabels(c, "node-a")).To(HaveKeyWithValue(dc.GetFirmwareNodeLabel(), "a-value"))
+			Expect(getNodeLabels(c, "node-b")).NotTo(HaveKey(dc.GetFirmwareNodeLabel()))
+		})
+	})
+
+	Context("Release", func() {
+		It("should detach the DaemonSet until it is adopted again", func() {
+			dc.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyOrphan
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			_, err := d.Reconcile(ctx, dc, nil, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d.Release(ctx, dc)).To(Succeed())
+
+			ds := &appsv1.DaemonSet{}
+			key := client.ObjectKey{Name: d.GetName(dc), Namespace: testNamespace}
+			Expect(c.Get(ctx, key, ds)).To(Succeed())
+			Expect(ds.OwnerReferences).To(BeEmpty())
+			Expect(ds.Annotations).To(HaveKeyWithValue(examplecomv1alpha1.DeletionPolicyAnnotation, examplecomv1alpha1.DeletionPolicyOrphan))
+
+			_, err = d.Reconcile(ctx, dc, nil, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(c.Get(ctx, key, ds)).To(Succeed())
+			Expect(ds.OwnerReferences).To(HaveLen(1))
+			Expect(ds.Annotations).NotTo(HaveKey(examplecomv1alpha1.DeletionPolicyAnnotation))
+		})
+
+		It("should not adopt a retained DaemonSet", func() {
+			dc.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyRetain
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			_, err := d.Reconcile(ctx, dc, nil, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d.Release(ctx, dc)).To(Succeed())
+
+			_, err = d.Reconcile(ctx, dc, nil, corev1.PodSpec{})
+			Expect(err).To(MatchError(module.ErrDriverRetained))
+		})
+	})
+
+	Context("Delete", func() {
+		It("should delete the DaemonSet and the node labels", func() {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   "node-a",
+					Labels: map[string]string{dc.GetFirmwareNodeLabel(): "a-value"},
+				}},
+			).Build()
+			d := NewDaemonSet(c, s, testDriverNamespace, testRole, nodeLabel)
+
+			_, err := d.Reconcile(ctx, dc, nil, corev1.PodSpec{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Delete(ctx, dc)).To(Succeed())
+
+			exists, err := d.Exists(ctx, dc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeFalse())
+			Expect(getNodeLabels(c, "node-a")).NotTo(HaveKey(dc.GetFirmwareNodeLabel()))
+		})
+	})
+})
@@ -21,6 +21,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 const (
@@ -28,16 +29,38 @@ const (
 
 	Errored = "Errored"
 
+	Degraded = "Degraded"
+
+	Terminating = "Terminating"
+
 	ReasonModuleFailed = "ModuleFailed"
 
 	ReasonConflictingNodeSelector = "ConflictingNodeSelector"
+
+	ReasonNodeLabelsFailed = "NodeLabelsFailed"
+
+	ReasonDriverPodsFailed = "DriverPodsFailed"
+
+	ReasonPreflightFailed = "PreflightFailed"
+
+	ReasonPrometheusRuleFailed = "PrometheusRuleFailed"
+
+	ReasonFirmwareFailed = "FirmwareFailed"
+
+	ReasonFirmwareIncompatible = "FirmwareIncompatible"
+
+	ReasonInTreeFailed = "InTreeFailed"
+
+	ReasonResourceShortfall = "ResourceShortfall"
 )
 
 //go:generate mockgen -source=conditions.go -package=conditions -destination=mock_conditions.go
 
 type Updater interface {
-	SetConditionsReady(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig, reason, message string) error
-	SetConditionsErrored(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig, reason, message string) error
+	SetConditionsReady(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) error
+	SetConditionsErrored(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) error
+	SetConditionsDegraded(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) error
+	SetConditionsTerminating(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, message string) error
 }
 
 type updater struct {
@@ -48,36 +71,98 @@ func NewUpdater(sw client.StatusWriter) Updater {
 	return &updater{statusWriter: sw}
 }
 
-func (u *updater) SetConditionsReady(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig, reason, message string) error {
-	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+func (u *updater) SetConditionsReady(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "conditions.SetConditionsReady", cr)
+	defer tracing.End(span, &err)
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
 		Type:    Ready,
 		Status:  metav1.ConditionTrue,
 		Reason:  reason,
 		Message: message,
 	})
 
-	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
 		Type:   Errored,
 		Status: metav1.ConditionFalse,
 		Reason: Ready,
 	})
 
+	meta.RemoveStatusCondition(&cr.GetStatus().Conditions, Degraded)
+
 	return u.statusWriter.Update(ctx, cr)
 }
 
-func (u *updater) SetConditionsErrored(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig, reason, message string) error {
-	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+func (u *updater) SetConditionsErrored(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "conditions.SetConditionsErrored", cr)
+	defer tracing.End(span, &err)
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
 		Type:   Ready,
 		Status: metav1.ConditionFalse,
 		Reason: Errored,
 	})
 
-	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
 		Type:    Errored,
 		Status:  metav1.ConditionTrue,
 		Reason:  reason,
 		Message: message,
 	})
 
+	meta.RemoveStatusCondition(&cr.GetStatus().Conditions, Degraded)
+
+	return u.statusWriter.Update(ctx, cr)
+}
+
+// SetConditionsDegraded reports that the DeviceConfig resources have been reconciled, but the
+// driver is not available on some of the selected nodes.
+func (u *updater) SetConditionsDegraded(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, reason, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "conditions.SetConditionsDegraded", cr)
+	defer tracing.End(span, &err)
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
+		Type:   Ready,
+		Status: metav1.ConditionFalse,
+		Reason: Degraded,
+	})
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
+		Type:   Errored,
+		Status: metav1.ConditionFalse,
+		Reason: Degraded,
+	})
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
+		Type:    Degraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+
+	return u.statusWriter.Update(ctx, cr)
+}
+
+// SetConditionsTerminating reports that the driver of a deleted DeviceConfig is being removed
+// from the nodes.
+func (u *updater) SetConditionsTerminating(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "conditions.SetConditionsTerminating", cr)
+	defer tracing.End(span, &err)
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
+		Type:    Ready,
+		Status:  metav1.ConditionFalse,
+		Reason:  Terminating,
+		Message: message,
+	})
+
+	meta.SetStatusCondition(&cr.GetStatus().Conditions, metav1.Condition{
+		Type:   Errored,
+		Status: metav1.ConditionFalse,
+		Reason: Terminating,
+	})
+
+	meta.RemoveStatusCondition(&cr.GetStatus().Conditions, Degraded)
+
 	return u.statusWriter.Update(ctx, cr)
 }
@@ -17,6 +17,7 @@ import (
 	"context"
 	"errors"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	gomock "github.com/golang/mock/gomock"
@@ -81,6 +82,67 @@ var _ = Describe("ConditionsUpdater", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("with a previously degraded DeviceConfig", func() {
+			It("should remove the Degraded condition", func() {
+				c.EXPECT().Update(context.TODO(), dc).Times(2)
+
+				Expect(u.SetConditionsDegraded(context.TODO(), dc, "test reason", "test message")).To(Succeed())
+				Expect(u.SetConditionsReady(context.TODO(), dc, "test reason", "test message")).To(Succeed())
+
+				Expect(meta.FindStatusCondition(dc.Status.Conditions, "Degraded")).To(BeNil())
+			})
+		})
+	})
+
+	Describe("SetConditionsDegraded", func() {
+		Context("with successful status update", func() {
+			BeforeEach(func() {
+				c.EXPECT().Update(context.TODO(), dc)
+
+				err := u.SetConditionsDegraded(context.TODO(), dc, "test reason", "test message")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should have set the Ready and Errored conditions as false", func() {
+				Expect(meta.IsStatusConditionFalse(dc.Status.Conditions, "Ready")).To(BeTrue())
+				Expect(meta.IsStatusConditionFalse(dc.Status.Conditions, "Errored")).To(BeTrue())
+			})
+
+			It("should have set the Degraded condition as true", func() {
+				degraded := meta.FindStatusCondition(dc.Status.Conditions, "Degraded")
+
+				Expect(degraded).ToNot(BeNil())
+				Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+				Expect(degraded.Reason).To(Equal("test reason"))
+				Expect(degraded.Message).To(Equal("test message"))
+			})
+		})
+	})
+
+	Describe("SetConditionsTerminating", func() {
+		Context("with successful status update", func() {
+			BeforeEach(func() {
+				meta.SetStatusCondition(&dc.Status.Conditions, metav1.Condition{Type: "Degraded", Status: metav1.ConditionTrue, Reason: "test"})
+				c.EXPECT().Update(context.TODO(), dc)
+
+				err := u.SetConditionsTerminating(context.TODO(), dc, "test message")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should have set the Ready condition as false with the Terminating reason", func() {
+				ready := meta.FindStatusCondition(dc.Status.Conditions, "Ready")
+
+				Expect(ready).ToNot(BeNil())
+				Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+				Expect(ready.Reason).To(Equal("Terminating"))
+				Expect(ready.Message).To(Equal("test message"))
+			})
+
+			It("should have removed the Degraded condition", func() {
+				Expect(meta.FindStatusCondition(dc.Status.Conditions, "Degraded")).To(BeNil())
+			})
+		})
 	})
 
 	Describe("SetConditionsErrored", func() {
@@ -124,5 +186,17 @@ var _ = Describe("ConditionsUpdater", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("with a previously degraded DeviceConfig", func() {
+			It("should remove the Degraded condition", func() {
+				c.EXPECT().Update(context.TODO(), dc).Times(2)
+
+				Expect(u.SetConditionsDegraded(context.TODO(), dc, "test reason", "test message")).To(Succeed())
+				Expect(u.SetConditionsErrored(context.TODO(), dc, "test reason", "test message")).To(Succeed())
+
+				Expect(meta.FindStatusCondition(dc.Status.Conditions, "Degraded")).To(BeNil())
+				Expect(meta.IsStatusConditionTrue(dc.Status.Conditions, "Errored")).To(BeTrue())
+			})
+		})
 	})
 })
@@ -35,8 +35,22 @@ func (m *MockUpdater) EXPECT() *MockUpdaterMockRecorder {
 	return m.recorder
 }
 
+// SetConditionsDegraded mocks base method.
+func (m *MockUpdater) SetConditionsDegraded(ctx context.Context, cr v1alpha1.DeviceConfigObject, reason, message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetConditionsDegraded", ctx, cr, reason, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetConditionsDegraded indicates an expected call of SetConditionsDegraded.
+func (mr *MockUpdaterMockRecorder) SetConditionsDegraded(ctx, cr, reason, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConditionsDegraded", reflect.TypeOf((*MockUpdater)(nil).SetConditionsDegraded), ctx, cr, reason, message)
+}
+
 // SetConditionsErrored mocks base method.
-func (m *MockUpdater) SetConditionsErrored(ctx context.Context, cr *v1alpha1.DeviceConfig, reason, message string) error {
+func (m *MockUpdater) SetConditionsErrored(ctx context.Context, cr v1alpha1.DeviceConfigObject, reason, message string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetConditionsErrored", ctx, cr, reason, message)
 	ret0, _ := ret[0].(error)
@@ -50,7 +64,7 @@ func (mr *MockUpdaterMockRecorder) SetConditionsErrored(ctx, cr, reason, message
 }
 
 // SetConditionsReady mocks base method.
-func (m *MockUpdater) SetConditionsReady(ctx context.Context, cr *v1alpha1.DeviceConfig, reason, message string) error {
+func (m *MockUpdater) SetConditionsReady(ctx context.Context, cr v1alpha1.DeviceConfigObject, reason, message string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetConditionsReady", ctx, cr, reason, message)
 	ret0, _ := ret[0].(error)
@@ -62,3 +76,17 @@ func (mr *MockUpdaterMockRecorder) SetConditionsReady(ctx, cr, reason, message i
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConditionsReady", reflect.TypeOf((*MockUpdater)(nil).SetConditionsReady), ctx, cr, reason, message)
 }
+
+// SetConditionsTerminating mocks base method.
+func (m *MockUpdater) SetConditionsTerminating(ctx context.Context, cr v1alpha1.DeviceConfigObject, message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetConditionsTerminating", ctx, cr, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetConditionsTerminating indicates an expected call of SetConditionsTerminating.
+func (mr *MockUpdaterMockRecorder) SetConditionsTerminating(ctx, cr, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConditionsTerminating", reflect.TypeOf((*MockUpdater)(nil).SetConditionsTerminating), ctx, cr, message)
+}
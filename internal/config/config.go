@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Built-in defaults of the driver resources, used for the fields the configuration leaves
+// unset.
+const (
+	DefaultDriverServiceAccount = "driver-sample"
+	DefaultKernelRegexp         = `^.*\.el\d_?\d?\..*$`
+	DefaultPCIVendorID          = "1da3"
+	DefaultImagePullPolicy      = corev1.PullAlways
+	DefaultUtilityImage         = "docker.io/library/busybox:1.36"
+)
+
+var (
+	pciVendorIDRegexp = regexp.MustCompile(`^[0-9a-f]{4}$`)
+
+	codecs serializer.CodecFactory
+
+	// defaults holds the current Defaults, which are read concurrently by the reconcilers and
+	// replaced when the configuration is reloaded.
+	defaults atomic.Value
+)
+
+func init() {
+	s := runtime.NewScheme()
+	utilruntime.Must(AddToScheme(s))
+	codecs = serializer.NewCodecFactory(s, serializer.EnableStrict)
+
+	defaults.Store(Defaults{}.complete())
+}
+
+// GetDefaults returns the current defaults of the driver resources.
+func GetDefaults() Defaults {
+	return defaults.Load().(Defaults)
+}
+
+// SetDefaults replaces the current defaults of the driver resources, completing the unset
+// fields with the built-in defaults.
+func SetDefaults(d Defaults) {
+	defaults.Store(d.complete())
+}
+
+// Load reads and validates the operator configuration file at the given path.
+func Load(path string) (*OperatorConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the operator configuration: %w", err)
+	}
+	return Decode(content)
+}
+
+// Decode decodes and validates the given operator configuration.
+func Decode(content []byte) (*OperatorConfig, error) {
+	c := &OperatorConfig{}
+	if err := runtime.DecodeInto(codecs.UniversalDecoder(GroupVersion), content, c); err != nil {
+		return nil, fmt.Errorf("could not decode the operator configuration: %w", err)
+	}
+	if err := c.Defaults.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid operator configuration: %w", err)
+	}
+	return c, nil
+}
+
+// Validate returns an error listing the invalid fields of the defaults.
+func (d Defaults) Validate() error {
+	errs := []string{}
+
+	if d.DriverServiceAccount != "" {
+		if msgs := validation.IsDNS1123Subdomain(d.DriverServiceAccount); len(msgs) > 0 {
+			errs = append(errs, fmt.Sprintf("driverServiceAccount: %s", strings.Join(msgs, ", ")))
+		}
+	}
+	if d.KernelRegexp != "" {
+		if _, err := regexp.Compile(d.KernelRegexp); err != nil {
+			errs = append(errs, fmt.Sprintf("kernelRegexp: %v", err))
+		}
+	}
+	if d.PCIVendorID != "" && !pciVendorIDRegexp.MatchString(d.PCIVendorID) {
+		errs = append(errs, "pciVendorID: must be 4 lowercase hexadecimal digits")
+	}
+	switch d.ImagePullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		errs = append(errs, fmt.Sprintf("imagePullPolicy: unsupported value %q", d.ImagePullPolicy))
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// complete returns the defaults with their unset fields set to the built-in defaults.
+func (d Defaults) complete() Defaults {
+	if d.DriverServiceAccount == "" {
+		d.DriverServiceAccount = DefaultDriverServiceAccount
+	}
+	if d.KernelRegexp == "" {
+		d.KernelRegexp = DefaultKernelRegexp
+	}
+	if d.PCIVendorID == "" {
+		d.PCIVendorID = DefaultPCIVendorID
+	}
+	if d.ImagePullPolicy == "" {
+		d.ImagePullPolicy = DefaultImagePullPolicy
+	}
+	if d.UtilityImage == "" {
+		d.UtilityImage = DefaultUtilityImage
+	}
+	return d
+}
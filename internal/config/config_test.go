@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testConfig = `
+apiVersion: config.example.com/v1alpha1
+kind: OperatorConfig
+leaderElection:
+  leaderElect: true
+  resourceName: a-lease
+driverNamespace: a-namespace
+defaults:
+  driverServiceAccount: a-service-account
+  pciVendorID: 10de
+`
+
+var _ = Describe("Decode", func() {
+	It("should decode the manager options and the defaults", func() {
+		c, err := Decode([]byte(testConfig))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(c.LeaderElection.ResourceName).To(Equal("a-lease"))
+		Expect(c.DriverNamespace).To(Equal("a-namespace"))
+		Expect(c.Defaults).To(Equal(Defaults{DriverServiceAccount: "a-service-account", PCIVendorID: "10de"}))
+	})
+
+	It("should reject unknown fields", func() {
+		_, err := Decode([]byte(testConfig + "unknownField: true\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject invalid defaults", func() {
+		_, err := Decode([]byte(`
+apiVersion: config.example.com/v1alpha1
+kind: OperatorConfig
+defaults:
+  kernelRegexp: '^(unclosed'
+  pciVendorID: not-a-vendor
+  imagePullPolicy: Sometimes
+`))
+		Expect(err).To(MatchError(And(
+			ContainSubstring("kernelRegexp"),
+			ContainSubstring("pciVendorID"),
+			ContainSubstring("imagePullPolicy"),
+		)))
+	})
+})
+
+var _ = Describe("SetDefaults", func() {
+	AfterEach(func() {
+		SetDefaults(Defaults{})
+	})
+
+	It("should complete the unset defaults with the built-in ones", func() {
+		SetDefaults(Defaults{PCIVendorID: "10de"})
+
+		Expect(GetDefaults()).To(Equal(Defaults{
+			DriverServiceAccount: DefaultDriverServiceAccount,
+			KernelRegexp:         DefaultKernelRegexp,
+			PCIVendorID:          "10de",
+			ImagePullPolicy:      corev1.PullAlways,
+			UtilityImage:         DefaultUtilityImage,
+		}))
+	})
+})
+
+var _ = Describe("Watcher", func() {
+	var (
+		ctx     context.Context
+		path    string
+		w       *Watcher
+		changes int
+	)
+
+	writeConfig := func(content string) {
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		writeConfig(testConfig)
+
+		loaded, err := Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		SetDefaults(loaded.Defaults)
+
+		changes = 0
+		w = NewWatcher(path, loaded, time.Second)
+		w.OnChange(func() { changes++ })
+	})
+
+	AfterEach(func() {
+		SetDefaults(Defaults{})
+	})
+
+	It("should reload the changed defaults", func() {
+		w.reload(ctx)
+		Expect(changes).To(BeZero())
+
+		writeConfig(testConfig + "  imagePullPolicy: IfNotPresent\n")
+		w.reload(ctx)
+
+		Expect(changes).To(Equal(1))
+		Expect(GetDefaults().ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(GetDefaults().PCIVendorID).To(Equal("10de"))
+	})
+
+	It("should keep the current defaults when the configuration is invalid", func() {
+		writeConfig(testConfig + "  imagePullPolicy: Sometimes\n")
+		w.reload(ctx)
+
+		Expect(changes).To(BeZero())
+		Expect(GetDefaults().ImagePullPolicy).To(Equal(corev1.PullAlways))
+		Expect(GetDefaults().DriverServiceAccount).To(Equal("a-service-account"))
+	})
+})
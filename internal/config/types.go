@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config contains the configuration of the operator, i.e. the options of its manager
+// and the defaults of the driver resources it renders.
+// +kubebuilder:object:generate=true
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group version of the operator configuration.
+	GroupVersion = schema.GroupVersion{Group: "config.example.com", Version: "v1alpha1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+//+kubebuilder:object:root=true
+
+// OperatorConfig is the configuration of the operator
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec configures the manager, e.g. its metrics and health
+	// probe addresses and its leader election. Changes require a restart
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// DriverNamespace is the namespace of the Modules created for ClusterDeviceConfigs.
+	// Changes require a restart
+	DriverNamespace string `json:"driverNamespace,omitempty"`
+	// DefaultBackend is the backend deploying the driver of the DeviceConfigs which do not set
+	// one. Changes require a restart
+	DefaultBackend string `json:"defaultBackend,omitempty"`
+
+	// Defaults are the defaults of the driver resources, reloaded when the configuration changes
+	Defaults Defaults `json:"defaults,omitempty"`
+}
+
+// Defaults are the operator defaults of the driver resources
+type Defaults struct {
+	// DriverServiceAccount is the service account of the driver pods
+	DriverServiceAccount string `json:"driverServiceAccount,omitempty"`
+	// KernelRegexp matches the kernels the driver image is built for
+	KernelRegexp string `json:"kernelRegexp,omitempty"`
+	// PCIVendorID is the PCI vendor of the devices selected by the DeviceConfigs without a
+	// node selector, through the NFD labels of their nodes
+	PCIVendorID string `json:"pciVendorID,omitempty"`
+	// ImagePullPolicy is the pull policy of the driver images
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// UtilityImage is the image unloading and reloading the in-tree kernel modules replaced by
+	// the driver, which must ship the rmmod and modprobe tools
+	UtilityImage string `json:"utilityImage,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Watcher reloads the defaults of the operator configuration file whenever it changes, e.g.
+// when its ConfigMap is updated, and runs the registered callbacks. The other settings require
+// a restart, so their changes are only logged.
+//
+// The file is polled rather than watched, since the kubelet updates a mounted ConfigMap by
+// swapping a symbolic link to its data directory.
+//
+// +kubebuilder:object:generate=false
+type Watcher struct {
+	path     string
+	interval time.Duration
+
+	onChange []func()
+
+	// loaded is the last loaded configuration and content the content it has been decoded from.
+	loaded  *OperatorConfig
+	content []byte
+}
+
+// NewWatcher returns a Watcher of the configuration file at the given path, from which the
+// given configuration has been loaded at startup.
+func NewWatcher(path string, loaded *OperatorConfig, interval time.Duration) *Watcher {
+	return &Watcher{
+		path:     path,
+		interval: interval,
+		loaded:   loaded,
+	}
+}
+
+// OnChange registers a callback run once the defaults have changed. Callbacks must be
+// registered before the Watcher is started.
+func (w *Watcher) OnChange(f func()) {
+	w.onChange = append(w.onChange, f)
+}
+
+// Start polls the configuration file until the context is done.
+func (w *Watcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, w.reload, w.interval)
+	return nil
+}
+
+// NeedLeaderElection returns false, so that every replica renders the same defaults.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload loads the configuration file if its content has changed. An invalid configuration
+// is ignored, keeping the current defaults.
+func (w *Watcher) reload(ctx context.Context) {
+	logger := log.FromContext(ctx).WithName("config-watcher")
+
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		logger.Error(err, "Failed to read the operator configuration", "path", w.path)
+		return
+	}
+	if bytes.Equal(content, w.content) {
+		return
+	}
+
+	c, err := Decode(content)
+	if err != nil {
+		logger.Error(err, "Ignoring the changes of the operator configuration", "path", w.path)
+		return
+	}
+	w.content = content
+
+	previous := w.loaded
+	w.loaded = c
+
+	if !reflect.DeepEqual(previous.ControllerManagerConfigurationSpec, c.ControllerManagerConfigurationSpec) ||
+		previous.DriverNamespace != c.DriverNamespace || previous.DefaultBackend != c.DefaultBackend {
+		logger.Info("The manager options of the operator configuration have changed, restart the operator to apply them")
+	}
+
+	if reflect.DeepEqual(previous.Defaults, c.Defaults) {
+		return
+	}
+
+	SetDefaults(c.Defaults)
+	logger.Info("Reloaded the operator defaults", "defaults", GetDefaults())
+	for _, f := range w.onChange {
+		f()
+	}
+}
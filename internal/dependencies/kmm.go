@@ -0,0 +1,264 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const (
+	reasonModuleCRDFound    = "ModuleCRDFound"
+	reasonModuleCRDNotFound = "ModuleCRDNotFound"
+)
+
+// ErrKMMNotAvailable is returned by the readiness check until the KMM Module CRD is served.
+var ErrKMMNotAvailable = errors.New("the KMM Module CRD is not available")
+
+//+kubebuilder:rbac:groups=example.com,resources=operatorstatuses,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=example.com,resources=operatorstatuses/status,verbs=get;update;patch
+
+// Discoverer looks up the resources served by the cluster for an API group version. It is
+// implemented by the client-go discovery client.
+type Discoverer interface {
+	ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error)
+}
+
+// KMMWatcher waits for the KMM Module CRD to be served by the cluster and then runs the
+// registered callbacks, e.g. to start the controllers owning Modules. It reports the KMM
+// availability through a readiness check, while the KMMStatusReporter reports it in the
+// OperatorStatus singleton.
+type KMMWatcher struct {
+	discovery Discoverer
+	interval  time.Duration
+
+	onAvailable []func() error
+
+	mu        sync.RWMutex
+	available bool
+	// served is the result of the last lookup of the KMM Module CRD, if any.
+	served *bool
+}
+
+func NewKMMWatcher(d Discoverer, interval time.Duration) *KMMWatcher {
+	return &KMMWatcher{
+		discovery: d,
+		interval:  interval,
+	}
+}
+
+// OnAvailable registers a callback run once the KMM Module CRD is served. Callbacks must be
+// registered before the KMMWatcher is started.
+func (w *KMMWatcher) OnAvailable(f func() error) {
+	w.onAvailable = append(w.onAvailable, f)
+}
+
+// Start polls the cluster for the KMM Module CRD until it is served, then runs the registered
+// callbacks.
+func (w *KMMWatcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("kmm-watcher")
+
+	err := wait.PollImmediateUntilWithContext(ctx, w.interval, func(ctx context.Context) (bool, error) {
+		available, err := w.isModuleCRDServed()
+		if err != nil {
+			logger.Error(err, "Failed to look up the KMM Module CRD")
+			return false, nil
+		}
+		w.setServed(available)
+		if !available {
+			logger.Info("Waiting for the KMM Module CRD to be installed")
+		}
+		return available, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	logger.Info("KMM Module CRD found, starting the dependent controllers")
+	for _, f := range w.onAvailable {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	w.available = true
+	w.mu.Unlock()
+
+	return nil
+}
+
+// NeedLeaderElection returns false, so that every replica reports its readiness.
+func (w *KMMWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *KMMWatcher) setServed(served bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.served = &served
+}
+
+// getServed returns the result of the last lookup of the KMM Module CRD, and false until the
+// first lookup has succeeded.
+func (w *KMMWatcher) getServed() (bool, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.served == nil {
+		return false, false
+	}
+	return *w.served, true
+}
+
+// ReadyzCheck reports the operator as not ready until the KMM Module CRD is served and the
+// dependent controllers have been started.
+func (w *KMMWatcher) ReadyzCheck(_ *http.Request) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.available {
+		return ErrKMMNotAvailable
+	}
+	return nil
+}
+
+// isModuleCRDServed looks up the KMM Module CRD in each supported API version, since the KMM
+// releases serve different ones.
+func (w *KMMWatcher) isModuleCRDServed() (bool, error) {
+	for _, version := range module.SupportedVersions() {
+		gv := module.ModuleGroupKind.WithVersion(version).GroupVersion()
+		resources, err := w.discovery.ServerResourcesForGroupVersion(gv.String())
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		for _, r := range resources.APIResources {
+			if r.Kind == module.ModuleGroupKind.Kind {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// KMMStatusReporter reports the KMM availability observed by a KMMWatcher in the OperatorStatus
+// singleton. It only runs on the leader, so that the replicas do not race on its updates.
+type KMMStatusReporter struct {
+	watcher  *KMMWatcher
+	client   client.Client
+	interval time.Duration
+
+	// reported is the availability last reported in the OperatorStatus, if any.
+	reported *bool
+}
+
+func NewKMMStatusReporter(w *KMMWatcher, c client.Client, interval time.Duration) *KMMStatusReporter {
+	return &KMMStatusReporter{
+		watcher:  w,
+		client:   c,
+		interval: interval,
+	}
+}
+
+// Start reports the KMM availability every interval, until the KMM Module CRD has been reported
+// as installed.
+func (r *KMMStatusReporter) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("kmm-status-reporter")
+
+	err := wait.PollImmediateUntilWithContext(ctx, r.interval, func(ctx context.Context) (bool, error) {
+		available, ok := r.watcher.getServed()
+		if !ok {
+			return false, nil
+		}
+		if err := r.reportAvailability(ctx, available); err != nil {
+			logger.Error(err, "Failed to update the OperatorStatus")
+			return false, nil
+		}
+		return available, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection returns true, so that only the leader updates the OperatorStatus.
+func (r *KMMStatusReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// reportAvailability sets the KMMAvailable condition of the OperatorStatus singleton, creating
+// it if needed. The OperatorStatus is only updated when the availability changes.
+func (r *KMMStatusReporter) reportAvailability(ctx context.Context, available bool) error {
+	if r.reported != nil && *r.reported == available {
+		return nil
+	}
+
+	status := &examplecomv1alpha1.OperatorStatus{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: examplecomv1alpha1.OperatorStatusName}, status)
+	if apierrors.IsNotFound(err) {
+		status.Name = examplecomv1alpha1.OperatorStatusName
+		err = r.client.Create(ctx, status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get OperatorStatus: %w", err)
+	}
+
+	condition := metav1.Condition{
+		Type:    examplecomv1alpha1.KMMAvailableCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonModuleCRDNotFound,
+		Message: "The KMM Module CRD is not installed",
+	}
+	if available {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonModuleCRDFound
+		condition.Message = "The KMM Module CRD is installed"
+	}
+	meta.SetStatusCondition(&status.Status.Conditions, condition)
+
+	if err := r.client.Status().Update(ctx, status); err != nil {
+		return fmt.Errorf("failed to update OperatorStatus: %w", err)
+	}
+
+	r.reported = &available
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// stubDiscoverer serves the KMM Module CRD in the given version once installed.
+type stubDiscoverer struct {
+	mu      sync.Mutex
+	version string
+}
+
+func (d *stubDiscoverer) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.version == "" || groupVersion != "kmm.sigs.k8s.io/"+d.version {
+		return nil, apierrors.NewNotFound(schema.GroupResource{}, groupVersion)
+	}
+	return &metav1.APIResourceList{
+		GroupVersion: groupVersion,
+		APIResources: []metav1.APIResource{{Name: "modules", Kind: "Module", Namespaced: true}},
+	}, nil
+}
+
+func (d *stubDiscoverer) install(version string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.version = version
+}
+
+var _ = Describe("KMMWatcher", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		c      client.Client
+		d      *stubDiscoverer
+		w      *KMMWatcher
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		s := runtime.NewScheme()
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(s).Build()
+		d = &stubDiscoverer{}
+		w = NewKMMWatcher(d, 10*time.Millisecond)
+	})
+
+	getKMMAvailableCondition := func() *metav1.Condition {
+		status := &examplecomv1alpha1.OperatorStatus{}
+		if err := c.Get(ctx, types.NamespacedName{Name: examplecomv1alpha1.OperatorStatusName}, status); err != nil {
+			return nil
+		}
+		return meta.FindStatusCondition(status.Status.Conditions, examplecomv1alpha1.KMMAvailableCondition)
+	}
+
+	It("should run the callbacks once the KMM Module CRD is installed, without writing the OperatorStatus", func() {
+		var started bool
+		w.OnAvailable(func() error {
+			started = true
+			return nil
+		})
+
+		done := make(chan error)
+		go func() {
+			done <- w.Start(ctx)
+		}()
+
+		Eventually(func() bool {
+			_, ok := w.getServed()
+			return ok
+		}).Should(BeTrue())
+		Expect(w.ReadyzCheck(nil)).To(MatchError(ErrKMMNotAvailable))
+
+		d.install("v1beta1")
+
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(started).To(BeTrue())
+		Expect(w.ReadyzCheck(nil)).To(Succeed())
+		Expect(getKMMAvailableCondition()).To(BeNil())
+	})
+
+	It("should report the KMM availability in the OperatorStatus", func() {
+		r := NewKMMStatusReporter(w, c, 10*time.Millisecond)
+		Expect(r.NeedLeaderElection()).To(BeTrue())
+
+		go func() {
+			defer GinkgoRecover()
+			Expect(w.Start(ctx)).To(Succeed())
+		}()
+		reported := make(chan error)
+		go func() {
+			reported <- r.Start(ctx)
+		}()
+
+		Eventually(getKMMAvailableCondition).Should(And(
+			Not(BeNil()),
+			HaveField("Status", metav1.ConditionFalse),
+		))
+
+		d.install("v1beta1")
+
+		Eventually(reported).Should(Receive(BeNil()))
+		Expect(getKMMAvailableCondition()).To(HaveField("Status", metav1.ConditionTrue))
+	})
+
+	It("should detect the KMM Module CRD in a newer API version", func() {
+		d.install("v1beta2")
+
+		Expect(w.Start(ctx)).To(Succeed())
+		Expect(w.ReadyzCheck(nil)).To(Succeed())
+	})
+
+	It("should return the error of a callback", func() {
+		d.install("v1beta1")
+		w.OnAvailable(func() error {
+			return errors.New("some-error")
+		})
+
+		Expect(w.Start(ctx)).To(MatchError("some-error"))
+		Expect(w.ReadyzCheck(nil)).To(MatchError(ErrKMMNotAvailable))
+	})
+
+	It("should stop waiting when its context is cancelled", func() {
+		cancel()
+
+		Expect(w.Start(ctx)).To(Succeed())
+		Expect(w.ReadyzCheck(nil)).To(MatchError(ErrKMMNotAvailable))
+	})
+})
@@ -0,0 +1,204 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driverpods
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+// MaxReportedNodeFailures is the maximum number of node failures reported in the
+// DeviceConfig status.
+const MaxReportedNodeFailures = 10
+
+// failureReasons are the container waiting reasons which indicate that the driver cannot be
+// loaded on a node, as opposed to a driver pod which is still starting.
+var failureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerError":       true,
+	"CreateContainerConfigError": true,
+}
+
+//go:generate mockgen -source=driverpods.go -package=driverpods -destination=mock_driverpods.go
+
+// Inspector inspects the KMM ModuleLoader pods of a DeviceConfig.
+type Inspector interface {
+	GetNodeFailures(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) ([]examplecomv1alpha1.NodeFailure, error)
+	GetLoadedNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) ([]string, error)
+}
+
+type inspector struct {
+	client          client.Client
+	driverNamespace string
+}
+
+func NewInspector(c client.Client, driverNamespace string) Inspector {
+	return &inspector{client: c, driverNamespace: driverNamespace}
+}
+
+// GetNodeFailures returns the failures of the ModuleLoader pods of the given DeviceConfig,
+// one per node and sorted by node name.
+func (i *inspector) GetNodeFailures(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ []examplecomv1alpha1.NodeFailure, err error) {
+	ctx, span := tracing.Start(ctx, "driverpods.GetNodeFailures", cr)
+	defer tracing.End(span, &err)
+
+	namespace, err := module.GetModuleNamespace(cr, i.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := i.listDriverPods(ctx, cr, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string]examplecomv1alpha1.NodeFailure)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		reason, message, failed := podFailure(&pod)
+		if !failed {
+			continue
+		}
+		failures[pod.Spec.NodeName] = examplecomv1alpha1.NodeFailure{
+			Node:          pod.Spec.NodeName,
+			KernelVersion: pod.Labels[module.KernelVersionLabel],
+			Reason:        reason,
+			Message:       message,
+		}
+	}
+
+	nodeFailures := make([]examplecomv1alpha1.NodeFailure, 0, len(failures))
+	for _, f := range failures {
+		nodeFailures = append(nodeFailures, f)
+	}
+	sort.Slice(nodeFailures, func(i, j int) bool {
+		return nodeFailures[i].Node < nodeFailures[j].Node
+	})
+	return nodeFailures, nil
+}
+
+// GetLoadedNodes returns the sorted names of the nodes the driver of the given DeviceConfig
+// may still be loaded on, i.e. the nodes running one of its driver pods, terminating ones
+// included. When the DeviceConfig requires the unload to be confirmed, the nodes KMM still
+// reports the kernel module as loaded on are returned too.
+func (i *inspector) GetLoadedNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "driverpods.GetLoadedNodes", cr)
+	defer tracing.End(span, &err)
+
+	namespace, err := module.GetModuleNamespace(cr, i.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := i.listDriverPods(ctx, cr, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]bool)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != "" {
+			loaded[pod.Spec.NodeName] = true
+		}
+	}
+
+	if t := cr.GetSpec().Teardown; t != nil && t.ConfirmUnload {
+		for _, name := range module.GetModuleNames(cr) {
+			nodeList := &v1.NodeList{}
+			readyLabel := module.GetModuleReadyLabel(namespace, name)
+			if err := i.client.List(ctx, nodeList, client.HasLabels{readyLabel}); err != nil {
+				return nil, fmt.Errorf("failed to list nodes: %w", err)
+			}
+			for _, n := range nodeList.Items {
+				loaded[n.Name] = true
+			}
+		}
+	}
+
+	nodes := make([]string, 0, len(loaded))
+	for n := range loaded {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+func (i *inspector) listDriverPods(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, namespace string) (*v1.PodList, error) {
+	selector, err := module.GetModuleLoaderSelector(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	podList := &v1.PodList{}
+	if err := i.client.List(ctx, podList, client.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list driver pods: %w", err)
+	}
+	return podList, nil
+}
+
+// podFailure returns the reason and message of the first failing container of the given pod.
+func podFailure(pod *v1.Pod) (string, string, bool) {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if w := cs.State.Waiting; w != nil && failureReasons[w.Reason] {
+			return w.Reason, w.Message, true
+		}
+	}
+	if pod.Status.Phase == v1.PodFailed {
+		reason := pod.Status.Reason
+		if reason == "" {
+			reason = string(v1.PodFailed)
+		}
+		return reason, pod.Status.Message, true
+	}
+	return "", "", false
+}
+
+// FormatNodeFailures describes the given node failures, naming up to MaxReportedNodeFailures
+// nodes.
+func FormatNodeFailures(failures []examplecomv1alpha1.NodeFailure) string {
+	reported := failures
+	if len(reported) > MaxReportedNodeFailures {
+		reported = reported[:MaxReportedNodeFailures]
+	}
+
+	nodes := make([]string, 0, len(reported))
+	for _, f := range reported {
+		if f.KernelVersion != "" {
+			nodes = append(nodes, fmt.Sprintf("%s (%s, kernel %s)", f.Node, f.Reason, f.KernelVersion))
+		} else {
+			nodes = append(nodes, fmt.Sprintf("%s (%s)", f.Node, f.Reason))
+		}
+	}
+
+	msg := fmt.Sprintf("driver pods failing on %d node(s): %s", len(failures), strings.Join(nodes, ", "))
+	if more := len(failures) - len(reported); more > 0 {
+		msg = fmt.Sprintf("%s and %d more", msg, more)
+	}
+	return msg
+}
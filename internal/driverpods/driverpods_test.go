@@ -0,0 +1,177 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driverpods
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+	testKernel          = "5.14.0-70.el9.x86_64"
+)
+
+var _ = Describe("DriverPodsInspector", func() {
+	var (
+		ctx context.Context
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace},
+		}
+	})
+
+	Describe("GetNodeFailures", func() {
+		It("should report the failing driver pods per node", func() {
+			running := makeTestPod(testNamespace, module.GetModuleName(dc), "node-a", waiting(""))
+			pullFailure := makeTestPod(testNamespace, module.GetModuleName(dc), "node-c", waiting("ImagePullBackOff"))
+			crashing := makeTestPod(testNamespace, module.GetModuleName(dc), "node-b", waiting("CrashLoopBackOff"))
+			otherModule := makeTestPod(testNamespace, "other-module", "node-d", waiting("CrashLoopBackOff"))
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(running, pullFailure, crashing, otherModule).
+				Build()
+
+			failures, err := NewInspector(c, testDriverNamespace).GetNodeFailures(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failures).To(Equal([]examplecomv1alpha1.NodeFailure{
+				{Node: "node-b", KernelVersion: testKernel, Reason: "CrashLoopBackOff", Message: "some message"},
+				{Node: "node-c", KernelVersion: testKernel, Reason: "ImagePullBackOff", Message: "some message"},
+			}))
+		})
+
+		It("should look up the pods of a ClusterDeviceConfig in the driver namespace", func() {
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"},
+			}
+			failed := makeTestPod(testDriverNamespace, module.GetModuleName(cdc), "node-a")
+			failed.Status.Phase = corev1.PodFailed
+			failed.Status.Reason = "Evicted"
+
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(failed).Build()
+
+			failures, err := NewInspector(c, testDriverNamespace).GetNodeFailures(ctx, cdc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failures).To(HaveLen(1))
+			Expect(failures[0].Reason).To(Equal("Evicted"))
+		})
+	})
+
+	Describe("GetLoadedNodes", func() {
+		It("should return the nodes still running a driver pod", func() {
+			podB := makeTestPod(testNamespace, module.GetModuleName(dc), "node-b")
+			podA := makeTestPod(testNamespace, module.GetModuleName(dc), "node-a")
+			pending := makeTestPod(testNamespace, module.GetModuleName(dc), "")
+			otherModule := makeTestPod(testNamespace, "other-module", "node-c")
+			loadedNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-d",
+				Labels: map[string]string{module.GetModuleReadyLabel(testNamespace, module.GetModuleName(dc)): ""},
+			}}
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(podB, podA, pending, otherModule, loadedNode).
+				Build()
+
+			nodes, err := NewInspector(c, testDriverNamespace).GetLoadedNodes(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodes).To(Equal([]string{"node-a", "node-b"}))
+		})
+
+		It("should also return the nodes the module is reported loaded on when confirming the unload", func() {
+			dc.Spec.Teardown = &examplecomv1alpha1.TeardownSpec{ConfirmUnload: true}
+			pod := makeTestPod(testNamespace, module.GetModuleName(dc), "node-b")
+			loadedNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-a",
+				Labels: map[string]string{module.GetModuleReadyLabel(testNamespace, module.GetModuleName(dc)): ""},
+			}}
+			unloadedNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-c"}}
+
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(pod, loadedNode, unloadedNode).
+				Build()
+
+			nodes, err := NewInspector(c, testDriverNamespace).GetLoadedNodes(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodes).To(Equal([]string{"node-a", "node-b"}))
+		})
+	})
+
+	Describe("FormatNodeFailures", func() {
+		It("should name a bounded number of failing nodes", func() {
+			failures := []examplecomv1alpha1.NodeFailure{}
+			for i := 0; i < MaxReportedNodeFailures+2; i++ {
+				failures = append(failures, examplecomv1alpha1.NodeFailure{
+					Node:          fmt.Sprintf("node-%02d", i),
+					KernelVersion: testKernel,
+					Reason:        "ImagePullBackOff",
+				})
+			}
+
+			msg := FormatNodeFailures(failures)
+			Expect(msg).To(HavePrefix("driver pods failing on 12 node(s): node-00 (ImagePullBackOff, kernel " + testKernel + ")"))
+			Expect(msg).To(HaveSuffix("and 2 more"))
+			Expect(msg).ToNot(ContainSubstring("node-10"))
+		})
+	})
+})
+
+type podOptions func(*corev1.Pod)
+
+func waiting(reason string) podOptions {
+	return func(p *corev1.Pod) {
+		state := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		if reason != "" {
+			state = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: "some message"}}
+		}
+		p.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "module-loader", State: state}}
+	}
+}
+
+func makeTestPod(namespace, moduleName, nodeName string, opts ...podOptions) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", moduleName, nodeName),
+			Namespace: namespace,
+			Labels: map[string]string{
+				module.ModuleNameLabel:    moduleName,
+				module.KernelVersionLabel: testKernel,
+				module.RoleLabel:          module.ModuleLoaderRole,
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
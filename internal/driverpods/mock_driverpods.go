@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: driverpods.go
+
+// Package driverpods is a generated GoMock package.
+package driverpods
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockInspector is a mock of Inspector interface.
+type MockInspector struct {
+	ctrl     *gomock.Controller
+	recorder *MockInspectorMockRecorder
+}
+
+// MockInspectorMockRecorder is the mock recorder for MockInspector.
+type MockInspectorMockRecorder struct {
+	mock *MockInspector
+}
+
+// NewMockInspector creates a new mock instance.
+func NewMockInspector(ctrl *gomock.Controller) *MockInspector {
+	mock := &MockInspector{ctrl: ctrl}
+	mock.recorder = &MockInspectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInspector) EXPECT() *MockInspectorMockRecorder {
+	return m.recorder
+}
+
+// GetLoadedNodes mocks base method.
+func (m *MockInspector) GetLoadedNodes(ctx context.Context, cr v1alpha1.DeviceConfigObject) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoadedNodes", ctx, cr)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoadedNodes indicates an expected call of GetLoadedNodes.
+func (mr *MockInspectorMockRecorder) GetLoadedNodes(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoadedNodes", reflect.TypeOf((*MockInspector)(nil).GetLoadedNodes), ctx, cr)
+}
+
+// GetNodeFailures mocks base method.
+func (m *MockInspector) GetNodeFailures(ctx context.Context, cr v1alpha1.DeviceConfigObject) ([]v1alpha1.NodeFailure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeFailures", ctx, cr)
+	ret0, _ := ret[0].([]v1alpha1.NodeFailure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeFailures indicates an expected call of GetNodeFailures.
+func (mr *MockInspectorMockRecorder) GetNodeFailures(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeFailures", reflect.TypeOf((*MockInspector)(nil).GetNodeFailures), ctx, cr)
+}
@@ -17,44 +17,82 @@ import (
 	"context"
 	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 //go:generate mockgen -source=finalizers.go -package=finalizers -destination=mock_finalizers.go
 
 type Updater interface {
-	AddDeletionFinalizer(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error
-	RemoveDeletionFinalizer(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error
-	ContainsDeletionFinalizer(cr *examplecomv1alpha1.DeviceConfig) bool
+	AddDeletionFinalizer(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	RemoveDeletionFinalizer(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	ContainsDeletionFinalizer(cr examplecomv1alpha1.DeviceConfigObject) bool
 }
 
 type updater struct {
-	statusWriter client.StatusWriter
+	client client.Client
 }
 
-func NewUpdater(sw client.StatusWriter) Updater {
-	return &updater{statusWriter: sw}
+func NewUpdater(c client.Client) Updater {
+	return &updater{client: c}
 }
 
-func (u *updater) AddDeletionFinalizer(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error {
-	controllerutil.AddFinalizer(cr, examplecomv1alpha1.DeviceConfigDeletionFinalizer)
-	if err := u.statusWriter.Update(ctx, cr); err != nil {
-		return fmt.Errorf("failed to add deletion finalizer for %s: %w", cr.Name, err)
+func (u *updater) AddDeletionFinalizer(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "finalizers.AddDeletionFinalizer", cr)
+	defer tracing.End(span, &err)
+
+	err = u.patchFinalizers(ctx, cr, func() bool {
+		return controllerutil.AddFinalizer(cr, examplecomv1alpha1.DeviceConfigDeletionFinalizer)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add deletion finalizer for %s: %w", cr.GetName(), err)
 	}
 	return nil
 }
 
-func (u *updater) RemoveDeletionFinalizer(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error {
-	controllerutil.RemoveFinalizer(cr, examplecomv1alpha1.DeviceConfigDeletionFinalizer)
-	if err := u.statusWriter.Update(ctx, cr); err != nil {
-		return fmt.Errorf("failed to remove deletion finalizer for %s: %w", cr.Name, err)
+func (u *updater) RemoveDeletionFinalizer(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "finalizers.RemoveDeletionFinalizer", cr)
+	defer tracing.End(span, &err)
+
+	err = u.patchFinalizers(ctx, cr, func() bool {
+		return controllerutil.RemoveFinalizer(cr, examplecomv1alpha1.DeviceConfigDeletionFinalizer)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove deletion finalizer for %s: %w", cr.GetName(), err)
 	}
 	return nil
 }
 
-func (u *updater) ContainsDeletionFinalizer(cr *examplecomv1alpha1.DeviceConfig) bool {
+// patchFinalizers writes the finalizers changed by mutate with a metadata merge patch. The
+// patch is guarded by the resource version, so that it never overwrites the finalizers of
+// other controllers, and is retried on conflicts with the latest finalizers of the resource.
+func (u *updater) patchFinalizers(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, mutate func() bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		original := cr.DeepCopyObject().(client.Object)
+		if !mutate() {
+			return nil
+		}
+
+		err := u.client.Patch(ctx, cr, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+		if apierrors.IsConflict(err) {
+			latest := cr.DeepCopyObject().(client.Object)
+			if err := u.client.Get(ctx, client.ObjectKeyFromObject(cr), latest); err != nil {
+				return err
+			}
+			// Only the metadata is refreshed, so that the status computed by the current
+			// reconciliation is kept.
+			cr.SetResourceVersion(latest.GetResourceVersion())
+			cr.SetFinalizers(latest.GetFinalizers())
+		}
+		return err
+	})
+}
+
+func (u *updater) ContainsDeletionFinalizer(cr examplecomv1alpha1.DeviceConfigObject) bool {
 	return controllerutil.ContainsFinalizer(cr, examplecomv1alpha1.DeviceConfigDeletionFinalizer)
 }
@@ -17,7 +17,13 @@ import (
 	"context"
 	"errors"
 
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	gomock "github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
@@ -25,6 +31,7 @@ import (
 
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
 	"github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 var _ = Describe("FinalizersUpdater", func() {
@@ -35,15 +42,24 @@ var _ = Describe("FinalizersUpdater", func() {
 	)
 
 	BeforeEach(func() {
-		dc = &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"}}
+		dc = &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", ResourceVersion: "1"}}
 		c = client.NewMockClient(gomock.NewController(GinkgoT()))
 		u = NewUpdater(c)
 	})
 
 	Describe("AddDeletionFinalizer", func() {
-		Context("with a successful update", func() {
+		Context("with a successful patch", func() {
 			BeforeEach(func() {
-				c.EXPECT().Update(context.TODO(), dc)
+				c.EXPECT().Patch(context.TODO(), dc, gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj ctrlclient.Object, patch ctrlclient.Patch, _ ...ctrlclient.PatchOption) error {
+						data, err := patch.Data(obj)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(string(data)).To(Equal(
+							`{"metadata":{"finalizers":["` + examplecomv1alpha1.DeviceConfigDeletionFinalizer + `"],"resourceVersion":"1"}}`,
+						))
+						return nil
+					},
+				)
 			})
 
 			It("should add the deletion finalizer to the DeviceConfig", func() {
@@ -53,9 +69,33 @@ var _ = Describe("FinalizersUpdater", func() {
 			})
 		})
 
-		Context("with a failed update", func() {
+		Context("with a conflicting patch", func() {
 			BeforeEach(func() {
-				c.EXPECT().Update(context.TODO(), dc).Return(errors.New("some error"))
+				gomock.InOrder(
+					c.EXPECT().Patch(context.TODO(), dc, gomock.Any()).
+						Return(apierrors.NewConflict(schema.GroupResource{Resource: "deviceconfigs"}, dc.Name, errors.New("some conflict"))),
+					c.EXPECT().Get(context.TODO(), ctrlclient.ObjectKeyFromObject(dc), gomock.Any()).DoAndReturn(
+						func(_ context.Context, _ ctrlclient.ObjectKey, obj ctrlclient.Object, _ ...ctrlclient.GetOption) error {
+							obj.SetResourceVersion("2")
+							obj.SetFinalizers([]string{"another-finalizer"})
+							return nil
+						},
+					),
+					c.EXPECT().Patch(context.TODO(), dc, gomock.Any()),
+				)
+			})
+
+			It("should retry with the latest finalizers", func() {
+				err := u.AddDeletionFinalizer(context.TODO(), dc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dc.Finalizers).To(ConsistOf("another-finalizer", examplecomv1alpha1.DeviceConfigDeletionFinalizer))
+				Expect(dc.ResourceVersion).To(Equal("2"))
+			})
+		})
+
+		Context("with a failed patch", func() {
+			BeforeEach(func() {
+				c.EXPECT().Patch(context.TODO(), dc, gomock.Any()).Return(errors.New("some error"))
 			})
 
 			It("should return an error", func() {
@@ -63,6 +103,29 @@ var _ = Describe("FinalizersUpdater", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("with a failed patch and tracing enabled", func() {
+			var exporter *tracetest.InMemoryExporter
+
+			BeforeEach(func() {
+				exporter = tracetest.NewInMemoryExporter()
+				tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+				c.EXPECT().Patch(gomock.Any(), dc, gomock.Any()).Return(errors.New("some error"))
+			})
+
+			AfterEach(func() {
+				tracing.SetTracerProvider(nil)
+			})
+
+			It("should record the error on its span", func() {
+				Expect(u.AddDeletionFinalizer(context.TODO(), dc)).ToNot(Succeed())
+
+				spans := exporter.GetSpans()
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Name).To(Equal("finalizers.AddDeletionFinalizer"))
+				Expect(spans[0].Status.Code).To(Equal(codes.Error))
+			})
+		})
 	})
 
 	Describe("RemoveDeletionFinalizer", func() {
@@ -70,9 +133,9 @@ var _ = Describe("FinalizersUpdater", func() {
 			dc.SetFinalizers([]string{examplecomv1alpha1.DeviceConfigDeletionFinalizer})
 		})
 
-		Context("with a successful update", func() {
+		Context("with a successful patch", func() {
 			BeforeEach(func() {
-				c.EXPECT().Update(context.TODO(), dc)
+				c.EXPECT().Patch(context.TODO(), dc, gomock.Any())
 			})
 
 			It("should remove the finalizer from the DeviceConfig", func() {
@@ -82,9 +145,17 @@ var _ = Describe("FinalizersUpdater", func() {
 			})
 		})
 
-		Context("with a failed update", func() {
+		Context("without a deletion finalizer", func() {
+			It("should not patch the DeviceConfig", func() {
+				dc.SetFinalizers(nil)
+
+				Expect(u.RemoveDeletionFinalizer(context.TODO(), dc)).To(Succeed())
+			})
+		})
+
+		Context("with a failed patch", func() {
 			BeforeEach(func() {
-				c.EXPECT().Update(context.TODO(), dc).Return(errors.New("some error"))
+				c.EXPECT().Patch(context.TODO(), dc, gomock.Any()).Return(errors.New("some error"))
 			})
 
 			It("should return an error", func() {
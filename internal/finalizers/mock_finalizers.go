@@ -36,7 +36,7 @@ func (m *MockUpdater) EXPECT() *MockUpdaterMockRecorder {
 }
 
 // AddDeletionFinalizer mocks base method.
-func (m *MockUpdater) AddDeletionFinalizer(ctx context.Context, cr *v1alpha1.DeviceConfig) error {
+func (m *MockUpdater) AddDeletionFinalizer(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AddDeletionFinalizer", ctx, cr)
 	ret0, _ := ret[0].(error)
@@ -50,7 +50,7 @@ func (mr *MockUpdaterMockRecorder) AddDeletionFinalizer(ctx, cr interface{}) *go
 }
 
 // ContainsDeletionFinalizer mocks base method.
-func (m *MockUpdater) ContainsDeletionFinalizer(cr *v1alpha1.DeviceConfig) bool {
+func (m *MockUpdater) ContainsDeletionFinalizer(cr v1alpha1.DeviceConfigObject) bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ContainsDeletionFinalizer", cr)
 	ret0, _ := ret[0].(bool)
@@ -64,7 +64,7 @@ func (mr *MockUpdaterMockRecorder) ContainsDeletionFinalizer(cr interface{}) *go
 }
 
 // RemoveDeletionFinalizer mocks base method.
-func (m *MockUpdater) RemoveDeletionFinalizer(ctx context.Context, cr *v1alpha1.DeviceConfig) error {
+func (m *MockUpdater) RemoveDeletionFinalizer(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "RemoveDeletionFinalizer", ctx, cr)
 	ret0, _ := ret[0].(error)
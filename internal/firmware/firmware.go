@@ -0,0 +1,237 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firmware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/companion"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	// Role is the value of the KMM role label of the firmware DaemonSets and pods.
+	Role = "firmware"
+
+	// VersionLabel is set on the firmware pods to the version of the firmware they install.
+	VersionLabel = "example.com/firmware-version"
+
+	installContainerName = "install-firmware"
+	containerName        = "firmware"
+	firmwareVolume       = "node-firmware"
+	// sourcePath is the directory of the firmware image holding the firmware blobs.
+	sourcePath = "/firmware"
+	// nodePath is where the node firmware directory is mounted in the firmware pods.
+	nodePath = "/host-firmware"
+)
+
+// ErrIncompatibleFirmware is returned when the firmware version does not match the driver
+// version.
+var ErrIncompatibleFirmware = errors.New("incompatible firmware")
+
+//go:generate mockgen -source=firmware.go -package=firmware -destination=mock_firmware.go
+
+// Reconciler installs the firmware of a DeviceConfig on its nodes with a companion DaemonSet,
+// which copies the firmware blobs to the node before the driver is loaded. The nodes are
+// labelled with the installed firmware version, which the driver node selector requires.
+type Reconciler interface {
+	ReconcileFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (*examplecomv1alpha1.FirmwareStatus, error)
+	DeleteFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	ReleaseFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+}
+
+type reconciler struct {
+	daemonSet *companion.DaemonSet
+}
+
+func NewReconciler(c client.Client, s *runtime.Scheme, driverNamespace string) Reconciler {
+	return &reconciler{
+		daemonSet: companion.NewDaemonSet(c, s, driverNamespace, Role, examplecomv1alpha1.DeviceConfigObject.GetFirmwareNodeLabel),
+	}
+}
+
+// ReconcileFirmware installs the firmware of the given resource on its nodes and returns the
+// firmware version installed on each of them. The firmware resources are removed when the
+// resource has no firmware.
+func (r *reconciler) ReconcileFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ *examplecomv1alpha1.FirmwareStatus, err error) {
+	ctx, span := tracing.Start(ctx, "firmware.ReconcileFirmware", cr)
+	defer tracing.End(span, &err)
+
+	fw := cr.GetSpec().Firmware
+	if fw == nil {
+		installed, err := r.daemonSet.Exists(ctx, cr)
+		if err != nil || !installed {
+			return nil, err
+		}
+		return nil, r.DeleteFirmware(ctx, cr)
+	}
+
+	if err := Validate(cr.GetSpec()); err != nil {
+		return nil, err
+	}
+
+	namespace, err := r.daemonSet.Reconcile(ctx, cr, map[string]string{VersionLabel: fw.Version}, makePodSpec(fw))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.labelNodes(ctx, cr, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &examplecomv1alpha1.FirmwareStatus{Version: fw.Version, Nodes: nodes}, nil
+}
+
+// DeleteFirmware deletes the firmware DaemonSet of the given resource and the firmware labels
+// of its nodes. The firmware blobs are left on the nodes.
+func (r *reconciler) DeleteFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "firmware.DeleteFirmware", cr)
+	defer tracing.End(span, &err)
+
+	return r.daemonSet.Delete(ctx, cr)
+}
+
+// ReleaseFirmware leaves the firmware DaemonSet of the given deleted resource running along
+// with its released driver, until it is adopted by a resource of the same name.
+func (r *reconciler) ReleaseFirmware(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "firmware.ReleaseFirmware", cr)
+	defer tracing.End(span, &err)
+
+	return r.daemonSet.Release(ctx, cr)
+}
+
+// Validate returns an error if the firmware of the given spec is invalid or does not share its
+// major version with the driver version.
+func Validate(spec *examplecomv1alpha1.DeviceConfigSpec) error {
+	fw := spec.Firmware
+
+	if errs := validation.IsValidLabelValue(fw.Version); len(errs) > 0 {
+		return fmt.Errorf("invalid firmware version %q: %s", fw.Version, strings.Join(errs, ", "))
+	}
+	if p := fw.GetFirmwarePath(); !filepath.IsAbs(p) || filepath.Clean(p) != p {
+		return fmt.Errorf("invalid firmware path %q: must be a clean absolute path", p)
+	}
+
+	firmwareMajor, ok := majorVersion(fw.Version)
+	if !ok {
+		return fmt.Errorf("%w: firmware version %s has no major version", ErrIncompatibleFirmware, fw.Version)
+	}
+	driverMajor, ok := majorVersion(spec.DriverVersion)
+	if !ok || firmwareMajor != driverMajor {
+		return fmt.Errorf("%w: firmware version %s does not match the major version of driver version %s",
+			ErrIncompatibleFirmware, fw.Version, spec.DriverVersion)
+	}
+
+	return nil
+}
+
+// majorVersion returns the leading number of a version, e.g. 2 for v2.1.0-rc1.
+func majorVersion(version string) (string, bool) {
+	v := strings.TrimPrefix(version, "v")
+	end := strings.IndexFunc(v, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end == -1 {
+		end = len(v)
+	}
+	if end == 0 {
+		return "", false
+	}
+
+	major := strings.TrimLeft(v[:end], "0")
+	if major == "" {
+		major = "0"
+	}
+	return major, true
+}
+
+// GetDaemonSetName returns the name of the DaemonSet installing the firmware of the given
+// resource.
+func GetDaemonSetName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	return module.GetModuleName(cr) + "-" + Role
+}
+
+// labelNodes sets the firmware label of the nodes to the version installed by their ready
+// firmware pod and returns the firmware version of every labelled node.
+func (r *reconciler) labelNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, namespace string) ([]examplecomv1alpha1.NodeFirmware, error) {
+	versions, _, err := r.daemonSet.SyncNodeLabels(ctx, cr, namespace, func(p *corev1.Pod) string {
+		return p.Labels[VersionLabel]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]examplecomv1alpha1.NodeFirmware, 0, len(versions))
+	for name, version := range versions {
+		nodes = append(nodes, examplecomv1alpha1.NodeFirmware{Node: name, Version: version})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+
+	return nodes, nil
+}
+
+// makePodSpec returns the spec of the pods installing the given firmware.
+func makePodSpec(fw *examplecomv1alpha1.FirmwareSpec) corev1.PodSpec {
+	defaults := config.GetDefaults()
+	privileged := true
+	hostPathType := corev1.HostPathDirectoryOrCreate
+
+	return corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{
+				Name:            installContainerName,
+				Image:           fw.Image,
+				ImagePullPolicy: defaults.ImagePullPolicy,
+				Command:         []string{"cp", "-rf", sourcePath + "/.", nodePath + "/"},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: firmwareVolume, MountPath: nodePath},
+				},
+			},
+		},
+		// The pod keeps running once the firmware is installed, so that its readiness reports
+		// the installed firmware.
+		Containers: []corev1.Container{
+			{
+				Name:            containerName,
+				Image:           fw.Image,
+				ImagePullPolicy: defaults.ImagePullPolicy,
+				Command:         []string{"sleep", "infinity"},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{
+				Name: firmwareVolume,
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: fw.GetFirmwarePath(), Type: &hostPathType},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firmware
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	mockClient "github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/companion"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+)
+
+var _ = Describe("Validate", func() {
+	spec := func(driverVersion, firmwareVersion string) *examplecomv1alpha1.DeviceConfigSpec {
+		return &examplecomv1alpha1.DeviceConfigSpec{
+			DriverVersion: driverVersion,
+			Firmware:      &examplecomv1alpha1.FirmwareSpec{Image: "quay.io/example/firmware", Version: firmwareVersion},
+		}
+	}
+
+	DescribeTable("should accept a firmware sharing the driver major version",
+		func(driverVersion, firmwareVersion string) {
+			Expect(Validate(spec(driverVersion, firmwareVersion))).To(Succeed())
+		},
+		Entry("same version", "2.1.0", "2.1.0"),
+		Entry("other minor version", "2.1.0", "2.4"),
+		Entry("prefixed version", "v2.1.0", "02.0.1-rc1"),
+	)
+
+	DescribeTable("should reject an incompatible firmware",
+		func(driverVersion, firmwareVersion string) {
+			Expect(Validate(spec(driverVersion, firmwareVersion))).To(MatchError(ErrIncompatibleFirmware))
+		},
+		Entry("other major version", "2.1.0", "3.1.0"),
+		Entry("driver version without major version", "latest", "3.1.0"),
+		Entry("firmware version without major version", "2.1.0", "latest"),
+	)
+
+	It("should reject an invalid firmware", func() {
+		s := spec("2.1.0", "2.1.0+build/1")
+		Expect(Validate(s)).To(MatchError(ContainSubstring("invalid firmware version")))
+
+		s = spec("2.1.0", "2.1.0")
+		s.Firmware.Path = "/lib/../firmware"
+		Expect(Validate(s)).To(MatchError(ContainSubstring("invalid firmware path")))
+	})
+})
+
+var _ = Describe("Reconciler", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	nfdLabels := map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true"}
+
+	makeNode := func(name string, labels map[string]string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		for k, v := range labels {
+			n.Labels[k] = v
+		}
+		return n
+	}
+
+	makePod := func(name, node, version string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		labels := companion.GetManagedLabels(dc, Role)
+		labels[VersionLabel] = version
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	getNodeLabels := func(c client.Client, name string) map[string]string {
+		n := &corev1.Node{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: name}, n)).To(Succeed())
+		return n.Labels
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace, UID: "a-uid"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				DriverImage:   "quay.io/example/driver",
+				DriverVersion: "2.1.0",
+				ModuleName:    "example-driver",
+				Firmware: &examplecomv1alpha1.FirmwareSpec{
+					Image:   "quay.io/example/firmware:2.0.3",
+					Version: "2.0.3",
+				},
+			},
+		}
+	})
+
+	Describe("ReconcileFirmware", func() {
+		It("should install the firmware on the driver nodes", func() {
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+
+			ds := &appsv1.DaemonSet{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: GetDaemonSetName(dc)}, ds)).To(Succeed())
+			Expect(metav1.IsControlledBy(ds, dc)).To(BeTrue())
+			Expect(ds.Labels).To(HaveKeyWithValue(module.RoleLabel, Role))
+			Expect(ds.Labels).To(HaveKeyWithValue(native.ManagedByLabel, native.ManagedByValue))
+
+			pod := ds.Spec.Template.Spec
+			Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(VersionLabel, "2.0.3"))
+			Expect(pod.NodeSelector).To(Equal(nfdLabels))
+			Expect(pod.InitContainers).To(HaveLen(1))
+			Expect(pod.InitContainers[0].Image).To(Equal("quay.io/example/firmware:2.0.3"))
+			Expect(pod.Volumes).To(HaveLen(1))
+			Expect(pod.Volumes[0].HostPath.Path).To(Equal(examplecomv1alpha1.DefaultFirmwarePath))
+
+			// The driver is only scheduled on the nodes with the firmware installed.
+			Expect(dc.GetModuleNodeSelector()).To(HaveKeyWithValue(dc.GetFirmwareNodeLabel(), "2.0.3"))
+		})
+
+		It("should label the nodes with their installed firmware version", func() {
+			key := dc.GetFirmwareNodeLabel()
+			c := fake.NewClientBuilder().
+				WithScheme(s).
+				WithObjects(
+					makeNode("node-a", nfdLabels),
+					makeNode("node-b", map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true", key: "2.0.1"}),
+					makeNode("node-c", map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true", key: "2.0.1"}),
+					makeNode("node-d", map[string]string{key: "2.0.1"}),
+					makeNode("node-e", map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true", key: "2.0.1"}),
+					makePod("pod-a", "node-a", "2.0.3", true),
+					makePod("pod-b", "node-b", "2.0.3", true),
+					makePod("pod-c", "node-c", "2.0.3", false),
+				).
+				Build()
+
+			status, err := NewReconciler(c, s, testDriverNamespace).ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(&examplecomv1alpha1.FirmwareStatus{
+				Version: "2.0.3",
+				Nodes: []examplecomv1alpha1.NodeFirmware{
+					{Node: "node-a", Version: "2.0.3"},
+					{Node: "node-b", Version: "2.0.3"},
+					{Node: "node-c", Version: "2.0.1"},
+					{Node: "node-e", Version: "2.0.1"},
+				},
+			}))
+
+			Expect(getNodeLabels(c, "node-a")).To(HaveKeyWithValue(key, "2.0.3"))
+			Expect(getNodeLabels(c, "node-b")).To(HaveKeyWithValue(key, "2.0.3"))
+			Expect(getNodeLabels(c, "node-c")).To(HaveKeyWithValue(key, "2.0.1"))
+			Expect(getNodeLabels(c, "node-d")).ToNot(HaveKey(key))
+			// The firmware pod of node-e is being replaced by a rolling update.
+			Expect(getNodeLabels(c, "node-e")).To(HaveKeyWithValue(key, "2.0.1"))
+		})
+
+		It("should not install an incompatible firmware", func() {
+			dc.Spec.Firmware.Version = "3.0.0"
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileFirmware(ctx, dc)
+			Expect(err).To(MatchError(ErrIncompatibleFirmware))
+
+			dss := &appsv1.DaemonSetList{}
+			Expect(c.List(ctx, dss)).To(Succeed())
+			Expect(dss.Items).To(BeEmpty())
+		})
+
+		It("should remove the firmware once it is no longer configured", func() {
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			r := NewReconciler(c, s, testDriverNamespace)
+
+			_, err := r.ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Create(ctx, makeNode("node-a", map[string]string{dc.GetFirmwareNodeLabel(): "2.0.3"}))).To(Succeed())
+
+			dc.Spec.Firmware = nil
+			status, err := r.ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(BeNil())
+
+			dss := &appsv1.DaemonSetList{}
+			Expect(c.List(ctx, dss)).To(Succeed())
+			Expect(dss.Items).To(BeEmpty())
+			Expect(getNodeLabels(c, "node-a")).To(BeEmpty())
+		})
+
+		It("should only look up the cache when the firmware was never installed", func() {
+			c := mockClient.NewMockClient(gomock.NewController(GinkgoT()))
+			gomock.InOrder(
+				c.EXPECT().List(ctx, &appsv1.DaemonSetList{}, gomock.Any()).Return(nil),
+				c.EXPECT().List(ctx, &corev1.NodeList{}, gomock.Any()).Return(nil),
+			)
+
+			dc.Spec.Firmware = nil
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteFirmware", func() {
+		It("should only delete the firmware DaemonSet of the resource", func() {
+			driver := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "a-driver",
+					Namespace: testNamespace,
+					Labels: map[string]string{
+						module.ModuleNameLabel: module.GetModuleName(dc),
+						module.RoleLabel:       module.ModuleLoaderRole,
+						native.ManagedByLabel:  native.ManagedByValue,
+					},
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(driver).Build()
+			r := NewReconciler(c, s, testDriverNamespace)
+
+			_, err := r.ReconcileFirmware(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.DeleteFirmware(ctx, dc)).To(Succeed())
+
+			dss := &appsv1.DaemonSetList{}
+			Expect(c.List(ctx, dss)).To(Succeed())
+			Expect(dss.Items).To(HaveLen(1))
+			Expect(dss.Items[0].Name).To(Equal("a-driver"))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: firmware.go
+
+// Package firmware is a generated GoMock package.
+package firmware
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockReconciler is a mock of Reconciler interface.
+type MockReconciler struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconcilerMockRecorder
+}
+
+// MockReconcilerMockRecorder is the mock recorder for MockReconciler.
+type MockReconcilerMockRecorder struct {
+	mock *MockReconciler
+}
+
+// NewMockReconciler creates a new mock instance.
+func NewMockReconciler(ctrl *gomock.Controller) *MockReconciler {
+	mock := &MockReconciler{ctrl: ctrl}
+	mock.recorder = &MockReconcilerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciler) EXPECT() *MockReconcilerMockRecorder {
+	return m.recorder
+}
+
+// DeleteFirmware mocks base method.
+func (m *MockReconciler) DeleteFirmware(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFirmware", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFirmware indicates an expected call of DeleteFirmware.
+func (mr *MockReconcilerMockRecorder) DeleteFirmware(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFirmware", reflect.TypeOf((*MockReconciler)(nil).DeleteFirmware), ctx, cr)
+}
+
+// ReconcileFirmware mocks base method.
+func (m *MockReconciler) ReconcileFirmware(ctx context.Context, cr v1alpha1.DeviceConfigObject) (*v1alpha1.FirmwareStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileFirmware", ctx, cr)
+	ret0, _ := ret[0].(*v1alpha1.FirmwareStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileFirmware indicates an expected call of ReconcileFirmware.
+func (mr *MockReconcilerMockRecorder) ReconcileFirmware(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileFirmware", reflect.TypeOf((*MockReconciler)(nil).ReconcileFirmware), ctx, cr)
+}
+
+// ReleaseFirmware mocks base method.
+func (m *MockReconciler) ReleaseFirmware(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseFirmware", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseFirmware indicates an expected call of ReleaseFirmware.
+func (mr *MockReconcilerMockRecorder) ReleaseFirmware(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseFirmware", reflect.TypeOf((*MockReconciler)(nil).ReleaseFirmware), ctx, cr)
+}
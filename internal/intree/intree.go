@@ -0,0 +1,315 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package intree
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/companion"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	// Role is the value of the KMM role label of the in-tree DaemonSets and pods.
+	Role = "intree"
+
+	unloadContainerName = "unload-intree"
+	containerName       = "intree"
+	modprobeVolume      = "node-modprobe"
+	modulesVolume       = "node-modules"
+	// modprobePath is where the node modprobe configuration directory is mounted in the in-tree
+	// pods.
+	modprobePath = "/host-modprobe.d"
+	modulesPath  = "/lib/modules"
+)
+
+// unloadScript records which of the in-tree kernel modules are loaded, blacklists them and
+// unloads them in order. The modules recorded by a previous pod are kept, since they have
+// already been unloaded, and the recorded modules are reported in the termination message.
+const unloadScript = `set -eu
+set -f
+present=""
+if [ -f "$BLACKLIST_FILE" ]; then
+  present=$(sed -n 's/^# present://p' "$BLACKLIST_FILE")
+fi
+for m in $IN_TREE_MODULES; do
+  case " $present " in
+    *" $m "*) ;;
+    *) if grep -q "^$(echo "$m" | tr - _) " /proc/modules; then present="$present $m"; fi ;;
+  esac
+done
+{
+  echo "# present:$present"
+  for m in $IN_TREE_MODULES; do echo "blacklist $m"; done
+} > "$BLACKLIST_FILE"
+for m in $IN_TREE_MODULES; do
+  if grep -q "^$(echo "$m" | tr - _) " /proc/modules; then rmmod "$m"; fi
+done
+echo $present > /dev/termination-log
+`
+
+// restoreScript removes the blacklist and loads the recorded in-tree kernel modules again,
+// unless the driver is still loaded, e.g. when the pod is only restarted.
+const restoreScript = `set -f
+for m in $DRIVER_MODULES; do
+  if grep -q "^$(echo "$m" | tr - _) " /proc/modules; then exit 0; fi
+done
+present=""
+if [ -f "$BLACKLIST_FILE" ]; then
+  present=$(sed -n 's/^# present://p' "$BLACKLIST_FILE")
+  rm -f "$BLACKLIST_FILE"
+fi
+for m in $present; do modprobe "$m" || true; done
+`
+
+//go:generate mockgen -source=intree.go -package=intree -destination=mock_intree.go
+
+// Reconciler replaces the in-tree kernel modules of a DeviceConfig on its nodes with a
+// companion DaemonSet, which unloads and blacklists them before the driver is loaded and loads
+// them again once the DeviceConfig is deleted. The nodes are labelled once the in-tree kernel
+// modules are unloaded, which the driver node selector requires.
+type Reconciler interface {
+	ReconcileInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) ([]examplecomv1alpha1.NodeInTreeModules, error)
+	RestoreInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	ReleaseInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+}
+
+type reconciler struct {
+	daemonSet *companion.DaemonSet
+}
+
+func NewReconciler(c client.Client, s *runtime.Scheme, driverNamespace string) Reconciler {
+	return &reconciler{
+		daemonSet: companion.NewDaemonSet(c, s, driverNamespace, Role, examplecomv1alpha1.DeviceConfigObject.GetInTreeNodeLabel),
+	}
+}
+
+// ReconcileInTreeModules unloads the in-tree kernel modules replaced by the given resource
+// from its nodes and returns the ones which were loaded on each of them. The in-tree kernel
+// modules are restored when the resource no longer replaces any.
+func (r *reconciler) ReconcileInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ []examplecomv1alpha1.NodeInTreeModules, err error) {
+	ctx, span := tracing.Start(ctx, "intree.ReconcileInTreeModules", cr)
+	defer tracing.End(span, &err)
+
+	if len(cr.GetSpec().ReplaceInTree) == 0 {
+		replaced, err := r.daemonSet.Exists(ctx, cr)
+		if err != nil || !replaced {
+			return nil, err
+		}
+		return nil, r.RestoreInTreeModules(ctx, cr)
+	}
+
+	if err := Validate(cr); err != nil {
+		return nil, err
+	}
+
+	podSpec, err := makePodSpec(cr)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := r.daemonSet.Reconcile(ctx, cr, nil, podSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.labelNodes(ctx, cr, namespace)
+}
+
+// RestoreInTreeModules deletes the in-tree DaemonSet of the given resource and the in-tree
+// labels of its nodes. The in-tree pods load the in-tree kernel modules again on the nodes the
+// driver is no longer loaded on, and keep them blacklisted on the others.
+func (r *reconciler) RestoreInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "intree.RestoreInTreeModules", cr)
+	defer tracing.End(span, &err)
+
+	return r.daemonSet.Delete(ctx, cr)
+}
+
+// ReleaseInTreeModules leaves the in-tree DaemonSet of the given deleted resource running along
+// with its released driver, so that the in-tree kernel modules stay blacklisted until it is
+// adopted by a resource of the same name.
+func (r *reconciler) ReleaseInTreeModules(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "intree.ReleaseInTreeModules", cr)
+	defer tracing.End(span, &err)
+
+	return r.daemonSet.Release(ctx, cr)
+}
+
+// Validate returns an error if the given resource replaces one of its own kernel modules.
+func Validate(cr examplecomv1alpha1.DeviceConfigObject) error {
+	spec := cr.GetSpec()
+
+	driverModules := map[string]bool{normalize(spec.ModuleName): true}
+	for _, km := range spec.KernelModules {
+		driverModules[normalize(km.Name)] = true
+	}
+
+	for _, m := range spec.ReplaceInTree {
+		if driverModules[normalize(m)] {
+			return fmt.Errorf("in-tree kernel module %q cannot replace a kernel module of the driver", m)
+		}
+	}
+
+	return nil
+}
+
+// normalize returns the name of a kernel module as reported by the kernel, which does not
+// tell dashes and underscores apart.
+func normalize(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// GetDaemonSetName returns the name of the DaemonSet replacing the in-tree kernel modules of
+// the given resource.
+func GetDaemonSetName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	return module.GetModuleName(cr) + "-" + Role
+}
+
+// GetBlacklistFile returns the name of the node modprobe configuration file blacklisting the
+// in-tree kernel modules of the given resource, which is unique across namespaces since the
+// nodes are shared.
+func GetBlacklistFile(cr examplecomv1alpha1.DeviceConfigObject) string {
+	name := module.GetModuleName(cr) + "-intree.conf"
+	if ns := cr.GetNamespace(); ns != "" {
+		name = ns + "-" + name
+	}
+	return name
+}
+
+// labelNodes sets the in-tree label of the nodes whose in-tree pod is ready and returns the
+// in-tree kernel modules which were loaded on every labelled node.
+func (r *reconciler) labelNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, namespace string) ([]examplecomv1alpha1.NodeInTreeModules, error) {
+	replaced, pods, err := r.daemonSet.SyncNodeLabels(ctx, cr, namespace, func(*corev1.Pod) string {
+		return examplecomv1alpha1.InTreeNodeLabelValue
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string][]string)
+	for i := range pods {
+		if modules, ok := getPresentModules(&pods[i]); ok {
+			present[pods[i].Spec.NodeName] = modules
+		}
+	}
+
+	nodes := make([]examplecomv1alpha1.NodeInTreeModules, 0, len(replaced))
+	for name := range replaced {
+		nodes = append(nodes, examplecomv1alpha1.NodeInTreeModules{Node: name, Present: present[name]})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+
+	return nodes, nil
+}
+
+// getPresentModules returns the in-tree kernel modules reported by the unload container of the
+// given pod once it has succeeded.
+func getPresentModules(p *corev1.Pod) ([]string, bool) {
+	for _, s := range p.Status.InitContainerStatuses {
+		if s.Name != unloadContainerName {
+			continue
+		}
+		if t := s.State.Terminated; t != nil && t.ExitCode == 0 {
+			return strings.Fields(t.Message), true
+		}
+		if t := s.LastTerminationState.Terminated; t != nil && t.ExitCode == 0 {
+			return strings.Fields(t.Message), true
+		}
+	}
+	return nil, false
+}
+
+// makePodSpec returns the spec of the pods replacing the in-tree kernel modules of the given
+// resource.
+func makePodSpec(cr examplecomv1alpha1.DeviceConfigObject) (corev1.PodSpec, error) {
+	defaults := config.GetDefaults()
+
+	driverModules, err := module.GetLoadOrder(cr)
+	if err != nil {
+		return corev1.PodSpec{}, err
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "IN_TREE_MODULES", Value: strings.Join(cr.GetSpec().ReplaceInTree, " ")},
+		{Name: "DRIVER_MODULES", Value: strings.Join(driverModules, " ")},
+		{Name: "BLACKLIST_FILE", Value: modprobePath + "/" + GetBlacklistFile(cr)},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{Name: modprobeVolume, MountPath: modprobePath},
+		{Name: modulesVolume, MountPath: modulesPath, ReadOnly: true},
+	}
+
+	privileged := true
+	hostPathType := corev1.HostPathDirectoryOrCreate
+
+	return corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{
+				Name:            unloadContainerName,
+				Image:           defaults.UtilityImage,
+				ImagePullPolicy: defaults.ImagePullPolicy,
+				Command:         []string{"/bin/sh", "-c", unloadScript},
+				Env:             env,
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+				},
+				VolumeMounts: volumeMounts,
+			},
+		},
+		// The pod keeps running while the in-tree kernel modules are replaced, so that its
+		// readiness reports them unloaded and its deletion restores them.
+		Containers: []corev1.Container{
+			{
+				Name:            containerName,
+				Image:           defaults.UtilityImage,
+				ImagePullPolicy: defaults.ImagePullPolicy,
+				Command:         []string{"sleep", "infinity"},
+				Env:             env,
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{
+						Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", restoreScript}},
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+				},
+				VolumeMounts: volumeMounts,
+			},
+		},
+		Volumes: []corev1.Volume{
+			{
+				Name: modprobeVolume,
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/etc/modprobe.d", Type: &hostPathType},
+				},
+			},
+			{
+				Name: modulesVolume,
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: modulesPath},
+				},
+			},
+		},
+	}, nil
+}
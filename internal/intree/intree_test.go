@@ -0,0 +1,222 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package intree
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	mockClient "github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/companion"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+)
+
+var _ = Describe("Validate", func() {
+	It("should reject an in-tree kernel module of the driver", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				ModuleName:    "example_driver",
+				KernelModules: []examplecomv1alpha1.KernelModule{{Name: "example-helper"}},
+				ReplaceInTree: []string{"example_legacy"},
+			},
+		}
+		Expect(Validate(dc)).To(Succeed())
+
+		dc.Spec.ReplaceInTree = []string{"example_legacy", "example-driver"}
+		Expect(Validate(dc)).To(MatchError(ContainSubstring(`"example-driver"`)))
+
+		dc.Spec.ReplaceInTree = []string{"example_helper"}
+		Expect(Validate(dc)).To(MatchError(ContainSubstring(`"example_helper"`)))
+	})
+})
+
+var _ = Describe("Reconciler", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	nfdLabels := map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true"}
+
+	makeNode := func(name string, labels map[string]string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		for k, v := range labels {
+			n.Labels[k] = v
+		}
+		return n
+	}
+
+	makePod := func(name, node, present string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		state := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		if ready {
+			state = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: present}}
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: companion.GetManagedLabels(dc, Role)},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions:            []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+				InitContainerStatuses: []corev1.ContainerStatus{{Name: unloadContainerName, State: state}},
+			},
+		}
+	}
+
+	getNodeLabels := func(c client.Client, name string) map[string]string {
+		n := &corev1.Node{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: name}, n)).To(Succeed())
+		return n.Labels
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace, UID: "a-uid"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				DriverImage:   "quay.io/example/driver",
+				DriverVersion: "2.1.0",
+				ModuleName:    "example_driver",
+				ReplaceInTree: []string{"example_legacy", "example_legacy_core"},
+			},
+		}
+	})
+
+	Describe("ReconcileInTreeModules", func() {
+		It("should replace the in-tree kernel modules on the driver nodes", func() {
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileInTreeModules(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+
+			ds := &appsv1.DaemonSet{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: GetDaemonSetName(dc)}, ds)).To(Succeed())
+			Expect(metav1.IsControlledBy(ds, dc)).To(BeTrue())
+			Expect(ds.Labels).To(HaveKeyWithValue(module.RoleLabel, Role))
+			Expect(ds.Labels).To(HaveKeyWithValue(native.ManagedByLabel, native.ManagedByValue))
+
+			pod := ds.Spec.Template.Spec
+			Expect(pod.NodeSelector).To(Equal(nfdLabels))
+			Expect(pod.InitContainers).To(HaveLen(1))
+			Expect(pod.InitContainers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "IN_TREE_MODULES", Value: "example_legacy example_legacy_core"},
+				corev1.EnvVar{Name: "DRIVER_MODULES", Value: "example_driver"},
+				corev1.EnvVar{Name: "BLACKLIST_FILE", Value: "/host-modprobe.d/a-namespace-a-device-config-module-intree.conf"},
+			))
+			Expect(pod.Containers).To(HaveLen(1))
+			Expect(pod.Containers[0].Lifecycle.PreStop).ToNot(BeNil())
+
+			// The driver is only scheduled on the nodes with the in-tree kernel modules unloaded.
+			Expect(dc.GetModuleNodeSelector()).To(HaveKeyWithValue(dc.GetInTreeNodeLabel(), examplecomv1alpha1.InTreeNodeLabelValue))
+		})
+
+		It("should label the nodes and report the in-tree kernel modules which were loaded", func() {
+			key := dc.GetInTreeNodeLabel()
+			value := examplecomv1alpha1.InTreeNodeLabelValue
+			c := fake.NewClientBuilder().
+				WithScheme(s).
+				WithObjects(
+					makeNode("node-a", nfdLabels),
+					makeNode("node-b", map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true", key: value}),
+					makeNode("node-c", map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true", key: value}),
+					makeNode("node-d", map[string]string{key: value}),
+					makePod("pod-a", "node-a", "example_legacy example_legacy_core\n", true),
+					makePod("pod-b", "node-b", "", true),
+					makePod("pod-c", "node-c", "", false),
+				).
+				Build()
+
+			nodes, err := NewReconciler(c, s, testDriverNamespace).ReconcileInTreeModules(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodes).To(Equal([]examplecomv1alpha1.NodeInTreeModules{
+				{Node: "node-a", Present: []string{"example_legacy", "example_legacy_core"}},
+				{Node: "node-b", Present: []string{}},
+				{Node: "node-c"},
+			}))
+
+			Expect(getNodeLabels(c, "node-a")).To(HaveKeyWithValue(key, value))
+			Expect(getNodeLabels(c, "node-b")).To(HaveKeyWithValue(key, value))
+			Expect(getNodeLabels(c, "node-c")).To(HaveKeyWithValue(key, value))
+			Expect(getNodeLabels(c, "node-d")).ToNot(HaveKey(key))
+		})
+
+		It("should not replace a kernel module of the driver", func() {
+			dc.Spec.ReplaceInTree = []string{"example-driver"}
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileInTreeModules(ctx, dc)
+			Expect(err).To(HaveOccurred())
+
+			dss := &appsv1.DaemonSetList{}
+			Expect(c.List(ctx, dss)).To(Succeed())
+			Expect(dss.Items).To(BeEmpty())
+		})
+
+		It("should restore the in-tree kernel modules once they are no longer replaced", func() {
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+			r := NewReconciler(c, s, testDriverNamespace)
+
+			_, err := r.ReconcileInTreeModules(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Create(ctx, makeNode("node-a", map[string]string{dc.GetInTreeNodeLabel(): examplecomv1alpha1.InTreeNodeLabelValue}))).To(Succeed())
+
+			dc.Spec.ReplaceInTree = nil
+			nodes, err := r.ReconcileInTreeModules(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodes).To(BeNil())
+
+			dss := &appsv1.DaemonSetList{}
+			Expect(c.List(ctx, dss)).To(Succeed())
+			Expect(dss.Items).To(BeEmpty())
+			Expect(getNodeLabels(c, "node-a")).To(BeEmpty())
+		})
+
+		It("should only look up the cache when the in-tree kernel modules were never replaced", func() {
+			c := mockClient.NewMockClient(gomock.NewController(GinkgoT()))
+			gomock.InOrder(
+				c.EXPECT().List(ctx, &appsv1.DaemonSetList{}, gomock.Any()).Return(nil),
+				c.EXPECT().List(ctx, &corev1.NodeList{}, gomock.Any()).Return(nil),
+			)
+
+			dc.Spec.ReplaceInTree = nil
+			_, err := NewReconciler(c, s, testDriverNamespace).ReconcileInTreeModules(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
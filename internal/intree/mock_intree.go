@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: intree.go
+
+// Package intree is a generated GoMock package.
+package intree
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockReconciler is a mock of Reconciler interface.
+type MockReconciler struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconcilerMockRecorder
+}
+
+// MockReconcilerMockRecorder is the mock recorder for MockReconciler.
+type MockReconcilerMockRecorder struct {
+	mock *MockReconciler
+}
+
+// NewMockReconciler creates a new mock instance.
+func NewMockReconciler(ctrl *gomock.Controller) *MockReconciler {
+	mock := &MockReconciler{ctrl: ctrl}
+	mock.recorder = &MockReconcilerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciler) EXPECT() *MockReconcilerMockRecorder {
+	return m.recorder
+}
+
+// ReconcileInTreeModules mocks base method.
+func (m *MockReconciler) ReconcileInTreeModules(ctx context.Context, cr v1alpha1.DeviceConfigObject) ([]v1alpha1.NodeInTreeModules, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileInTreeModules", ctx, cr)
+	ret0, _ := ret[0].([]v1alpha1.NodeInTreeModules)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileInTreeModules indicates an expected call of ReconcileInTreeModules.
+func (mr *MockReconcilerMockRecorder) ReconcileInTreeModules(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileInTreeModules", reflect.TypeOf((*MockReconciler)(nil).ReconcileInTreeModules), ctx, cr)
+}
+
+// ReleaseInTreeModules mocks base method.
+func (m *MockReconciler) ReleaseInTreeModules(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseInTreeModules", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseInTreeModules indicates an expected call of ReleaseInTreeModules.
+func (mr *MockReconcilerMockRecorder) ReleaseInTreeModules(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseInTreeModules", reflect.TypeOf((*MockReconciler)(nil).ReleaseInTreeModules), ctx, cr)
+}
+
+// RestoreInTreeModules mocks base method.
+func (m *MockReconciler) RestoreInTreeModules(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreInTreeModules", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreInTreeModules indicates an expected call of RestoreInTreeModules.
+func (mr *MockReconcilerMockRecorder) RestoreInTreeModules(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreInTreeModules", reflect.TypeOf((*MockReconciler)(nil).RestoreInTreeModules), ctx, cr)
+}
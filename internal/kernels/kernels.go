@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+//go:generate mockgen -source=kernels.go -package=kernels -destination=mock_kernels.go
+
+// CoverageReporter reports how the kernels of the nodes selected by a DeviceConfig are covered by its
+// kernel mappings.
+type CoverageReporter interface {
+	GetKernelCoverage(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) ([]examplecomv1alpha1.KernelCoverage, error)
+}
+
+type coverageReporter struct {
+	client client.Client
+}
+
+func NewCoverageReporter(c client.Client) CoverageReporter {
+	return &coverageReporter{client: c}
+}
+
+// GetKernelCoverage returns the selected nodes grouped by kernel version, sorted by kernel
+// version, along with the kernel mapping and driver image resolved for each of them.
+func (r *coverageReporter) GetKernelCoverage(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ []examplecomv1alpha1.KernelCoverage, err error) {
+	ctx, span := tracing.Start(ctx, "kernels.GetKernelCoverage", cr)
+	defer tracing.End(span, &err)
+
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector for %s: %w", cr.GetName(), err)
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := r.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	coverage := make(map[string]*examplecomv1alpha1.KernelCoverage)
+	for _, n := range nodeList.Items {
+		kernel := n.Status.NodeInfo.KernelVersion
+		if kernel == "" || !selector.Matches(labels.Set(n.Labels)) {
+			continue
+		}
+		kc, exists := coverage[kernel]
+		if !exists {
+			kc = &examplecomv1alpha1.KernelCoverage{KernelVersion: kernel}
+			kc.Mapping, kc.Image, _ = module.GetKernelMapping(cr, kernel)
+			coverage[kernel] = kc
+		}
+		kc.NodeCount++
+	}
+
+	kernels := make([]examplecomv1alpha1.KernelCoverage, 0, len(coverage))
+	for _, kc := range coverage {
+		kernels = append(kernels, *kc)
+	}
+	sort.Slice(kernels, func(i, j int) bool {
+		return kernels[i].KernelVersion < kernels[j].KernelVersion
+	})
+	return kernels, nil
+}
+
+// GetUnmappedKernels returns the kernel versions of the given coverage which are not matched
+// by any kernel mapping.
+func GetUnmappedKernels(coverage []examplecomv1alpha1.KernelCoverage) []string {
+	var unmapped []string
+	for _, kc := range coverage {
+		if kc.Mapping == "" {
+			unmapped = append(unmapped, kc.KernelVersion)
+		}
+	}
+	return unmapped
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernels
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+const (
+	testKernel         = "5.14.0-70.13.1.el9_0.x86_64"
+	testUnmappedKernel = "5.15.0-48-generic"
+)
+
+var _ = Describe("CoverageReporter", func() {
+	Describe("GetKernelCoverage", func() {
+		It("should break the selected nodes down by kernel version", func() {
+			dc := &examplecomv1alpha1.DeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+				Spec: examplecomv1alpha1.DeviceConfigSpec{
+					DriverImage:   "driver",
+					DriverVersion: "1.0",
+					NodeSelector:  map[string]string{"pool": "a"},
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				makeTestNode("node-a", "a", testKernel),
+				makeTestNode("node-b", "a", testKernel),
+				makeTestNode("node-c", "a", testUnmappedKernel),
+				makeTestNode("node-d", "b", testUnmappedKernel),
+			).Build()
+
+			coverage, err := NewCoverageReporter(c).GetKernelCoverage(context.TODO(), dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(coverage).To(Equal([]examplecomv1alpha1.KernelCoverage{
+				{
+					KernelVersion: testKernel,
+					Mapping:       `^.*\.el\d_?\d?\..*$`,
+					Image:         "driver:1.0-" + testKernel,
+					NodeCount:     2,
+				},
+				{KernelVersion: testUnmappedKernel, NodeCount: 1},
+			}))
+			Expect(GetUnmappedKernels(coverage)).To(Equal([]string{testUnmappedKernel}))
+		})
+	})
+})
+
+func makeTestNode(name, pool, kernel string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KernelVersion: kernel},
+		},
+	}
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: kernels.go
+
+// Package kernels is a generated GoMock package.
+package kernels
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockCoverageReporter is a mock of CoverageReporter interface.
+type MockCoverageReporter struct {
+	ctrl     *gomock.Controller
+	recorder *MockCoverageReporterMockRecorder
+}
+
+// MockCoverageReporterMockRecorder is the mock recorder for MockCoverageReporter.
+type MockCoverageReporterMockRecorder struct {
+	mock *MockCoverageReporter
+}
+
+// NewMockCoverageReporter creates a new mock instance.
+func NewMockCoverageReporter(ctrl *gomock.Controller) *MockCoverageReporter {
+	mock := &MockCoverageReporter{ctrl: ctrl}
+	mock.recorder = &MockCoverageReporterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCoverageReporter) EXPECT() *MockCoverageReporterMockRecorder {
+	return m.recorder
+}
+
+// GetKernelCoverage mocks base method.
+func (m *MockCoverageReporter) GetKernelCoverage(ctx context.Context, cr v1alpha1.DeviceConfigObject) ([]v1alpha1.KernelCoverage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKernelCoverage", ctx, cr)
+	ret0, _ := ret[0].([]v1alpha1.KernelCoverage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKernelCoverage indicates an expected call of GetKernelCoverage.
+func (mr *MockCoverageReporterMockRecorder) GetKernelCoverage(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKernelCoverage", reflect.TypeOf((*MockCoverageReporter)(nil).GetKernelCoverage), ctx, cr)
+}
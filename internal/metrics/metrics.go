@@ -18,18 +18,138 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
+const (
+	namespaceLabel    = "namespace"
+	deviceConfigLabel = "device_config"
+)
+
+// deviceConfigLabels are the labels identifying a DeviceConfig, or a ClusterDeviceConfig
+// with an empty namespace, on every series.
+var deviceConfigLabels = []string{namespaceLabel, deviceConfigLabel}
+
 var (
 	ReconciliationFailed = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "he_sample_operator_reconciliation_failed",
 			Help: "Reports whether the reconciliation per DeviceConfig is failed or not.",
 		},
-		[]string{"device_config"},
+		deviceConfigLabels,
+	)
+
+	ReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "he_sample_operator_reconcile_duration_seconds",
+			Help:    "Reports the duration of the reconciliations per DeviceConfig.",
+			Buckets: prometheus.DefBuckets,
+		},
+		deviceConfigLabels,
+	)
+
+	SelectedNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_selected_nodes",
+			Help: "Reports the number of nodes selected by a DeviceConfig.",
+		},
+		deviceConfigLabels,
+	)
+
+	DesiredNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_desired_nodes",
+			Help: "Reports the number of nodes which should run the driver of a DeviceConfig.",
+		},
+		deviceConfigLabels,
+	)
+
+	AvailableNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_available_nodes",
+			Help: "Reports the number of nodes on which the driver of a DeviceConfig is available.",
+		},
+		deviceConfigLabels,
+	)
+
+	ConflictingDeviceConfigs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_conflicting_device_configs",
+			Help: "Reports the number of DeviceConfigs whose node selectors conflict with a DeviceConfig.",
+		},
+		deviceConfigLabels,
+	)
+
+	UpgradeProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_upgrade_progress_ratio",
+			Help: "Reports the ratio of the desired nodes running the current driver of a DeviceConfig.",
+		},
+		deviceConfigLabels,
+	)
+
+	NodeMatchToDriverAvailable = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "he_sample_operator_node_match_to_driver_available_seconds",
+			Help:    "Reports the time from a node being selected by a DeviceConfig until its driver is available.",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+		},
+		deviceConfigLabels,
+	)
+
+	UnmappedKernelNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_unmapped_kernel_nodes",
+			Help: "Reports the number of nodes selected by a DeviceConfig running a kernel version which is not matched by any kernel mapping.",
+		},
+		[]string{namespaceLabel, deviceConfigLabel, "kernel_version"},
+	)
+
+	ResourceShortfallNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "he_sample_operator_resource_shortfall_nodes",
+			Help: "Reports the number of nodes running the driver of a DeviceConfig which advertise fewer extended resources than expected.",
+		},
+		[]string{namespaceLabel, deviceConfigLabel, "reason"},
 	)
 )
 
+// deviceConfigVecs are the metric vectors with series per DeviceConfig.
+var deviceConfigVecs = []*prometheus.MetricVec{
+	ReconciliationFailed.MetricVec,
+	ReconcileDuration.MetricVec,
+	SelectedNodes.MetricVec,
+	DesiredNodes.MetricVec,
+	AvailableNodes.MetricVec,
+	ConflictingDeviceConfigs.MetricVec,
+	UpgradeProgress.MetricVec,
+	NodeMatchToDriverAvailable.MetricVec,
+	UnmappedKernelNodes.MetricVec,
+	ResourceShortfallNodes.MetricVec,
+}
+
 func init() {
 	metrics.Registry.MustRegister(
 		ReconciliationFailed,
+		ReconcileDuration,
+		SelectedNodes,
+		DesiredNodes,
+		AvailableNodes,
+		ConflictingDeviceConfigs,
+		UpgradeProgress,
+		NodeMatchToDriverAvailable,
+		UnmappedKernelNodes,
+		ResourceShortfallNodes,
 	)
 }
+
+// DeviceConfigLabels returns the labels identifying the series of the given DeviceConfig.
+func DeviceConfigLabels(namespace, name string) prometheus.Labels {
+	return prometheus.Labels{namespaceLabel: namespace, deviceConfigLabel: name}
+}
+
+// DeleteDeviceConfigSeries deletes all the series of the given DeviceConfig, so that the
+// series of deleted DeviceConfigs are not exported anymore.
+func DeleteDeviceConfigSeries(namespace, name string) {
+	labels := DeviceConfigLabels(namespace, name)
+	for _, vec := range deviceConfigVecs {
+		vec.DeletePartialMatch(labels)
+	}
+}
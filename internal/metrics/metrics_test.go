@@ -0,0 +1,49 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteDeviceConfigSeries", func() {
+	It("should only delete the series of the given DeviceConfig", func() {
+		ReconciliationFailed.WithLabelValues("a-namespace", "a-device-config").Set(1)
+		ReconciliationFailed.WithLabelValues("other-namespace", "a-device-config").Set(1)
+		ReconcileDuration.WithLabelValues("a-namespace", "a-device-config").Observe(1)
+		UnmappedKernelNodes.WithLabelValues("a-namespace", "a-device-config", "5.15.0-48-generic").Set(1)
+		ResourceShortfallNodes.WithLabelValues("a-namespace", "a-device-config", "InsufficientDevices").Set(1)
+
+		DeleteDeviceConfigSeries("a-namespace", "a-device-config")
+
+		Expect(countSeries(ReconciliationFailed)).To(Equal(1))
+		Expect(countSeries(ReconcileDuration)).To(BeZero())
+		Expect(countSeries(UnmappedKernelNodes)).To(BeZero())
+		Expect(countSeries(ResourceShortfallNodes)).To(BeZero())
+
+		DeleteDeviceConfigSeries("other-namespace", "a-device-config")
+
+		Expect(countSeries(ReconciliationFailed)).To(BeZero())
+	})
+})
+
+func countSeries(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+	return len(ch)
+}
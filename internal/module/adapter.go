@@ -0,0 +1,226 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+)
+
+// ModuleGroupKind is the kind of the KMM Modules, which is served in different API versions
+// by the KMM releases.
+var ModuleGroupKind = schema.GroupKind{Group: kmmv1beta1.GroupVersion.Group, Kind: "Module"}
+
+// Adapter renders the Module of a DeviceConfig in one KMM Module API version.
+type Adapter interface {
+	// GroupVersionKind returns the kind of the rendered Modules.
+	GroupVersionKind() schema.GroupVersionKind
+	// SetDesiredSpec sets the spec of the Module of the given node group, leaving its metadata
+	// untouched.
+	SetDesiredSpec(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject, group string) error
+}
+
+// adapters holds the adapters of the supported Module API versions, from the preferred one.
+var adapters = []Adapter{
+	&v1beta2Adapter{},
+	&v1beta1Adapter{},
+}
+
+// SupportedVersions returns the supported Module API versions, from the preferred one.
+func SupportedVersions() []string {
+	versions := make([]string, 0, len(adapters))
+	for _, a := range adapters {
+		versions = append(versions, a.GroupVersionKind().Version)
+	}
+	return versions
+}
+
+// GetAdapter returns the adapter of the preferred supported Module API version served by the
+// cluster. A NoKindMatchError is returned when KMM is not installed or serves none of the
+// supported versions.
+func GetAdapter(mapper meta.RESTMapper) (Adapter, error) {
+	mapping, err := mapper.RESTMapping(ModuleGroupKind, SupportedVersions()...)
+	if err != nil {
+		return nil, err
+	}
+	return getAdapter(mapping.GroupVersionKind.Version)
+}
+
+// GetServedGroupVersionKind returns the kind of the Modules in the preferred supported API
+// version served by the cluster.
+func GetServedGroupVersionKind(mapper meta.RESTMapper) (schema.GroupVersionKind, error) {
+	a, err := GetAdapter(mapper)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	return a.GroupVersionKind(), nil
+}
+
+func getAdapter(version string) (Adapter, error) {
+	for _, a := range adapters {
+		if a.GroupVersionKind().Version == version {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported Module API version %q", version)
+}
+
+// v1beta1Adapter renders the Modules of the vendored kmm.sigs.k8s.io/v1beta1 API, where every
+// kernel mapping sets its own container image.
+type v1beta1Adapter struct{}
+
+func (a *v1beta1Adapter) GroupVersionKind() schema.GroupVersionKind {
+	return kmmv1beta1.GroupVersion.WithKind(ModuleGroupKind.Kind)
+}
+
+func (a *v1beta1Adapter) SetDesiredSpec(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject, group string) error {
+	kernelMappings := []kmmv1beta1.KernelMapping{}
+	for _, km := range makeKernelMappings(cr) {
+		kernelMappings = append(kernelMappings, kmmv1beta1.KernelMapping{
+			ContainerImage: km.containerImage,
+			Regexp:         km.regexp,
+		})
+	}
+
+	loadOrder, err := GetLoadOrder(cr)
+	if err != nil {
+		return err
+	}
+
+	modprobe := kmmv1beta1.ModprobeSpec{
+		ModuleName: cr.GetSpec().ModuleName,
+		Parameters: cr.GetSpec().GetModuleParameters(group),
+	}
+	// The v1beta1 API loads a single kernel module, so several ones are loaded and unloaded
+	// in order by raw modprobe arguments.
+	if len(loadOrder) > 1 {
+		args := []string{"-a", "-d", modulesDirName}
+		modprobe.RawArgs = &kmmv1beta1.ModprobeArgs{
+			Load:   append(append([]string{}, args...), loadOrder...),
+			Unload: append(append([]string{"-r"}, args...), GetUnloadOrder(loadOrder)...),
+		}
+	}
+
+	spec := kmmv1beta1.ModuleSpec{
+		ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+			Container: kmmv1beta1.ModuleLoaderContainerSpec{
+				ImagePullPolicy: config.GetDefaults().ImagePullPolicy,
+				KernelMappings:  kernelMappings,
+				Modprobe:        modprobe,
+			},
+			ServiceAccountName: config.GetDefaults().DriverServiceAccount,
+		},
+		Selector: getModuleSelector(cr, group),
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return fmt.Errorf("could not convert the Module spec: %w", err)
+	}
+	removeNulls(obj)
+	m.Object["spec"] = obj
+	return nil
+}
+
+// removeNulls removes the unset fields of the typed API, which are rendered as null values,
+// so that they are neither applied nor owned by the operator.
+func removeNulls(obj map[string]interface{}) {
+	for k, v := range obj {
+		switch v := v.(type) {
+		case nil:
+			delete(obj, k)
+		case map[string]interface{}:
+			removeNulls(v)
+		case []interface{}:
+			for _, item := range v {
+				if item, ok := item.(map[string]interface{}); ok {
+					removeNulls(item)
+				}
+			}
+		}
+	}
+}
+
+// v1beta2Adapter renders the Modules of the kmm.sigs.k8s.io/v1beta2 API, whose module loader
+// container sets the image shared by the kernel mappings and the directory of the kernel
+// modules in the image.
+type v1beta2Adapter struct{}
+
+func (a *v1beta2Adapter) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: ModuleGroupKind.Group, Version: "v1beta2", Kind: ModuleGroupKind.Kind}
+}
+
+func (a *v1beta2Adapter) SetDesiredSpec(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject, group string) error {
+	kernelMappings := []interface{}{}
+	for _, km := range makeKernelMappings(cr) {
+		kernelMappings = append(kernelMappings, map[string]interface{}{
+			"regexp": km.regexp,
+		})
+	}
+
+	selector := map[string]interface{}{}
+	for k, v := range getModuleSelector(cr, group) {
+		selector[k] = v
+	}
+
+	loadOrder, err := GetLoadOrder(cr)
+	if err != nil {
+		return err
+	}
+
+	modprobe := map[string]interface{}{
+		"moduleName": cr.GetSpec().ModuleName,
+		"dirName":    modulesDirName,
+	}
+	// The modules loading order starts with the last loaded kernel module, which is the one
+	// named by the Module, and KMM unloads them in the same order.
+	if len(loadOrder) > 1 {
+		unloadOrder := GetUnloadOrder(loadOrder)
+		modulesLoadingOrder := make([]interface{}, 0, len(unloadOrder))
+		for _, name := range unloadOrder {
+			modulesLoadingOrder = append(modulesLoadingOrder, name)
+		}
+		modprobe["moduleName"] = unloadOrder[0]
+		modprobe["modulesLoadingOrder"] = modulesLoadingOrder
+	}
+	if params := cr.GetSpec().GetModuleParameters(group); len(params) > 0 {
+		parameters := make([]interface{}, 0, len(params))
+		for _, p := range params {
+			parameters = append(parameters, p)
+		}
+		modprobe["parameters"] = parameters
+	}
+
+	m.Object["spec"] = map[string]interface{}{
+		"moduleLoader": map[string]interface{}{
+			"container": map[string]interface{}{
+				"containerImage":  driverImageTemplate(cr),
+				"imagePullPolicy": string(config.GetDefaults().ImagePullPolicy),
+				"kernelMappings":  kernelMappings,
+				"modprobe":        modprobe,
+			},
+			"serviceAccountName": config.GetDefaults().DriverServiceAccount,
+		},
+		"selector": selector,
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// updateGolden rewrites the golden files with the rendered Modules, e.g. with
+// go test ./internal/module -args -update
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+var _ = Describe("Adapters", func() {
+	dc := &examplecomv1alpha1.DeviceConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-device-config",
+			Namespace: "a-namespace",
+			UID:       "a-uid",
+		},
+		Spec: examplecomv1alpha1.DeviceConfigSpec{
+			DriverImage:   testDriverImage,
+			DriverVersion: testDriverVersion,
+			ModuleName:    testModuleName,
+			NodeSelector:  map[string]string{testLabelKey: testLabelValue},
+		},
+	}
+
+	for _, a := range adapters {
+		a := a
+		version := a.GroupVersionKind().Version
+
+		It("should render the "+version+" Module matching its golden file", func() {
+			m := &unstructured.Unstructured{}
+			m.SetGroupVersionKind(a.GroupVersionKind())
+			m.SetName(GetModuleName(dc))
+			m.SetNamespace(dc.Namespace)
+
+			Expect(examplecomv1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+			Expect(NewReconciler(nil, scheme.Scheme, nil, "").SetDesiredModule(m, dc, "")).To(Succeed())
+
+			rendered, err := yaml.Marshal(m.Object)
+			Expect(err).ToNot(HaveOccurred())
+
+			golden := filepath.Join("testdata", "module-"+version+".yaml")
+			if *updateGolden {
+				Expect(os.WriteFile(golden, rendered, 0644)).To(Succeed())
+			}
+
+			expected, err := os.ReadFile(golden)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(rendered)).To(Equal(string(expected)))
+		})
+
+		It("should render the "+version+" node group Module matching its golden file", func() {
+			grouped := dc.DeepCopy()
+			grouped.Spec.ModuleParameters = []string{"num_queues=8", "debug=0"}
+			grouped.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+				{
+					Name:             "sriov",
+					NodeSelector:     map[string]string{"pool": "sriov"},
+					ModuleParameters: []string{"num_queues=16", "sriov_mode=1"},
+				},
+			}
+
+			m := &unstructured.Unstructured{}
+			m.SetGroupVersionKind(a.GroupVersionKind())
+			m.SetName(GetNodeGroupModuleName(grouped, "sriov"))
+			m.SetNamespace(grouped.Namespace)
+
+			Expect(examplecomv1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+			Expect(NewReconciler(nil, scheme.Scheme, nil, "").SetDesiredModule(m, grouped, "sriov")).To(Succeed())
+
+			rendered, err := yaml.Marshal(m.Object)
+			Expect(err).ToNot(HaveOccurred())
+
+			golden := filepath.Join("testdata", "module-"+version+"-nodegroup.yaml")
+			if *updateGolden {
+				Expect(os.WriteFile(golden, rendered, 0644)).To(Succeed())
+			}
+
+			expected, err := os.ReadFile(golden)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(rendered)).To(Equal(string(expected)))
+		})
+
+		It("should render the "+version+" Module of several kernel modules matching its golden file", func() {
+			multiple := dc.DeepCopy()
+			multiple.Spec.KernelModules = []examplecomv1alpha1.KernelModule{
+				{Name: "sample_rdma", DependsOn: []string{"sample_crypto"}},
+				{Name: "sample_crypto"},
+			}
+
+			m := &unstructured.Unstructured{}
+			m.SetGroupVersionKind(a.GroupVersionKind())
+			m.SetName(GetModuleName(multiple))
+			m.SetNamespace(multiple.Namespace)
+
+			Expect(examplecomv1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+			Expect(NewReconciler(nil, scheme.Scheme, nil, "").SetDesiredModule(m, multiple, "")).To(Succeed())
+
+			rendered, err := yaml.Marshal(m.Object)
+			Expect(err).ToNot(HaveOccurred())
+
+			golden := filepath.Join("testdata", "module-"+version+"-kernelmodules.yaml")
+			if *updateGolden {
+				Expect(os.WriteFile(golden, rendered, 0644)).To(Succeed())
+			}
+
+			expected, err := os.ReadFile(golden)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(rendered)).To(Equal(string(expected)))
+		})
+	}
+
+	It("should prefer the newest served API version", func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(ModuleGroupKind.WithVersion("v1beta1"), meta.RESTScopeNamespace)
+
+		a, err := GetAdapter(mapper)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.GroupVersionKind().Version).To(Equal("v1beta1"))
+
+		mapper.Add(ModuleGroupKind.WithVersion("v1beta2"), meta.RESTScopeNamespace)
+
+		a, err = GetAdapter(mapper)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.GroupVersionKind().Version).To(Equal("v1beta2"))
+	})
+
+	It("should not support an unknown API version", func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(ModuleGroupKind.WithVersion("v1"), meta.RESTScopeNamespace)
+
+		_, err := GetAdapter(mapper)
+		Expect(meta.IsNoMatchError(err)).To(BeTrue())
+	})
+})
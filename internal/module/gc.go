@@ -0,0 +1,170 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+const (
+	deviceConfigKind        = "DeviceConfig"
+	clusterDeviceConfigKind = "ClusterDeviceConfig"
+)
+
+// GarbageCollector periodically deletes the Modules generated by the operator whose owner is
+// gone or no longer names them, e.g. after a DeviceConfig has been renamed or the Module naming
+// convention has changed. The Modules left running by a deleted owner on purpose, according to
+// its deletion policy, are kept.
+type GarbageCollector struct {
+	client client.Client
+	// driverNamespace is the namespace of the ClusterDeviceConfig Modules.
+	driverNamespace string
+	interval        time.Duration
+}
+
+func NewGarbageCollector(c client.Client, driverNamespace string, interval time.Duration) *GarbageCollector {
+	return &GarbageCollector{
+		client:          c,
+		driverNamespace: driverNamespace,
+		interval:        interval,
+	}
+}
+
+// Start collects the stale Modules every interval until the context is done.
+func (gc *GarbageCollector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("module-gc")
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := gc.Collect(ctx); err != nil {
+			logger.Error(err, "Failed to garbage collect Modules")
+		}
+	}, gc.interval)
+
+	return nil
+}
+
+// NeedLeaderElection returns true, so that only the leader deletes Modules.
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect deletes the stale Modules. Nothing is collected while KMM is not installed, and the
+// Modules created during the last interval are skipped, so that the cache has caught up with
+// their owner.
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("module-gc")
+
+	gvk, err := GetServedGroupVersionKind(gc.client.RESTMapper())
+	if meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	modules := &unstructured.UnstructuredList{}
+	modules.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := gc.client.List(ctx, modules, client.MatchingLabels{ManagedLabel: ManagedLabelValue}); err != nil {
+		return fmt.Errorf("failed to list Modules: %w", err)
+	}
+
+	for i := range modules.Items {
+		m := &modules.Items[i]
+		if !m.GetDeletionTimestamp().IsZero() || time.Since(m.GetCreationTimestamp().Time) < gc.interval {
+			continue
+		}
+		if m.GetAnnotations()[examplecomv1alpha1.DeletionPolicyAnnotation] != "" {
+			continue
+		}
+
+		stale, err := gc.isStale(ctx, m)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			continue
+		}
+
+		uid := m.GetUID()
+		err = gc.client.Delete(ctx, m, client.Preconditions{UID: &uid})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Module %s: %w", m.GetName(), err)
+		}
+		logger.Info("Deleted stale Module", "namespace", m.GetNamespace(), "name", m.GetName())
+	}
+
+	return nil
+}
+
+// isStale returns whether the owner of the given Module is gone or no longer names it. The
+// Modules whose owner is unknown are never stale.
+func (gc *GarbageCollector) isStale(ctx context.Context, m *unstructured.Unstructured) (bool, error) {
+	name := m.GetAnnotations()[OwnerNameAnnotation]
+	if name == "" {
+		return false, nil
+	}
+
+	var owner examplecomv1alpha1.DeviceConfigObject
+	switch m.GetAnnotations()[OwnerKindAnnotation] {
+	case deviceConfigKind:
+		owner = &examplecomv1alpha1.DeviceConfig{}
+		err := gc.client.Get(ctx, types.NamespacedName{Namespace: m.GetNamespace(), Name: name}, owner)
+		if err != nil {
+			return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+		}
+	case clusterDeviceConfigKind:
+		owner = &examplecomv1alpha1.ClusterDeviceConfig{}
+		err := gc.client.Get(ctx, types.NamespacedName{Name: name}, owner)
+		if err != nil {
+			return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+		}
+	default:
+		return false, nil
+	}
+
+	namespace, err := GetModuleNamespace(owner, gc.driverNamespace)
+	if err != nil {
+		return false, nil
+	}
+	if namespace != m.GetNamespace() {
+		return true, nil
+	}
+	for _, name := range GetModuleNames(owner) {
+		if name == m.GetName() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getOwnerKind returns the kind of the given resource, recorded in the owner annotation of its
+// Module.
+func getOwnerKind(cr examplecomv1alpha1.DeviceConfigObject) string {
+	if _, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		return clusterDeviceConfigKind
+	}
+	return deviceConfigKind
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+var _ = Describe("GarbageCollector", func() {
+	const interval = time.Minute
+
+	var (
+		ctx    context.Context
+		s      *runtime.Scheme
+		mapper meta.RESTMapper
+	)
+
+	makeModule := func(name, namespace string, annotations map[string]string) *unstructured.Unstructured {
+		m := &unstructured.Unstructured{}
+		m.SetGroupVersionKind(kmmv1beta1.GroupVersion.WithKind("Module"))
+		m.SetName(name)
+		m.SetNamespace(namespace)
+		m.SetLabels(map[string]string{ManagedLabel: ManagedLabelValue})
+		m.SetAnnotations(annotations)
+		m.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-2 * interval)))
+		return m
+	}
+
+	ownedBy := func(kind, name string) map[string]string {
+		return map[string]string{OwnerKindAnnotation: kind, OwnerNameAnnotation: name}
+	}
+
+	listModules := func(c client.Client) []string {
+		modules := &unstructured.UnstructuredList{}
+		modules.SetGroupVersionKind(kmmv1beta1.GroupVersion.WithKind("ModuleList"))
+		Expect(c.List(ctx, modules)).To(Succeed())
+
+		names := []string{}
+		for _, m := range modules.Items {
+			names = append(names, m.GetName())
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+		Expect(kmmv1beta1.AddToScheme(s)).To(Succeed())
+
+		m := meta.NewDefaultRESTMapper(nil)
+		m.Add(ModuleGroupKind.WithVersion("v1beta1"), meta.RESTScopeNamespace)
+		mapper = m
+	})
+
+	It("should delete the Modules whose owner is gone or no longer names them", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"}}
+		cdc := &examplecomv1alpha1.ClusterDeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"}}
+
+		unmanaged := makeModule("an-unmanaged-module", "a-namespace", nil)
+		unmanaged.SetLabels(nil)
+		recent := makeModule("a-recent-module", "a-namespace", ownedBy("DeviceConfig", "a-deleted-device-config"))
+		recent.SetCreationTimestamp(metav1.Now())
+
+		c := fake.NewClientBuilder().
+			WithScheme(s).
+			WithRESTMapper(mapper).
+			WithObjects(
+				dc,
+				cdc,
+				makeModule(GetModuleName(dc), "a-namespace", ownedBy("DeviceConfig", dc.Name)),
+				makeModule(GetModuleName(cdc), testDriverNamespace, ownedBy("ClusterDeviceConfig", cdc.Name)),
+				makeModule("a-renamed-module", "a-namespace", ownedBy("DeviceConfig", dc.Name)),
+				makeModule("an-orphaned-module", "a-namespace", ownedBy("DeviceConfig", "a-deleted-device-config")),
+				makeModule("a-cluster-module", testDriverNamespace, ownedBy("ClusterDeviceConfig", "a-deleted-device-config")),
+				makeModule("a-released-module", "a-namespace", map[string]string{
+					OwnerKindAnnotation:                         "DeviceConfig",
+					OwnerNameAnnotation:                         "a-deleted-device-config",
+					examplecomv1alpha1.DeletionPolicyAnnotation: examplecomv1alpha1.DeletionPolicyOrphan,
+				}),
+				unmanaged,
+				recent,
+			).
+			Build()
+
+		Expect(NewGarbageCollector(c, testDriverNamespace, interval).Collect(ctx)).To(Succeed())
+
+		Expect(listModules(c)).To(ConsistOf(
+			GetModuleName(dc),
+			GetModuleName(cdc),
+			"a-released-module",
+			"an-unmanaged-module",
+			"a-recent-module",
+		))
+	})
+
+	It("should delete the Modules of the removed node groups", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				NodeGroups: []examplecomv1alpha1.NodeGroup{{Name: "sriov", NodeSelector: map[string]string{"pool": "sriov"}}},
+			},
+		}
+
+		c := fake.NewClientBuilder().
+			WithScheme(s).
+			WithRESTMapper(mapper).
+			WithObjects(
+				dc,
+				makeModule(GetModuleName(dc), "a-namespace", ownedBy("DeviceConfig", dc.Name)),
+				makeModule(GetNodeGroupModuleName(dc, "sriov"), "a-namespace", ownedBy("DeviceConfig", dc.Name)),
+				makeModule(GetNodeGroupModuleName(dc, "removed"), "a-namespace", ownedBy("DeviceConfig", dc.Name)),
+			).
+			Build()
+
+		Expect(NewGarbageCollector(c, testDriverNamespace, interval).Collect(ctx)).To(Succeed())
+
+		Expect(listModules(c)).To(ConsistOf(
+			GetModuleName(dc),
+			GetNodeGroupModuleName(dc, "sriov"),
+		))
+	})
+
+	It("should not collect anything while KMM is not installed", func() {
+		c := fake.NewClientBuilder().WithScheme(s).WithRESTMapper(meta.NewDefaultRESTMapper(nil)).Build()
+
+		Expect(NewGarbageCollector(c, testDriverNamespace, interval).Collect(ctx)).To(Succeed())
+	})
+})
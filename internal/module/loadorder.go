@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// ErrParametersWithKernelModules is returned when the kernel modules of a resource are
+// loaded with parameters, which modprobe does not support when loading several modules.
+var ErrParametersWithKernelModules = errors.New("module parameters cannot be combined with kernel modules")
+
+// GetLoadOrder returns the kernel modules of the given resource in the order they are loaded,
+// i.e. every kernel module after the ones it depends on, starting with the ModuleName one. The
+// kernel modules are unloaded in the reverse order. An error is returned if a dependency is
+// unknown or the dependencies form a cycle.
+func GetLoadOrder(cr examplecomv1alpha1.DeviceConfigObject) ([]string, error) {
+	spec := cr.GetSpec()
+	if len(spec.KernelModules) == 0 {
+		return []string{spec.ModuleName}, nil
+	}
+	if spec.ModuleName == "" {
+		return nil, errors.New("kernel modules require a module name")
+	}
+
+	dependencies := map[string][]string{spec.ModuleName: nil}
+	names := []string{spec.ModuleName}
+	for _, km := range spec.KernelModules {
+		if _, ok := dependencies[km.Name]; ok {
+			return nil, fmt.Errorf("duplicate kernel module %q", km.Name)
+		}
+		dependsOn := km.DependsOn
+		if len(dependsOn) == 0 {
+			dependsOn = []string{spec.ModuleName}
+		}
+		dependencies[km.Name] = dependsOn
+		names = append(names, km.Name)
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			// The path starts with the first kernel module of the cycle.
+			for i, n := range path {
+				if n == name {
+					path = append(path[i:], name)
+					break
+				}
+			}
+			return fmt.Errorf("dependency cycle between kernel modules: %s", strings.Join(path, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependencies[name] {
+			if _, ok := dependencies[dep]; !ok {
+				return fmt.Errorf("kernel module %q depends on unknown kernel module %q", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// GetUnloadOrder returns the given load order reversed, i.e. every kernel module before the
+// ones it depends on.
+func GetUnloadOrder(loadOrder []string) []string {
+	unloadOrder := make([]string, 0, len(loadOrder))
+	for i := len(loadOrder) - 1; i >= 0; i-- {
+		unloadOrder = append(unloadOrder, loadOrder[i])
+	}
+	return unloadOrder
+}
+
+// ValidateKernelModules returns an error if the kernel modules of the given resource cannot
+// be loaded, i.e. if their dependencies are invalid or they are loaded with parameters.
+func ValidateKernelModules(cr examplecomv1alpha1.DeviceConfigObject) error {
+	if _, err := GetLoadOrder(cr); err != nil {
+		return err
+	}
+
+	spec := cr.GetSpec()
+	if len(spec.KernelModules) == 0 {
+		return nil
+	}
+	if len(spec.ModuleParameters) > 0 {
+		return ErrParametersWithKernelModules
+	}
+	for _, g := range spec.NodeGroups {
+		if len(g.ModuleParameters) > 0 {
+			return fmt.Errorf("node group %s: %w", g.Name, ErrParametersWithKernelModules)
+		}
+	}
+	return nil
+}
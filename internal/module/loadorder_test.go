@@ -0,0 +1,106 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+var _ = Describe("GetLoadOrder", func() {
+	var dc *examplecomv1alpha1.DeviceConfig
+
+	BeforeEach(func() {
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+			Spec:       examplecomv1alpha1.DeviceConfigSpec{ModuleName: "core"},
+		}
+	})
+
+	It("should only load the module name without kernel modules", func() {
+		Expect(GetLoadOrder(dc)).To(Equal([]string{"core"}))
+	})
+
+	It("should load every kernel module after its dependencies", func() {
+		dc.Spec.KernelModules = []examplecomv1alpha1.KernelModule{
+			{Name: "rdma", DependsOn: []string{"core", "crypto"}},
+			{Name: "crypto"},
+			{Name: "tools", DependsOn: []string{"rdma"}},
+			{Name: "debug"},
+		}
+
+		order, err := GetLoadOrder(dc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(order).To(Equal([]string{"core", "crypto", "rdma", "tools", "debug"}))
+		Expect(GetUnloadOrder(order)).To(Equal([]string{"debug", "tools", "rdma", "crypto", "core"}))
+	})
+
+	DescribeTable("should reject invalid dependencies",
+		func(kernelModules []examplecomv1alpha1.KernelModule, message string) {
+			dc.Spec.KernelModules = kernelModules
+
+			_, err := GetLoadOrder(dc)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown dependency",
+			[]examplecomv1alpha1.KernelModule{{Name: "rdma", DependsOn: []string{"crypto"}}},
+			`kernel module "rdma" depends on unknown kernel module "crypto"`),
+		Entry("duplicate kernel module",
+			[]examplecomv1alpha1.KernelModule{{Name: "core"}},
+			`duplicate kernel module "core"`),
+		Entry("self dependency",
+			[]examplecomv1alpha1.KernelModule{{Name: "rdma", DependsOn: []string{"rdma"}}},
+			"dependency cycle between kernel modules: rdma -> rdma"),
+		Entry("dependency cycle",
+			[]examplecomv1alpha1.KernelModule{
+				{Name: "rdma", DependsOn: []string{"crypto"}},
+				{Name: "crypto", DependsOn: []string{"tools"}},
+				{Name: "tools", DependsOn: []string{"core", "rdma"}},
+			},
+			"dependency cycle between kernel modules: rdma -> crypto -> tools -> rdma"),
+	)
+
+	It("should require a module name", func() {
+		dc.Spec.ModuleName = ""
+		dc.Spec.KernelModules = []examplecomv1alpha1.KernelModule{{Name: "rdma"}}
+
+		_, err := GetLoadOrder(dc)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ValidateKernelModules", func() {
+	It("should reject module parameters with kernel modules", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				ModuleName:    "core",
+				KernelModules: []examplecomv1alpha1.KernelModule{{Name: "rdma"}},
+				NodeGroups: []examplecomv1alpha1.NodeGroup{
+					{Name: "sriov", NodeSelector: map[string]string{"pool": "sriov"}, ModuleParameters: []string{"sriov_mode=1"}},
+				},
+			},
+		}
+		Expect(ValidateKernelModules(dc)).To(MatchError(ErrParametersWithKernelModules))
+
+		dc.Spec.NodeGroups = nil
+		Expect(ValidateKernelModules(dc)).To(Succeed())
+
+		dc.Spec.ModuleParameters = []string{"num_queues=8"}
+		Expect(ValidateKernelModules(dc)).To(MatchError(ErrParametersWithKernelModules))
+	})
+})
@@ -9,8 +9,8 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	v1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // MockReconciler is a mock of Reconciler interface.
@@ -37,7 +37,7 @@ func (m *MockReconciler) EXPECT() *MockReconcilerMockRecorder {
 }
 
 // DeleteModule mocks base method.
-func (m *MockReconciler) DeleteModule(ctx context.Context, dc *v1alpha1.DeviceConfig) error {
+func (m *MockReconciler) DeleteModule(ctx context.Context, dc v1alpha1.DeviceConfigObject) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteModule", ctx, dc)
 	ret0, _ := ret[0].(error)
@@ -51,7 +51,7 @@ func (mr *MockReconcilerMockRecorder) DeleteModule(ctx, dc interface{}) *gomock.
 }
 
 // ReconcileModule mocks base method.
-func (m *MockReconciler) ReconcileModule(ctx context.Context, dc *v1alpha1.DeviceConfig) error {
+func (m *MockReconciler) ReconcileModule(ctx context.Context, dc v1alpha1.DeviceConfigObject) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ReconcileModule", ctx, dc)
 	ret0, _ := ret[0].(error)
@@ -64,16 +64,30 @@ func (mr *MockReconcilerMockRecorder) ReconcileModule(ctx, dc interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileModule", reflect.TypeOf((*MockReconciler)(nil).ReconcileModule), ctx, dc)
 }
 
+// ReleaseModule mocks base method.
+func (m *MockReconciler) ReleaseModule(ctx context.Context, dc v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseModule", ctx, dc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseModule indicates an expected call of ReleaseModule.
+func (mr *MockReconcilerMockRecorder) ReleaseModule(ctx, dc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseModule", reflect.TypeOf((*MockReconciler)(nil).ReleaseModule), ctx, dc)
+}
+
 // SetDesiredModule mocks base method.
-func (m_2 *MockReconciler) SetDesiredModule(m *v1beta1.Module, cr *v1alpha1.DeviceConfig) error {
+func (m_2 *MockReconciler) SetDesiredModule(m *unstructured.Unstructured, cr v1alpha1.DeviceConfigObject, group string) error {
 	m_2.ctrl.T.Helper()
-	ret := m_2.ctrl.Call(m_2, "SetDesiredModule", m, cr)
+	ret := m_2.ctrl.Call(m_2, "SetDesiredModule", m, cr, group)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetDesiredModule indicates an expected call of SetDesiredModule.
-func (mr *MockReconcilerMockRecorder) SetDesiredModule(m, cr interface{}) *gomock.Call {
+func (mr *MockReconcilerMockRecorder) SetDesiredModule(m, cr, group interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDesiredModule", reflect.TypeOf((*MockReconciler)(nil).SetDesiredModule), m, cr)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDesiredModule", reflect.TypeOf((*MockReconciler)(nil).SetDesiredModule), m, cr, group)
 }
@@ -17,115 +17,414 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 const (
-	moduleSuffix = "module"
+	moduleSuffix        = "module"
+	clusterModuleSuffix = "cluster-module"
 
-	driverServiceAccount = "driver-sample"
+	// kernelVersionVariable is substituted by KMM in the driver image of the kernel mappings.
+	kernelVersionVariable = "${KERNEL_FULL_VERSION}"
+	// modulesDirName is the directory of the kernel modules in the driver image.
+	modulesDirName = "/opt"
+
+	driftEventReason   = "ModuleDrift"
+	adoptedEventReason = "AdoptedModule"
+)
+
+// FieldManager is the field manager of the Modules applied by the operator.
+const FieldManager = "he-sample-operator"
+
+// Metadata set on every Module generated by the operator, so that the Modules whose owner is
+// gone are garbage collected even when their name no longer matches the naming convention.
+const (
+	ManagedLabel      = "example.com/operator-managed"
+	ManagedLabelValue = "true"
+
+	// OwnerKindAnnotation and OwnerNameAnnotation reference the DeviceConfig or
+	// ClusterDeviceConfig of a Module. The owner of a Module is in its namespace for a
+	// DeviceConfig, and its full name is kept since it may not be a valid label value.
+	OwnerKindAnnotation = "example.com/owner-kind"
+	OwnerNameAnnotation = "example.com/owner-name"
 )
 
+// Labels set by KMM on the ModuleLoader DaemonSets and pods of a Module.
+const (
+	ModuleNameLabel    = "kmm.node.kubernetes.io/module.name"
+	KernelVersionLabel = "kmm.node.kubernetes.io/kernel-version.full"
+	RoleLabel          = "kmm.node.kubernetes.io/role"
+
+	ModuleLoaderRole = "module-loader"
+)
+
+// GetModuleReadyLabel returns the label set by KMM on the nodes the kernel module of the
+// given Module is loaded on.
+func GetModuleReadyLabel(namespace, name string) string {
+	return fmt.Sprintf("kmm.node.kubernetes.io/%s.%s.ready", namespace, name)
+}
+
 //go:generate mockgen -source=module.go -package=module -destination=mock_module.go
 
 type Reconciler interface {
-	ReconcileModule(ctx context.Context, dc *examplecomv1alpha1.DeviceConfig) error
-	SetDesiredModule(m *kmmv1beta1.Module, cr *examplecomv1alpha1.DeviceConfig) error
-	DeleteModule(ctx context.Context, dc *examplecomv1alpha1.DeviceConfig) error
+	ReconcileModule(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) error
+	SetDesiredModule(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject, group string) error
+	DeleteModule(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) error
+	ReleaseModule(ctx context.Context, dc examplecomv1alpha1.DeviceConfigObject) error
 }
 
+// ErrModuleNotManaged is returned when reconciling a resource whose Module already exists
+// without having been generated by the operator.
+var ErrModuleNotManaged = fmt.Errorf("the Module is not managed by the operator, set the %s annotation of the DeviceConfig to its name to adopt it",
+	examplecomv1alpha1.AdoptModuleAnnotation)
+
+// ErrNoDriverNamespace is returned when reconciling the Module of a ClusterDeviceConfig while
+// no driver namespace has been configured.
+var ErrNoDriverNamespace = errors.New("no driver namespace configured for ClusterDeviceConfig Modules")
+
 type moduleReconciler struct {
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	// driverNamespace is the namespace of the ClusterDeviceConfig Modules.
+	driverNamespace string
 }
 
-func NewReconciler(c client.Client, s *runtime.Scheme) *moduleReconciler {
+func NewReconciler(c client.Client, s *runtime.Scheme, recorder record.EventRecorder, driverNamespace string) *moduleReconciler {
 	return &moduleReconciler{
-		client: c,
-		scheme: s,
+		client:          c,
+		scheme:          s,
+		recorder:        recorder,
+		driverNamespace: driverNamespace,
+	}
+}
+
+// GetModuleName returns the name of the KMM Module of the given resource, i.e. the Module named
+// by its adoption annotation or a name derived from its own. ClusterDeviceConfig Modules have
+// their own suffix, so that they never collide with the Module of a DeviceConfig of the same
+// name in the driver namespace. KMM labels the ModuleLoader pods with the Module name, so
+// derived names are truncated to a valid label value.
+func GetModuleName(cr examplecomv1alpha1.DeviceConfigObject) string {
+	if name := cr.GetAnnotations()[examplecomv1alpha1.AdoptModuleAnnotation]; name != "" {
+		return name
+	}
+	if cr.GetNamespace() == "" {
+		return examplecomv1alpha1.TruncateLabelName(fmt.Sprintf("%s-%s", cr.GetName(), clusterModuleSuffix))
+	}
+	return examplecomv1alpha1.TruncateLabelName(fmt.Sprintf("%s-%s", cr.GetName(), moduleSuffix))
+}
+
+// GetNodeGroupModuleName returns the name of the KMM Module of the given node group of the
+// resource, or of the resource itself for an empty group.
+func GetNodeGroupModuleName(cr examplecomv1alpha1.DeviceConfigObject, group string) string {
+	if group == "" {
+		return GetModuleName(cr)
 	}
+	return examplecomv1alpha1.TruncateLabelName(fmt.Sprintf("%s-%s", GetModuleName(cr), group))
 }
 
-func GetModuleName(cr *examplecomv1alpha1.DeviceConfig) string {
-	return fmt.Sprintf("%s-%s", cr.Name, moduleSuffix)
+// GetModuleNames returns the names of all the KMM Modules of the given resource, i.e. the
+// Module of the nodes which belong to no node group followed by the Module of every group.
+func GetModuleNames(cr examplecomv1alpha1.DeviceConfigObject) []string {
+	names := []string{GetModuleName(cr)}
+	for _, g := range cr.GetSpec().NodeGroups {
+		names = append(names, GetNodeGroupModuleName(cr, g.Name))
+	}
+	return names
 }
 
-func (r *moduleReconciler) ReconcileModule(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error {
-	logger := log.FromContext(ctx)
+// GetModuleLoaderSelector returns the selector of the ModuleLoader DaemonSets and pods of all
+// the Modules of the given resource.
+func GetModuleLoaderSelector(cr examplecomv1alpha1.DeviceConfigObject) (client.MatchingLabelsSelector, error) {
+	names, err := labels.NewRequirement(ModuleNameLabel, selection.In, GetModuleNames(cr))
+	if err != nil {
+		return client.MatchingLabelsSelector{}, fmt.Errorf("invalid Module names: %w", err)
+	}
+	role, err := labels.NewRequirement(RoleLabel, selection.Equals, []string{ModuleLoaderRole})
+	if err != nil {
+		return client.MatchingLabelsSelector{}, err
+	}
+	return client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*names, *role)}, nil
+}
+
+// getNodeGroups returns the node groups of the given resource the Modules are rendered for,
+// i.e. an empty group for the nodes which belong to no group followed by every group.
+func getNodeGroups(cr examplecomv1alpha1.DeviceConfigObject) []string {
+	groups := []string{""}
+	for _, g := range cr.GetSpec().NodeGroups {
+		groups = append(groups, g.Name)
+	}
+	return groups
+}
+
+// GetModuleNamespace returns the namespace of the KMM Module of the given resource, i.e. its
+// own namespace or the driver namespace for a ClusterDeviceConfig.
+func GetModuleNamespace(cr examplecomv1alpha1.DeviceConfigObject, driverNamespace string) (string, error) {
+	if ns := cr.GetNamespace(); ns != "" {
+		return ns, nil
+	}
+	if driverNamespace == "" {
+		return "", ErrNoDriverNamespace
+	}
+	return driverNamespace, nil
+}
+
+// ReconcileModule server-side applies the Modules of the given resource, one for the nodes
+// which belong to no node group and one per group, so that the operator only owns the fields
+// it renders. The fields it owns which have been changed by other field managers are recorded
+// as drift in the resource status and reverted. A Module orphaned by a deleted resource of the
+// same name is adopted. The Modules of the removed node groups are garbage collected.
+func (r *moduleReconciler) ReconcileModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "module.ReconcileModule", cr)
+	defer tracing.End(span, &err)
 
-	existingModule := &kmmv1beta1.Module{}
-	err := r.client.Get(ctx, types.NamespacedName{
-		Namespace: cr.Namespace,
-		Name:      GetModuleName(cr),
-	}, existingModule)
-	exists := !apierrors.IsNotFound(err)
-	if err != nil && !apierrors.IsNotFound(err) {
+	if err := validateNodeGroups(cr); err != nil {
+		return err
+	}
+	if err := ValidateKernelModules(cr); err != nil {
 		return err
 	}
 
-	m := &kmmv1beta1.Module{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      GetModuleName(cr),
-			Namespace: cr.ObjectMeta.Namespace,
-		},
+	for _, group := range getNodeGroups(cr) {
+		if err := r.applyModule(ctx, cr, group); err != nil {
+			return err
+		}
 	}
 
-	if exists {
-		m = existingModule
+	return nil
+}
+
+// applyModule server-side applies the Module of the given node group of the resource.
+func (r *moduleReconciler) applyModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject, group string) error {
+	logger := log.FromContext(ctx)
+
+	m, err := r.newModule(cr, GetNodeGroupModuleName(cr, group))
+	if err != nil {
+		return err
+	}
+	if errs := validation.IsDNS1123Subdomain(m.GetName()); len(errs) > 0 {
+		return fmt.Errorf("invalid Module name %q: %s", m.GetName(), strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(m.GetName()); len(errs) > 0 {
+		return fmt.Errorf("invalid Module name %q: %s", m.GetName(), strings.Join(errs, ", "))
+	}
+	if err := r.adoptModule(ctx, m.DeepCopy(), cr); err != nil {
+		return err
+	}
+	if err := r.SetDesiredModule(m, cr, group); err != nil {
+		return err
 	}
 
-	res, err := controllerutil.CreateOrPatch(ctx, r.client, m, func() error {
-		return r.SetDesiredModule(m, cr)
-	})
+	err = r.client.Patch(ctx, m, client.Apply, client.FieldOwner(FieldManager))
+	if apierrors.IsConflict(err) {
+		drift := getFieldDrift(err)
+		if len(drift) == 0 {
+			return fmt.Errorf("could not apply Module: %w", err)
+		}
+
+		cr.GetStatus().ModuleDrift = &examplecomv1alpha1.ModuleDrift{
+			Fields:     drift,
+			DetectedAt: metav1.Now(),
+		}
+		r.recorder.Event(cr, corev1.EventTypeWarning, driftEventReason,
+			fmt.Sprintf("Reverting the changes of other field managers to Module %s: %s", m.GetName(), formatFieldDrift(drift)))
 
+		err = r.client.Patch(ctx, m, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
+	}
 	if err != nil {
-		return fmt.Errorf("could not create or patch Module: %v", err)
+		return fmt.Errorf("could not apply Module: %w", err)
 	}
 
-	logger.Info("Reconciled Module", "resource", m.Name, "result", res)
+	logger.Info("Applied Module", "resource", m.GetName(), "version", m.GroupVersionKind().Version)
 
 	return nil
 }
 
-func (r *moduleReconciler) DeleteModule(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error {
-	m := &kmmv1beta1.Module{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      GetModuleName(cr),
-			Namespace: cr.ObjectMeta.Namespace,
-		},
+// validateNodeGroups returns an error if a node group of the given resource uses the name
+// reserved for the nodes which belong to no group.
+func validateNodeGroups(cr examplecomv1alpha1.DeviceConfigObject) error {
+	for _, g := range cr.GetSpec().NodeGroups {
+		if g.Name == examplecomv1alpha1.DefaultNodeGroup {
+			return fmt.Errorf("invalid node group name %q: the name is reserved for the nodes which belong to no node group", g.Name)
+		}
 	}
+	return nil
+}
 
-	err := r.client.Delete(ctx, m)
-	if err != nil && !apierrors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete Module %s: %w", m.Name, err)
+// DeleteModule deletes the Modules of the given resource.
+func (r *moduleReconciler) DeleteModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "module.DeleteModule", cr)
+	defer tracing.End(span, &err)
+
+	for _, name := range GetModuleNames(cr) {
+		m, err := r.newModule(cr, name)
+		if err != nil {
+			return err
+		}
+
+		err = r.client.Delete(ctx, m)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Module %s: %w", m.GetName(), err)
+		}
 	}
 
 	return nil
 }
 
-func (r *moduleReconciler) SetDesiredModule(m *kmmv1beta1.Module, cr *examplecomv1alpha1.DeviceConfig) error {
+// ReleaseModule leaves the Modules of the given deleted resource running, detached from it
+// and annotated with its deletion policy.
+func (r *moduleReconciler) ReleaseModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "module.ReleaseModule", cr)
+	defer tracing.End(span, &err)
+
+	for _, name := range GetModuleNames(cr) {
+		m, err := r.newModule(cr, name)
+		if err != nil {
+			return err
+		}
+
+		if err := r.client.Get(ctx, client.ObjectKeyFromObject(m), m); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get Module %s: %w", m.GetName(), err)
+		}
+
+		patch := client.MergeFrom(m.DeepCopy())
+		Release(m, cr)
+		if err := r.client.Patch(ctx, m, patch); err != nil {
+			return fmt.Errorf("failed to release Module %s: %w", m.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// adoptModule takes over the existing Module of the given resource, if any. A Module which has
+// not been generated by the operator is only taken over when the resource names it in its
+// adoption annotation, and a Module orphaned by a deleted resource has its deletion policy
+// annotation removed.
+func (r *moduleReconciler) adoptModule(ctx context.Context, m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject) error {
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(m), m); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get Module %s: %w", m.GetName(), err)
+	}
+
+	if !isManaged(m, cr) {
+		if cr.GetAnnotations()[examplecomv1alpha1.AdoptModuleAnnotation] != m.GetName() {
+			return fmt.Errorf("could not adopt Module %s: %w", m.GetName(), ErrModuleNotManaged)
+		}
+		// The Module is labelled and owned once applied.
+		r.recorder.Event(cr, corev1.EventTypeNormal, adoptedEventReason, fmt.Sprintf("Adopted existing Module %s", m.GetName()))
+		return nil
+	}
+
+	patch := client.MergeFrom(m.DeepCopy())
+	adopted, err := Adopt(m)
+	if err != nil {
+		return fmt.Errorf("could not adopt Module %s: %w", m.GetName(), err)
+	}
+	if !adopted {
+		return nil
+	}
+
+	if err := r.client.Patch(ctx, m, patch); err != nil {
+		return fmt.Errorf("could not adopt Module %s: %w", m.GetName(), err)
+	}
+	r.recorder.Event(cr, corev1.EventTypeNormal, adoptedEventReason, fmt.Sprintf("Adopted orphaned Module %s", m.GetName()))
+
+	return nil
+}
+
+// isManaged returns whether the given Module has been generated by the operator for the given
+// resource. The Modules generated before they were labelled are recognized by their ownership.
+func isManaged(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject) bool {
+	if m.GetLabels()[ManagedLabel] == ManagedLabelValue || m.GetAnnotations()[examplecomv1alpha1.DeletionPolicyAnnotation] != "" {
+		return true
+	}
+	if cdc, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		return m.GetLabels()[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] == cdc.GetOwnerLabelValue()
+	}
+	return metav1.IsControlledBy(m, cr)
+}
+
+// newModule returns an empty Module of the given resource with the given name, in the
+// preferred supported API version served by the cluster.
+func (r *moduleReconciler) newModule(cr examplecomv1alpha1.DeviceConfigObject, name string) (*unstructured.Unstructured, error) {
+	namespace, err := GetModuleNamespace(cr, r.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := GetAdapter(r.client.RESTMapper())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &unstructured.Unstructured{}
+	m.SetGroupVersionKind(a.GroupVersionKind())
+	m.SetName(name)
+	m.SetNamespace(namespace)
+	return m, nil
+}
+
+// SetDesiredModule renders the spec of the Module of the given node group with the adapter of
+// its API version and sets its ownership. An empty group renders the Module of the nodes which
+// belong to no group.
+func (r *moduleReconciler) SetDesiredModule(m *unstructured.Unstructured, cr examplecomv1alpha1.DeviceConfigObject, group string) error {
 	if m == nil {
 		return errors.New("module cannot be nil")
 	}
 
-	ModuleLoader := r.makeModuleLoader(cr)
-	selector := cr.GetNodeSelector()
+	a, err := getAdapter(m.GroupVersionKind().Version)
+	if err != nil {
+		return err
+	}
+	if err := a.SetDesiredSpec(m, cr, group); err != nil {
+		return err
+	}
 
-	m.Spec = kmmv1beta1.ModuleSpec{
-		ModuleLoader: ModuleLoader,
-		Selector:     selector,
+	labels := m.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ManagedLabel] = ManagedLabelValue
+	m.SetLabels(labels)
+
+	annotations := m.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnerKindAnnotation] = getOwnerKind(cr)
+	annotations[OwnerNameAnnotation] = cr.GetName()
+	m.SetAnnotations(annotations)
+
+	// The Module of a ClusterDeviceConfig lives in the driver namespace, so it is tracked by
+	// label instead of an owner reference.
+	if cdc, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		labels[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] = cdc.GetOwnerLabelValue()
+		m.SetLabels(labels)
+		return nil
 	}
 
 	if err := ctrl.SetControllerReference(cr, m, r.scheme); err != nil {
@@ -135,25 +434,92 @@ func (r *moduleReconciler) SetDesiredModule(m *kmmv1beta1.Module, cr *examplecom
 	return nil
 }
 
-func (r *moduleReconciler) makeModuleLoader(cr *examplecomv1alpha1.DeviceConfig) kmmv1beta1.ModuleLoaderSpec {
-	moduleLoader := kmmv1beta1.ModuleLoaderSpec{
-		Container: kmmv1beta1.ModuleLoaderContainerSpec{
-			ImagePullPolicy: corev1.PullAlways,
-			KernelMappings:  r.makeKernelMappings(cr),
-		},
-		ServiceAccountName: driverServiceAccount,
+// getModuleSelector returns the node selector of the Module of the given node group. Once the
+// resource has node groups, every Module only selects the nodes labelled with its group, so
+// that the Modules select disjoint sets of nodes.
+func getModuleSelector(cr examplecomv1alpha1.DeviceConfigObject, group string) map[string]string {
+	selector := cr.GetModuleNodeSelector()
+	if len(cr.GetSpec().NodeGroups) == 0 {
+		return selector
 	}
+	if group == "" {
+		group = examplecomv1alpha1.DefaultNodeGroup
+	}
+	selector[cr.GetNodeGroupLabel()] = group
+	return selector
+}
 
-	return moduleLoader
+// getFieldDrift returns the fields of a server-side apply conflict, i.e. the fields owned by
+// the operator which have been changed by other field managers.
+func getFieldDrift(err error) []examplecomv1alpha1.FieldDrift {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+
+	drift := []examplecomv1alpha1.FieldDrift{}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		// The message of a conflict is formatted as: conflict with "<manager>" using <version>
+		manager := cause.Message
+		if _, after, found := strings.Cut(cause.Message, `"`); found {
+			manager, _, _ = strings.Cut(after, `"`)
+		}
+		drift = append(drift, examplecomv1alpha1.FieldDrift{Field: cause.Field, Manager: manager})
+	}
+	return drift
+}
+
+func formatFieldDrift(drift []examplecomv1alpha1.FieldDrift) string {
+	fields := make([]string, 0, len(drift))
+	for _, d := range drift {
+		fields = append(fields, fmt.Sprintf("%s (%s)", d.Field, d.Manager))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// kernelMapping is a kernel mapping of a DeviceConfig, rendered by the adapter of each Module
+// API version.
+type kernelMapping struct {
+	regexp         string
+	containerImage string
 }
 
-func (r *moduleReconciler) makeKernelMappings(cr *examplecomv1alpha1.DeviceConfig) []kmmv1beta1.KernelMapping {
-	kernelMappings := []kmmv1beta1.KernelMapping{
+func makeKernelMappings(cr examplecomv1alpha1.DeviceConfigObject) []kernelMapping {
+	kernelMappings := []kernelMapping{
 		{
-			ContainerImage: fmt.Sprintf("%s:%s-${KERNEL_FULL_VERSION}", cr.Spec.DriverImage, cr.Spec.DriverVersion),
-			Regexp:         `^.*\.el\d_?\d?\..*$`,
+			containerImage: driverImageTemplate(cr),
+			regexp:         config.GetDefaults().KernelRegexp,
 		},
 	}
 
 	return kernelMappings
 }
+
+// driverImageTemplate returns the driver image of the DeviceConfig, in which KMM substitutes
+// the kernel version of every node.
+func driverImageTemplate(cr examplecomv1alpha1.DeviceConfigObject) string {
+	return fmt.Sprintf("%s:%s-%s", cr.GetSpec().DriverImage, cr.GetSpec().DriverVersion, kernelVersionVariable)
+}
+
+// GetKernelMapping returns the regular expression of the DeviceConfig kernel mapping matching
+// the given kernel and the driver image KMM loads on the nodes running it.
+func GetKernelMapping(cr examplecomv1alpha1.DeviceConfigObject, kernelVersion string) (string, string, bool) {
+	for _, km := range makeKernelMappings(cr) {
+		re, err := regexp.Compile(km.regexp)
+		if err != nil || !re.MatchString(kernelVersion) {
+			continue
+		}
+		return km.regexp, strings.ReplaceAll(km.containerImage, kernelVersionVariable, kernelVersion), true
+	}
+	return "", "", false
+}
+
+// GetKernelDriverImage returns the driver image KMM loads on the nodes running the given
+// kernel, if any of the DeviceConfig kernel mappings matches it.
+func GetKernelDriverImage(cr examplecomv1alpha1.DeviceConfigObject, kernelVersion string) (string, bool) {
+	_, image, ok := GetKernelMapping(cr, kernelVersion)
+	return image, ok
+}
@@ -17,13 +17,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	gomock "github.com/golang/mock/gomock"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,22 +37,29 @@ import (
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
 	mockClient "github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/config"
 )
 
 const (
 	testDriverImage   = "driver"
 	testDriverVersion = "test"
+	testModuleName    = "sample"
 
 	testLabelKey   = "label"
 	testLabelValue = "test"
+
+	testDriverNamespace = "driver-namespace"
 )
 
+var notFound = apierrors.NewNotFound(schema.GroupResource{Group: "kmm.sigs.k8s.io", Resource: "modules"}, "a-module")
+
 var _ = Describe("ModuleReconciler", func() {
 	var (
-		dc  *examplecomv1alpha1.DeviceConfig
-		r   *moduleReconciler
-		c   *mockClient.MockClient
-		ctx context.Context
+		dc       *examplecomv1alpha1.DeviceConfig
+		r        *moduleReconciler
+		c        *mockClient.MockClient
+		recorder *record.FakeRecorder
+		ctx      context.Context
 	)
 
 	BeforeEach(func() {
@@ -61,59 +74,303 @@ var _ = Describe("ModuleReconciler", func() {
 		s := scheme.Scheme
 		Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
 		Expect(kmmv1beta1.AddToScheme(s)).ToNot(HaveOccurred())
-		r = NewReconciler(c, s)
+		recorder = record.NewFakeRecorder(1)
+		r = NewReconciler(c, s, recorder, testDriverNamespace)
 
 		ctx = context.TODO()
 	})
 
+	newRESTMapper := func(versions ...string) meta.RESTMapper {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for _, v := range versions {
+			mapper.Add(ModuleGroupKind.WithVersion(v), meta.RESTScopeNamespace)
+		}
+		return mapper
+	}
+
 	Describe("ReconcileModule", func() {
-		Context("with no client Get error", func() {
+		isApply := gomock.Eq(client.Apply)
+		fieldOwner := gomock.Eq(client.FieldOwner(FieldManager))
+
+		Context("with the v1beta1 Module API", func() {
 			BeforeEach(func() {
-				gomock.InOrder(
-					c.EXPECT().
-						Get(ctx, gomock.Any(), gomock.Any()).
-						Return(apierrors.NewNotFound(schema.GroupResource{Resource: "modules"}, GetModuleName(dc))).
-						AnyTimes(),
+				c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1")).AnyTimes()
+				c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(notFound).AnyTimes()
+			})
+
+			It("should apply the Module with the operator field manager", func() {
+				c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						Expect(obj.GetName()).To(Equal(GetModuleName(dc)))
+						Expect(obj.GetNamespace()).To(Equal(dc.Namespace))
+						Expect(obj.GetObjectKind().GroupVersionKind().Version).To(Equal("v1beta1"))
+						return nil
+					},
 				)
+
+				Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+				Expect(dc.Status.ModuleDrift).To(BeNil())
+			})
+
+			It("should return an apply error", func() {
+				c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).Return(errors.New("some-error"))
+
+				Expect(r.ReconcileModule(ctx, dc)).To(HaveOccurred())
+			})
+
+			It("should apply one Module per node group with disjoint selectors", func() {
+				dc.Spec.ModuleParameters = []string{"num_queues=8"}
+				dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+					{Name: "sriov", NodeSelector: map[string]string{"pool": "sriov"}, ModuleParameters: []string{"sriov_mode=1"}},
+					{Name: "large", NodeSelector: map[string]string{"pool": "large"}, ModuleParameters: []string{"num_queues=32"}},
+				}
+
+				applied := map[string]kmmv1beta1.ModuleSpec{}
+				c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						m := &kmmv1beta1.Module{}
+						Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, m)).To(Succeed())
+						applied[obj.GetName()] = m.Spec
+						return nil
+					},
+				).Times(3)
+
+				Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+				Expect(applied).To(HaveLen(3))
+
+				groupLabel := dc.GetNodeGroupLabel()
+				expected := map[string]struct {
+					group  string
+					params []string
+				}{
+					"a-device-config-module":       {examplecomv1alpha1.DefaultNodeGroup, []string{"num_queues=8"}},
+					"a-device-config-module-sriov": {"sriov", []string{"num_queues=8", "sriov_mode=1"}},
+					"a-device-config-module-large": {"large", []string{"num_queues=32"}},
+				}
+				for name, e := range expected {
+					Expect(applied).To(HaveKey(name))
+					Expect(applied[name].Selector).To(HaveKeyWithValue(groupLabel, e.group))
+					Expect(applied[name].ModuleLoader.Container.Modprobe.Parameters).To(Equal(e.params))
+				}
+			})
+
+			It("should not apply kernel modules with a dependency cycle", func() {
+				dc.Spec.ModuleName = testModuleName
+				dc.Spec.KernelModules = []examplecomv1alpha1.KernelModule{
+					{Name: "sample_rdma", DependsOn: []string{"sample_rdma"}},
+				}
+
+				Expect(r.ReconcileModule(ctx, dc)).To(MatchError(ContainSubstring("dependency cycle")))
+			})
+
+			It("should not apply a node group with the reserved name", func() {
+				dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+					{Name: examplecomv1alpha1.DefaultNodeGroup, NodeSelector: map[string]string{"pool": "a"}},
+				}
+
+				Expect(r.ReconcileModule(ctx, dc)).To(MatchError(ContainSubstring("reserved")))
 			})
 
-			Context("with no client Create error", func() {
-				BeforeEach(func() {
+			Context("with fields changed by other field managers", func() {
+				conflict := apierrors.NewApplyConflict([]metav1.StatusCause{
+					{
+						Type:    metav1.CauseTypeFieldManagerConflict,
+						Message: `conflict with "kubectl-edit" using kmm.sigs.k8s.io/v1beta1`,
+						Field:   ".spec.selector.label",
+					},
+				}, "Apply failed with 1 conflict")
+
+				It("should record the drift, emit an event and revert it", func() {
 					gomock.InOrder(
-						c.EXPECT().Create(ctx, gomock.Any()).Return(nil),
+						c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).Return(conflict),
+						c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner, gomock.Eq(client.ForceOwnership)).Return(nil),
 					)
+
+					Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+					Expect(dc.Status.ModuleDrift).ToNot(BeNil())
+					Expect(dc.Status.ModuleDrift.Fields).To(Equal([]examplecomv1alpha1.FieldDrift{
+						{Field: ".spec.selector.label", Manager: "kubectl-edit"},
+					}))
+					Expect(recorder.Events).To(Receive(And(
+						ContainSubstring(driftEventReason),
+						ContainSubstring(".spec.selector.label (kubectl-edit)"),
+					)))
 				})
-				It("should not return an error", func() {
-					Expect(r.ReconcileModule(ctx, dc)).ToNot(HaveOccurred())
-				})
-			})
 
-			Context("with client Create error", func() {
-				BeforeEach(func() {
+				It("should return an error when the drift cannot be reverted", func() {
 					gomock.InOrder(
-						c.EXPECT().Create(ctx, gomock.Any()).Return(errors.New("some-error")),
+						c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).Return(conflict),
+						c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner, gomock.Eq(client.ForceOwnership)).Return(errors.New("some-error")),
 					)
-				})
-				It("should return an error", func() {
+
 					Expect(r.ReconcileModule(ctx, dc)).To(HaveOccurred())
 				})
 			})
 		})
 
-		Context("with client Get error", func() {
+		Context("with a ClusterDeviceConfig", func() {
+			var cdc *examplecomv1alpha1.ClusterDeviceConfig
+
+			BeforeEach(func() {
+				cdc = &examplecomv1alpha1.ClusterDeviceConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"},
+				}
+			})
+
+			It("should apply the Module in the driver namespace", func() {
+				c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1"))
+				c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(notFound)
+				c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						Expect(obj.GetNamespace()).To(Equal(testDriverNamespace))
+						Expect(obj.GetName()).To(Equal("a-device-config-cluster-module"))
+						return nil
+					},
+				)
+
+				Expect(r.ReconcileModule(ctx, cdc)).To(Succeed())
+			})
+
+			It("should return an error without a driver namespace", func() {
+				r = NewReconciler(c, scheme.Scheme, recorder, "")
+
+				Expect(r.ReconcileModule(ctx, cdc)).To(MatchError(ErrNoDriverNamespace))
+			})
+		})
+
+		It("should apply the Module in the preferred served API version", func() {
+			c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1", "v1beta2"))
+			c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(notFound)
+			c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+					Expect(obj.GetObjectKind().GroupVersionKind().Version).To(Equal("v1beta2"))
+					return nil
+				},
+			)
+
+			Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+		})
+
+		It("should return a NoKindMatchError when KMM is not installed", func() {
+			c.EXPECT().RESTMapper().Return(newRESTMapper())
+
+			Expect(meta.IsNoMatchError(r.ReconcileModule(ctx, dc))).To(BeTrue())
+		})
+
+		Context("with an existing Module not generated by the operator", func() {
+			BeforeEach(func() {
+				c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1")).AnyTimes()
+				c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(nil)
+			})
+
+			It("should not take it over", func() {
+				Expect(r.ReconcileModule(ctx, dc)).To(MatchError(ErrModuleNotManaged))
+			})
+
+			It("should adopt it when named by the adoption annotation", func() {
+				dc.Annotations = map[string]string{examplecomv1alpha1.AdoptModuleAnnotation: "an-existing-module"}
+				c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						Expect(obj.GetName()).To(Equal("an-existing-module"))
+						Expect(obj.GetLabels()).To(HaveKeyWithValue(ManagedLabel, ManagedLabelValue))
+						return nil
+					},
+				)
+
+				Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+				Expect(recorder.Events).To(Receive(ContainSubstring("Adopted existing Module an-existing-module")))
+			})
+		})
+
+		It("should reject an invalid adopted Module name", func() {
+			c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1"))
+			dc.Annotations = map[string]string{examplecomv1alpha1.AdoptModuleAnnotation: "An_Invalid_Name"}
+
+			Expect(r.ReconcileModule(ctx, dc)).To(MatchError(ContainSubstring("invalid Module name")))
+		})
+
+		Context("with a Module released by a deleted DeviceConfig", func() {
+			getReleased := func(policy string) *gomock.Call {
+				return c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+						obj.SetAnnotations(map[string]string{examplecomv1alpha1.DeletionPolicyAnnotation: policy})
+						return nil
+					},
+				)
+			}
+
 			BeforeEach(func() {
+				c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1")).AnyTimes()
+			})
+
+			It("should adopt an orphaned Module", func() {
 				gomock.InOrder(
-					c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some-other-that-not-found-error")),
+					getReleased(examplecomv1alpha1.DeletionPolicyOrphan),
+					c.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+							Expect(obj.GetAnnotations()).ToNot(HaveKey(examplecomv1alpha1.DeletionPolicyAnnotation))
+							return nil
+						},
+					),
+					c.EXPECT().Patch(ctx, gomock.Any(), isApply, fieldOwner).DoAndReturn(
+						func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+							Expect(metav1.IsControlledBy(obj, dc)).To(BeTrue())
+							return nil
+						},
+					),
 				)
+
+				Expect(r.ReconcileModule(ctx, dc)).To(Succeed())
+				Expect(recorder.Events).To(Receive(ContainSubstring(adoptedEventReason)))
 			})
 
-			It("should return an error", func() {
-				Expect(r.ReconcileModule(ctx, dc)).To(HaveOccurred())
+			It("should not reconcile a retained Module", func() {
+				getReleased(examplecomv1alpha1.DeletionPolicyRetain)
+
+				Expect(r.ReconcileModule(ctx, dc)).To(MatchError(ErrDriverRetained))
 			})
 		})
 	})
 
+	Describe("ReleaseModule", func() {
+		BeforeEach(func() {
+			dc.UID = "a-uid"
+			dc.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyOrphan
+			c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1")).AnyTimes()
+		})
+
+		It("should detach the Module and annotate it with the deletion policy", func() {
+			gomock.InOrder(
+				c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+						obj.SetOwnerReferences([]metav1.OwnerReference{{Name: dc.Name, UID: dc.UID}})
+						return nil
+					},
+				),
+				c.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						Expect(obj.GetOwnerReferences()).To(BeEmpty())
+						Expect(obj.GetAnnotations()).To(HaveKeyWithValue(examplecomv1alpha1.DeletionPolicyAnnotation, examplecomv1alpha1.DeletionPolicyOrphan))
+						return nil
+					},
+				),
+			)
+
+			Expect(r.ReleaseModule(ctx, dc)).To(Succeed())
+		})
+
+		It("should ignore a missing Module", func() {
+			c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(notFound)
+
+			Expect(r.ReleaseModule(ctx, dc)).To(Succeed())
+		})
+	})
+
 	Describe("DeleteModule", func() {
+		BeforeEach(func() {
+			c.EXPECT().RESTMapper().Return(newRESTMapper("v1beta1")).AnyTimes()
+		})
+
 		Context("without a client Delete error", func() {
 			BeforeEach(func() {
 				gomock.InOrder(
@@ -151,20 +408,49 @@ var _ = Describe("ModuleReconciler", func() {
 				Expect(r.DeleteModule(ctx, dc)).To(HaveOccurred())
 			})
 		})
+
+		It("should delete the Module of every node group", func() {
+			dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{{Name: "sriov", NodeSelector: map[string]string{"pool": "sriov"}}}
+
+			var deleted []string
+			c.EXPECT().Delete(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+					deleted = append(deleted, obj.GetName())
+					return nil
+				},
+			).Times(2)
+
+			Expect(r.DeleteModule(ctx, dc)).To(Succeed())
+			Expect(deleted).To(Equal([]string{"a-device-config-module", "a-device-config-module-sriov"}))
+		})
 	})
 
 	Describe("SetDesiredModule", func() {
 		var (
-			m *kmmv1beta1.Module
+			m *unstructured.Unstructured
+			// typed is the typed v1beta1 view of m.
+			typed *kmmv1beta1.Module
 		)
 
+		newModule := func() *unstructured.Unstructured {
+			m := &unstructured.Unstructured{}
+			m.SetGroupVersionKind(kmmv1beta1.GroupVersion.WithKind("Module"))
+			return m
+		}
+
+		setDesiredModule := func(cr examplecomv1alpha1.DeviceConfigObject) {
+			Expect(r.SetDesiredModule(m, cr, "")).To(Succeed())
+			typed = &kmmv1beta1.Module{}
+			Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(m.Object, typed)).To(Succeed())
+		}
+
 		Context("with a nil Module as input", func() {
 			BeforeEach(func() {
 				m = nil
 			})
 
 			It("should return a module cannot be nil error", func() {
-				err := r.SetDesiredModule(m, dc)
+				err := r.SetDesiredModule(m, dc, "")
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("module cannot be nil"))
@@ -176,40 +462,127 @@ var _ = Describe("ModuleReconciler", func() {
 				dc.Spec.NodeSelector = map[string]string{testLabelKey: testLabelValue}
 				dc.Spec.DriverImage = testDriverImage
 				dc.Spec.DriverVersion = testDriverVersion
+				dc.Spec.ModuleName = testModuleName
 
-				m = &kmmv1beta1.Module{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "a-name",
-						Namespace: "a-namespace",
-					},
-				}
+				m = newModule()
+				m.SetName("a-name")
+				m.SetNamespace("a-namespace")
 
-				err := r.SetDesiredModule(m, dc)
-				Expect(err).ToNot(HaveOccurred())
+				setDesiredModule(dc)
 			})
 
 			Context("it returns a Module which", func() {
 				It("should contain the correct node selector", func() {
-					Expect(m.Spec.Selector).ToNot(BeNil())
+					Expect(typed.Spec.Selector).ToNot(BeNil())
 
-					v, contains := m.Spec.Selector[testLabelKey]
+					v, contains := typed.Spec.Selector[testLabelKey]
 					Expect(contains).To(BeTrue())
 					Expect(v).To(Equal(testLabelValue))
 				})
 
+				It("should select the nodes through the computed node label for set-based selectors", func() {
+					dc.Spec.NodeLabelSelector = &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: testLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{testLabelValue}},
+						},
+					}
+
+					setDesiredModule(dc)
+					Expect(typed.Spec.Selector).To(Equal(map[string]string{
+						examplecomv1alpha1.ComputedNodeLabelPrefix + "a-namespace.a-device-config": examplecomv1alpha1.ComputedNodeLabelValue,
+					}))
+				})
+
+				It("should be owned by the DeviceConfig", func() {
+					Expect(typed.OwnerReferences).To(HaveLen(1))
+					Expect(typed.OwnerReferences[0].Name).To(Equal(dc.Name))
+					Expect(typed.Labels).ToNot(HaveKey(examplecomv1alpha1.ClusterDeviceConfigOwnerLabel))
+				})
+
+				It("should be labelled as managed and reference its owner", func() {
+					Expect(typed.Labels).To(HaveKeyWithValue(ManagedLabel, ManagedLabelValue))
+					Expect(typed.Annotations).To(Equal(map[string]string{
+						OwnerKindAnnotation: "DeviceConfig",
+						OwnerNameAnnotation: dc.Name,
+					}))
+				})
+
+				It("should be labelled instead of owned for a ClusterDeviceConfig", func() {
+					cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+						ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"},
+						Spec:       dc.Spec,
+					}
+					m = newModule()
+
+					setDesiredModule(cdc)
+					Expect(typed.OwnerReferences).To(BeEmpty())
+					Expect(typed.Labels).To(HaveKeyWithValue(examplecomv1alpha1.ClusterDeviceConfigOwnerLabel, "a-device-config"))
+				})
+
 				It("should have the correct ModuleLoader", func() {
-					Expect(m.Spec.ModuleLoader).ToNot(BeNil())
+					Expect(typed.Spec.ModuleLoader).ToNot(BeNil())
 
-					Expect(m.Spec.ModuleLoader.Container.ImagePullPolicy).To(Equal(corev1.PullAlways))
+					Expect(typed.Spec.ModuleLoader.Container.ImagePullPolicy).To(Equal(corev1.PullAlways))
 
-					Expect(m.Spec.ModuleLoader.Container.KernelMappings).ToNot(BeNil())
-					Expect(m.Spec.ModuleLoader.Container.KernelMappings).To(HaveLen(1))
+					Expect(typed.Spec.ModuleLoader.Container.KernelMappings).ToNot(BeNil())
+					Expect(typed.Spec.ModuleLoader.Container.KernelMappings).To(HaveLen(1))
 					expectedImage := fmt.Sprintf("%s:%s-${KERNEL_FULL_VERSION}", testDriverImage, testDriverVersion)
-					Expect(m.Spec.ModuleLoader.Container.KernelMappings[0].ContainerImage).To(Equal(expectedImage))
+					Expect(typed.Spec.ModuleLoader.Container.KernelMappings[0].ContainerImage).To(Equal(expectedImage))
 
-					Expect(m.Spec.ModuleLoader.ServiceAccountName).To(Equal(driverServiceAccount))
+					Expect(typed.Spec.ModuleLoader.Container.Modprobe.ModuleName).To(Equal(testModuleName))
+
+					Expect(typed.Spec.ModuleLoader.ServiceAccountName).To(Equal(config.DefaultDriverServiceAccount))
 				})
 			})
 		})
 	})
 })
+
+var _ = Describe("GetModuleName", func() {
+	It("should derive the Module name from the DeviceConfig name", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"}}
+		Expect(GetModuleName(dc)).To(Equal("a-device-config-module"))
+
+		cdc := &examplecomv1alpha1.ClusterDeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: "a-device-config"}}
+		Expect(GetModuleName(cdc)).To(Equal("a-device-config-cluster-module"))
+	})
+
+	It("should truncate long names deterministically", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 70), Namespace: "a-namespace"}}
+		other := &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 71), Namespace: "a-namespace"}}
+
+		name := GetModuleName(dc)
+		Expect(name).To(HaveLen(63))
+		Expect(GetModuleName(dc)).To(Equal(name))
+		Expect(GetModuleName(other)).ToNot(Equal(name))
+	})
+
+	It("should return the Module named by the adoption annotation", func() {
+		dc := &examplecomv1alpha1.DeviceConfig{ObjectMeta: metav1.ObjectMeta{
+			Name:        "a-device-config",
+			Namespace:   "a-namespace",
+			Annotations: map[string]string{examplecomv1alpha1.AdoptModuleAnnotation: "an-existing-module"},
+		}}
+		Expect(GetModuleName(dc)).To(Equal("an-existing-module"))
+	})
+})
+
+var _ = Describe("GetKernelDriverImage", func() {
+	dc := &examplecomv1alpha1.DeviceConfig{
+		Spec: examplecomv1alpha1.DeviceConfigSpec{
+			DriverImage:   testDriverImage,
+			DriverVersion: testDriverVersion,
+		},
+	}
+
+	It("should resolve the image of a mapped kernel", func() {
+		image, ok := GetKernelDriverImage(dc, "5.14.0-70.13.1.el9_0.x86_64")
+		Expect(ok).To(BeTrue())
+		Expect(image).To(Equal("driver:test-5.14.0-70.13.1.el9_0.x86_64"))
+	})
+
+	It("should not resolve an unmapped kernel", func() {
+		_, ok := GetKernelDriverImage(dc, "5.15.0-48-generic")
+		Expect(ok).To(BeFalse())
+	})
+})
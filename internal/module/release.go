@@ -0,0 +1,63 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package module
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// ErrDriverRetained is returned when reconciling a driver left running by a deleted resource
+// with the Retain deletion policy.
+var ErrDriverRetained = fmt.Errorf("the driver has been retained by a deleted DeviceConfig, remove its %s annotation to adopt it",
+	examplecomv1alpha1.DeletionPolicyAnnotation)
+
+// Release detaches the given driver object from the given deleted resource, so that it is not
+// garbage collected with it, and annotates it with the deletion policy of the resource.
+func Release(obj metav1.Object, cr examplecomv1alpha1.DeviceConfigObject) {
+	refs := []metav1.OwnerReference{}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != cr.GetUID() {
+			refs = append(refs, ref)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[examplecomv1alpha1.DeletionPolicyAnnotation] = cr.GetSpec().GetDeletionPolicy()
+	obj.SetAnnotations(annotations)
+}
+
+// Adopt removes the deletion policy annotation of the given driver object and returns whether
+// it had been released with the Orphan deletion policy. ErrDriverRetained is returned for a
+// driver object released with the Retain deletion policy, which is left untouched.
+func Adopt(obj metav1.Object) (bool, error) {
+	annotations := obj.GetAnnotations()
+	switch annotations[examplecomv1alpha1.DeletionPolicyAnnotation] {
+	case "":
+		return false, nil
+	case examplecomv1alpha1.DeletionPolicyRetain:
+		return false, ErrDriverRetained
+	}
+
+	delete(annotations, examplecomv1alpha1.DeletionPolicyAnnotation)
+	obj.SetAnnotations(annotations)
+	return true, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+
+// Parse returns the sorted and deduplicated namespaces of comma-separated lists, e.g. the
+// WATCH_NAMESPACE environment variable. An empty result means all namespaces.
+func Parse(values ...string) []string {
+	seen := make(map[string]bool)
+	namespaces := make([]string, 0)
+	for _, value := range values {
+		for _, ns := range strings.Split(value, ",") {
+			ns = strings.TrimSpace(ns)
+			if ns == "" || seen[ns] {
+				continue
+			}
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+// Validate returns an error listing the namespaces which are not valid names or do not exist.
+// It is meant to be run at startup with an uncached reader, since the cache of the manager is
+// not started yet.
+func Validate(ctx context.Context, r client.Reader, namespaces []string) error {
+	var errs []error
+	for _, ns := range namespaces {
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid namespace %q: %s", ns, strings.Join(msgs, ", ")))
+			continue
+		}
+
+		err := r.Get(ctx, client.ObjectKey{Name: ns}, &corev1.Namespace{})
+		if apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("namespace %q does not exist", ns))
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get namespace %q: %w", ns, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// NewCache returns a cache builder restricted to the given namespaces, or to all namespaces if
+// none is given. The cluster-scoped objects, e.g. Nodes, are cached regardless of the
+// namespaces. The options, e.g. the label selectors of the cached objects, apply to every
+// namespace.
+func NewCache(namespaces []string, options cache.Options) cache.NewCacheFunc {
+	if len(namespaces) < 2 {
+		if len(namespaces) == 1 {
+			options.Namespace = namespaces[0]
+		}
+		return cache.BuilderWithOptions(options)
+	}
+
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		o := options
+		if o.Scheme == nil {
+			o.Scheme = opts.Scheme
+		}
+		if o.Mapper == nil {
+			o.Mapper = opts.Mapper
+		}
+		if o.Resync == nil {
+			o.Resync = opts.Resync
+		}
+
+		return cache.MultiNamespacedCacheBuilder(namespaces)(config, o)
+	}
+}
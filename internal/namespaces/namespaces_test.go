@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespaces
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	It("should return all namespaces for an empty list", func() {
+		Expect(Parse("")).To(BeEmpty())
+		Expect(Parse(" , ")).To(BeEmpty())
+	})
+
+	It("should return the sorted and deduplicated namespaces", func() {
+		Expect(Parse("tenant-b, tenant-a,,tenant-b", "driver-namespace")).To(Equal([]string{
+			"driver-namespace",
+			"tenant-a",
+			"tenant-b",
+		}))
+	})
+})
+
+var _ = Describe("Validate", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+	})
+
+	It("should accept existing namespaces", func() {
+		c := fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(makeTestNamespace("tenant-a"), makeTestNamespace("tenant-b")).
+			Build()
+
+		Expect(Validate(ctx, c, []string{"tenant-a", "tenant-b"})).To(Succeed())
+	})
+
+	It("should accept an empty list", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+		Expect(Validate(ctx, c, nil)).To(Succeed())
+	})
+
+	It("should report every missing or invalid namespace", func() {
+		c := fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(makeTestNamespace("tenant-a")).
+			Build()
+
+		err := Validate(ctx, c, []string{"tenant-a", "tenant-b", "Tenant_C"})
+		Expect(err).To(MatchError(And(
+			ContainSubstring(`namespace "tenant-b" does not exist`),
+			ContainSubstring(`invalid namespace "Tenant_C"`),
+			Not(ContainSubstring(`"tenant-a"`)),
+		)))
+	})
+})
+
+func makeTestNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
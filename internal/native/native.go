@@ -0,0 +1,415 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package native
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	// NodeKernelVersionLabel is set on the selected nodes to schedule the DaemonSet of their
+	// kernel version, which is not exposed by any well-known node label.
+	NodeKernelVersionLabel = "example.com/kernel-version.full"
+
+	// ManagedByLabel and ManagedByValue mark the DaemonSets and pods of the native backend.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "he-sample-operator"
+
+	containerName     = "module-loader"
+	loadOrderEnv      = "MODULE_LOAD_ORDER"
+	unloadOrderEnv    = "MODULE_UNLOAD_ORDER"
+	libModulesVolume  = "node-lib-modules"
+	libModulesPath    = "/lib/modules"
+	kernelHashLength  = 10
+	modulesRootPrefix = "/opt"
+)
+
+// The kernel module names are passed through the environment as space-separated lists, which
+// are split with globbing disabled, so that they are never otherwise interpreted by the shell.
+// The kernel modules are loaded one at a time in their load order and unloaded in reverse. The
+// module parameters are passed as the positional arguments of the load command, which are only
+// set for a single kernel module, see module.ValidateKernelModules.
+var (
+	loadCommand = []string{"/bin/sh", "-c", fmt.Sprintf(
+		`set -f; for m in $%s; do modprobe -v -d %s "$m" "$@" || exit 1; done; exec sleep infinity`, loadOrderEnv, modulesRootPrefix)}
+	unloadCommand = []string{"/bin/sh", "-c", fmt.Sprintf(
+		`set -f; for m in $%s; do modprobe -r -v -d %s "$m" || exit 1; done`, unloadOrderEnv, modulesRootPrefix)}
+	loadedCommand = []string{"/bin/sh", "-c", fmt.Sprintf(
+		`set -f; for m in $%s; do test -d "/sys/module/$(echo "$m" | tr - _)" || exit 1; done`, loadOrderEnv)}
+)
+
+// ErrNodeGroupsNotSupported is returned when reconciling the driver of a resource with node
+// groups, which are only supported by the KMM backend.
+var ErrNodeGroupsNotSupported = errors.New("node groups are not supported by the native backend")
+
+// ErrNoModuleName is returned when reconciling the driver of a resource without a module name,
+// whose pods would load no kernel module and still report the driver as loaded.
+var ErrNoModuleName = errors.New("the native backend requires a module name")
+
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete;deletecollection
+
+// nativeBackend deploys the driver of a DeviceConfig with one privileged DaemonSet per kernel
+// version of its selected nodes, which loads the kernel module with modprobe and unloads it
+// when its pod is terminated.
+//
+// The DaemonSets and pods carry the same labels as the KMM ModuleLoader ones, so that they
+// are inspected and watched the same way.
+type nativeBackend struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// driverNamespace is the namespace of the ClusterDeviceConfig DaemonSets.
+	driverNamespace string
+}
+
+func NewBackend(c client.Client, s *runtime.Scheme, driverNamespace string) *nativeBackend {
+	return &nativeBackend{
+		client:          c,
+		scheme:          s,
+		driverNamespace: driverNamespace,
+	}
+}
+
+func (b *nativeBackend) ReconcileModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "native.ReconcileModule", cr)
+	defer tracing.End(span, &err)
+
+	logger := log.FromContext(ctx)
+
+	if len(cr.GetSpec().NodeGroups) > 0 {
+		return ErrNodeGroupsNotSupported
+	}
+	if cr.GetSpec().ModuleName == "" {
+		return ErrNoModuleName
+	}
+	if err := module.ValidateKernelModules(cr); err != nil {
+		return err
+	}
+
+	namespace, err := module.GetModuleNamespace(cr, b.driverNamespace)
+	if err != nil {
+		return err
+	}
+
+	dss := &appsv1.DaemonSetList{}
+	if err := b.client.List(ctx, dss, client.InNamespace(namespace), client.MatchingLabels(managedLabels(cr))); err != nil {
+		return fmt.Errorf("failed to list DaemonSets: %w", err)
+	}
+	if err := b.adoptDaemonSets(ctx, dss); err != nil {
+		return err
+	}
+
+	images, err := b.labelSelectedNodes(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]bool, len(images))
+	for kernel, image := range images {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      GetDaemonSetName(cr, kernel),
+				Namespace: namespace,
+			},
+		}
+		desired[ds.Name] = true
+
+		res, err := controllerutil.CreateOrPatch(ctx, b.client, ds, func() error {
+			return b.setDesiredDaemonSet(ds, cr, kernel, image)
+		})
+		if err != nil {
+			return fmt.Errorf("could not create or patch DaemonSet %s: %w", ds.Name, err)
+		}
+		logger.Info("Reconciled DaemonSet", "resource", ds.Name, "kernel", kernel, "result", res)
+	}
+
+	for i := range dss.Items {
+		ds := &dss.Items[i]
+		if desired[ds.Name] {
+			continue
+		}
+		if err := b.client.Delete(ctx, ds, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete stale DaemonSet %s: %w", ds.Name, err)
+		}
+		logger.Info("Deleted stale DaemonSet", "resource", ds.Name)
+	}
+
+	return nil
+}
+
+func (b *nativeBackend) DeleteModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "native.DeleteModule", cr)
+	defer tracing.End(span, &err)
+
+	namespace, err := module.GetModuleNamespace(cr, b.driverNamespace)
+	if err != nil {
+		return err
+	}
+
+	err = b.client.DeleteAllOf(ctx, &appsv1.DaemonSet{},
+		client.InNamespace(namespace),
+		client.MatchingLabels(managedLabels(cr)),
+		client.PropagationPolicy(metav1.DeletePropagationBackground),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete DaemonSets: %w", err)
+	}
+
+	return b.unlabelSelectedNodes(ctx, cr)
+}
+
+// ReleaseModule leaves the DaemonSets of the given deleted resource running, detached from it
+// and annotated with its deletion policy.
+func (b *nativeBackend) ReleaseModule(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "native.ReleaseModule", cr)
+	defer tracing.End(span, &err)
+
+	namespace, err := module.GetModuleNamespace(cr, b.driverNamespace)
+	if err != nil {
+		return err
+	}
+
+	dss := &appsv1.DaemonSetList{}
+	if err := b.client.List(ctx, dss, client.InNamespace(namespace), client.MatchingLabels(managedLabels(cr))); err != nil {
+		return fmt.Errorf("failed to list DaemonSets: %w", err)
+	}
+
+	for i := range dss.Items {
+		ds := &dss.Items[i]
+		patch := client.MergeFrom(ds.DeepCopy())
+		module.Release(ds, cr)
+		if err := b.client.Patch(ctx, ds, patch); err != nil {
+			return fmt.Errorf("failed to release DaemonSet %s: %w", ds.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// adoptDaemonSets removes the deletion policy annotation of the given DaemonSets orphaned by a
+// deleted resource of the same name. Their ownership is set again when they are patched.
+func (b *nativeBackend) adoptDaemonSets(ctx context.Context, dss *appsv1.DaemonSetList) error {
+	logger := log.FromContext(ctx)
+
+	for i := range dss.Items {
+		ds := &dss.Items[i]
+		patch := client.MergeFrom(ds.DeepCopy())
+		adopted, err := module.Adopt(ds)
+		if err != nil {
+			return fmt.Errorf("could not adopt DaemonSet %s: %w", ds.Name, err)
+		}
+		if !adopted {
+			continue
+		}
+		if err := b.client.Patch(ctx, ds, patch); err != nil {
+			return fmt.Errorf("could not adopt DaemonSet %s: %w", ds.Name, err)
+		}
+		logger.Info("Adopted orphaned DaemonSet", "resource", ds.Name)
+	}
+
+	return nil
+}
+
+// GetDaemonSetName returns the name of the DaemonSet loading the driver of the given resource
+// on the nodes running the given kernel. The kernel is hashed, since its version may not be a
+// valid name.
+func GetDaemonSetName(cr examplecomv1alpha1.DeviceConfigObject, kernel string) string {
+	hash := sha256.Sum256([]byte(kernel))
+	return fmt.Sprintf("%s-%s", module.GetModuleName(cr), hex.EncodeToString(hash[:])[:kernelHashLength])
+}
+
+// labelSelectedNodes sets the kernel version label on the selected nodes and returns the
+// driver image of each of their kernels matched by a kernel mapping.
+func (b *nativeBackend) labelSelectedNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (map[string]string, error) {
+	logger := log.FromContext(ctx)
+
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := b.client.List(ctx, nodes, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	images := map[string]string{}
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+
+		kernel := n.Status.NodeInfo.KernelVersion
+		image, ok := module.GetKernelDriverImage(cr, kernel)
+		if !ok {
+			continue
+		}
+		if errs := validation.IsValidLabelValue(kernel); len(errs) > 0 {
+			logger.Info("Skipping node with a kernel version which is not a valid label value", "node", n.Name, "kernel", kernel)
+			continue
+		}
+		images[kernel] = image
+
+		if n.Labels[NodeKernelVersionLabel] == kernel {
+			continue
+		}
+		patch := client.MergeFrom(n.DeepCopy())
+		if n.Labels == nil {
+			n.Labels = map[string]string{}
+		}
+		n.Labels[NodeKernelVersionLabel] = kernel
+		if err := b.client.Patch(ctx, n, patch); err != nil {
+			return nil, fmt.Errorf("failed to label node %s: %w", n.Name, err)
+		}
+	}
+
+	return images, nil
+}
+
+// unlabelSelectedNodes removes the kernel version label from the selected nodes.
+func (b *nativeBackend) unlabelSelectedNodes(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error {
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := b.client.List(ctx, nodes, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		if _, ok := n.Labels[NodeKernelVersionLabel]; !ok {
+			continue
+		}
+		patch := client.MergeFrom(n.DeepCopy())
+		delete(n.Labels, NodeKernelVersionLabel)
+		if err := b.client.Patch(ctx, n, patch); err != nil {
+			return fmt.Errorf("failed to unlabel node %s: %w", n.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *nativeBackend) setDesiredDaemonSet(ds *appsv1.DaemonSet, cr examplecomv1alpha1.DeviceConfigObject, kernel, image string) error {
+	loadOrder, err := module.GetLoadOrder(cr)
+	if err != nil {
+		return err
+	}
+
+	nodeSelector := cr.GetModuleNodeSelector()
+	nodeSelector[NodeKernelVersionLabel] = kernel
+
+	privileged := true
+	defaults := config.GetDefaults()
+
+	ds.Labels = daemonSetLabels(cr, kernel)
+	ds.Spec = appsv1.DaemonSetSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: daemonSetLabels(cr, kernel)},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: daemonSetLabels(cr, kernel)},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:            containerName,
+						Image:           image,
+						ImagePullPolicy: defaults.ImagePullPolicy,
+						Command:         loadCommand,
+						// The first argument is the name of the shell script, i.e. $0.
+						Args: append([]string{containerName}, cr.GetSpec().GetModuleParameters(examplecomv1alpha1.DefaultNodeGroup)...),
+						Env: []corev1.EnvVar{
+							{Name: loadOrderEnv, Value: strings.Join(loadOrder, " ")},
+							{Name: unloadOrderEnv, Value: strings.Join(module.GetUnloadOrder(loadOrder), " ")},
+						},
+						Lifecycle: &corev1.Lifecycle{
+							PreStop: &corev1.LifecycleHandler{
+								Exec: &corev1.ExecAction{Command: unloadCommand},
+							},
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								Exec: &corev1.ExecAction{Command: loadedCommand},
+							},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: &privileged,
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: libModulesVolume, MountPath: libModulesPath, ReadOnly: true},
+						},
+					},
+				},
+				NodeSelector:       nodeSelector,
+				ServiceAccountName: defaults.DriverServiceAccount,
+				Volumes: []corev1.Volume{
+					{
+						Name: libModulesVolume,
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{Path: libModulesPath},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The DaemonSets of a ClusterDeviceConfig live in the driver namespace, so they are tracked
+	// by label instead of an owner reference.
+	if _, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		return nil
+	}
+
+	return ctrl.SetControllerReference(cr, ds, b.scheme)
+}
+
+// daemonSetLabels returns the labels of the DaemonSet and pods of the given resource for the
+// given kernel.
+func daemonSetLabels(cr examplecomv1alpha1.DeviceConfigObject, kernel string) map[string]string {
+	labels := managedLabels(cr)
+	labels[module.KernelVersionLabel] = kernel
+	return labels
+}
+
+// managedLabels returns the labels shared by all the DaemonSets and pods of the given
+// resource.
+func managedLabels(cr examplecomv1alpha1.DeviceConfigObject) map[string]string {
+	labels := map[string]string{
+		module.ModuleNameLabel: module.GetModuleName(cr),
+		module.RoleLabel:       module.ModuleLoaderRole,
+		ManagedByLabel:         ManagedByValue,
+	}
+	if cdc, ok := cr.(*examplecomv1alpha1.ClusterDeviceConfig); ok {
+		labels[examplecomv1alpha1.ClusterDeviceConfigOwnerLabel] = cdc.GetOwnerLabelValue()
+	}
+	return labels
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package native
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+
+	mappedKernel   = "5.14.0-70.13.1.el9_0.x86_64"
+	unmappedKernel = "5.15.0-48-generic"
+)
+
+var _ = Describe("NativeBackend", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	nfdLabels := map[string]string{"feature.node.kubernetes.io/pci-1da3.present": "true"}
+
+	makeNode := func(name, kernel string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nfdLabels},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{KernelVersion: kernel},
+			},
+		}
+	}
+
+	listDaemonSets := func(c client.Client, namespace string) []appsv1.DaemonSet {
+		dss := &appsv1.DaemonSetList{}
+		Expect(c.List(ctx, dss, client.InNamespace(namespace))).To(Succeed())
+		return dss.Items
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		s = runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(examplecomv1alpha1.AddToScheme(s)).To(Succeed())
+
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace, UID: "a-uid"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				DriverImage:   "quay.io/example/driver",
+				DriverVersion: "1.0.0",
+				ModuleName:    "example-driver",
+			},
+		}
+	})
+
+	Context("ReconcileModule", func() {
+		It("should create a DaemonSet for every mapped kernel of the selected nodes", func() {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				makeNode("node-1", mappedKernel),
+				makeNode("node-2", mappedKernel),
+				makeNode("node-3", unmappedKernel),
+			).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(Succeed())
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+
+			ds := dss[0]
+			Expect(ds.Name).To(Equal(GetDaemonSetName(dc, mappedKernel)))
+			Expect(metav1.IsControlledBy(&ds, dc)).To(BeTrue())
+			Expect(ds.Labels).To(Equal(map[string]string{
+				module.ModuleNameLabel:    module.GetModuleName(dc),
+				module.KernelVersionLabel: mappedKernel,
+				module.RoleLabel:          module.ModuleLoaderRole,
+				ManagedByLabel:            ManagedByValue,
+			}))
+			Expect(ds.Spec.Selector.MatchLabels).To(Equal(ds.Labels))
+			Expect(ds.Spec.Template.Labels).To(Equal(ds.Labels))
+
+			pod := ds.Spec.Template.Spec
+			Expect(pod.NodeSelector).To(HaveKeyWithValue(NodeKernelVersionLabel, mappedKernel))
+			Expect(pod.NodeSelector).To(HaveKeyWithValue("feature.node.kubernetes.io/pci-1da3.present", "true"))
+			Expect(pod.ServiceAccountName).To(Equal(config.DefaultDriverServiceAccount))
+			Expect(pod.Containers).To(HaveLen(1))
+			Expect(pod.Containers[0].Image).To(Equal("quay.io/example/driver:1.0.0-" + mappedKernel))
+			Expect(pod.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: loadOrderEnv, Value: "example-driver"}))
+			Expect(*pod.Containers[0].SecurityContext.Privileged).To(BeTrue())
+			Expect(pod.Containers[0].Lifecycle.PreStop.Exec.Command).To(Equal(unloadCommand))
+
+			for name, labelled := range map[string]bool{"node-1": true, "node-2": true, "node-3": false} {
+				n := &corev1.Node{}
+				Expect(c.Get(ctx, types.NamespacedName{Name: name}, n)).To(Succeed())
+				if labelled {
+					Expect(n.Labels).To(HaveKeyWithValue(NodeKernelVersionLabel, mappedKernel))
+				} else {
+					Expect(n.Labels).ToNot(HaveKey(NodeKernelVersionLabel))
+				}
+			}
+		})
+
+		It("should delete the DaemonSets of the kernels no longer running on the selected nodes", func() {
+			stale := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      GetDaemonSetName(dc, "5.14.0-70.el9.x86_64"),
+					Namespace: testNamespace,
+					Labels:    daemonSetLabels(dc, "5.14.0-70.el9.x86_64"),
+				},
+			}
+			other := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "another-daemonset", Namespace: testNamespace},
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				makeNode("node-1", mappedKernel),
+				stale,
+				other,
+			).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(Succeed())
+
+			names := []string{}
+			for _, ds := range listDaemonSets(c, testNamespace) {
+				names = append(names, ds.Name)
+			}
+			Expect(names).To(ConsistOf(GetDaemonSetName(dc, mappedKernel), "another-daemonset"))
+		})
+
+		It("should create the DaemonSets of a ClusterDeviceConfig in the driver namespace", func() {
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-device-config", UID: "a-uid"},
+				Spec:       dc.Spec,
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+
+			Expect(NewBackend(c, s, testDriverNamespace).ReconcileModule(ctx, cdc)).To(Succeed())
+
+			dss := listDaemonSets(c, testDriverNamespace)
+			Expect(dss).To(HaveLen(1))
+			Expect(dss[0].OwnerReferences).To(BeEmpty())
+			Expect(dss[0].Labels).To(HaveKeyWithValue(examplecomv1alpha1.ClusterDeviceConfigOwnerLabel, cdc.GetOwnerLabelValue()))
+		})
+
+		It("should return an error for a ClusterDeviceConfig without a driver namespace", func() {
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-device-config"},
+				Spec:       dc.Spec,
+			}
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, cdc)).To(MatchError(module.ErrNoDriverNamespace))
+		})
+
+		It("should load the kernel modules in dependency order and unload them in reverse", func() {
+			dc.Spec.KernelModules = []examplecomv1alpha1.KernelModule{
+				{Name: "example-rdma", DependsOn: []string{"example-crypto"}},
+				{Name: "example-crypto"},
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(Succeed())
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			env := dss[0].Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: loadOrderEnv, Value: "example-driver example-crypto example-rdma"},
+				corev1.EnvVar{Name: unloadOrderEnv, Value: "example-rdma example-crypto example-driver"},
+			))
+		})
+
+		It("should load the kernel module with its parameters", func() {
+			dc.Spec.ModuleParameters = []string{"debug=1", `name="a b"`}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(Succeed())
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			container := dss[0].Spec.Template.Spec.Containers[0]
+			Expect(container.Command).To(Equal(loadCommand))
+			Expect(container.Args).To(Equal([]string{containerName, "debug=1", `name="a b"`}))
+		})
+
+		It("should not deploy kernel modules with a dependency cycle", func() {
+			dc.Spec.KernelModules = []examplecomv1alpha1.KernelModule{
+				{Name: "example-rdma", DependsOn: []string{"example-crypto"}},
+				{Name: "example-crypto", DependsOn: []string{"example-rdma"}},
+			}
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(MatchError(ContainSubstring("dependency cycle")))
+			Expect(listDaemonSets(c, testNamespace)).To(BeEmpty())
+		})
+
+		It("should not deploy a DeviceConfig with node groups", func() {
+			dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{{Name: "sriov", NodeSelector: map[string]string{"pool": "sriov"}}}
+			c := fake.NewClientBuilder().WithScheme(s).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(MatchError(ErrNodeGroupsNotSupported))
+			Expect(listDaemonSets(c, testNamespace)).To(BeEmpty())
+		})
+
+		It("should not deploy a DeviceConfig without a module name", func() {
+			dc.Spec.ModuleName = ""
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+
+			Expect(NewBackend(c, s, "").ReconcileModule(ctx, dc)).To(MatchError(ErrNoModuleName))
+			Expect(listDaemonSets(c, testNamespace)).To(BeEmpty())
+		})
+	})
+
+	Context("DeleteModule", func() {
+		It("should delete the DaemonSets of the DeviceConfig", func() {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(
+				makeNode("node-1", mappedKernel),
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "another-daemonset", Namespace: testNamespace},
+				},
+			).Build()
+			b := NewBackend(c, s, "")
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+
+			Expect(b.DeleteModule(ctx, dc)).To(Succeed())
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			Expect(dss[0].Name).To(Equal("another-daemonset"))
+		})
+
+		It("should remove the kernel version label from the selected nodes", func() {
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+			b := NewBackend(c, s, "")
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+
+			Expect(b.DeleteModule(ctx, dc)).To(Succeed())
+
+			n := &corev1.Node{}
+			Expect(c.Get(ctx, client.ObjectKey{Name: "node-1"}, n)).To(Succeed())
+			Expect(n.Labels).ToNot(HaveKey(NodeKernelVersionLabel))
+		})
+	})
+
+	Context("ReleaseModule", func() {
+		var (
+			c client.Client
+			b *nativeBackend
+		)
+
+		BeforeEach(func() {
+			c = fake.NewClientBuilder().WithScheme(s).WithObjects(makeNode("node-1", mappedKernel)).Build()
+			b = NewBackend(c, s, "")
+			Expect(b.ReconcileModule(ctx, dc)).To(Succeed())
+		})
+
+		It("should leave the orphaned DaemonSets for a recreated DeviceConfig to adopt", func() {
+			dc.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyOrphan
+			Expect(b.ReleaseModule(ctx, dc)).To(Succeed())
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			Expect(dss[0].OwnerReferences).To(BeEmpty())
+			Expect(dss[0].Annotations).To(HaveKeyWithValue(examplecomv1alpha1.DeletionPolicyAnnotation, examplecomv1alpha1.DeletionPolicyOrphan))
+
+			recreated := dc.DeepCopy()
+			recreated.UID = "another-uid"
+			Expect(b.ReconcileModule(ctx, recreated)).To(Succeed())
+
+			dss = listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			Expect(metav1.IsControlledBy(&dss[0], recreated)).To(BeTrue())
+			Expect(dss[0].Annotations).ToNot(HaveKey(examplecomv1alpha1.DeletionPolicyAnnotation))
+		})
+
+		It("should not reconcile the retained DaemonSets anymore", func() {
+			dc.Spec.DeletionPolicy = examplecomv1alpha1.DeletionPolicyRetain
+			Expect(b.ReleaseModule(ctx, dc)).To(Succeed())
+
+			recreated := dc.DeepCopy()
+			recreated.UID = "another-uid"
+			Expect(b.ReconcileModule(ctx, recreated)).To(MatchError(module.ErrDriverRetained))
+
+			dss := listDaemonSets(c, testNamespace)
+			Expect(dss).To(HaveLen(1))
+			Expect(dss[0].OwnerReferences).To(BeEmpty())
+		})
+	})
+})
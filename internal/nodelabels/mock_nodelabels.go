@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: nodelabels.go
+
+// Package nodelabels is a generated GoMock package.
+package nodelabels
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockUpdater is a mock of Updater interface.
+type MockUpdater struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpdaterMockRecorder
+}
+
+// MockUpdaterMockRecorder is the mock recorder for MockUpdater.
+type MockUpdaterMockRecorder struct {
+	mock *MockUpdater
+}
+
+// NewMockUpdater creates a new mock instance.
+func NewMockUpdater(ctrl *gomock.Controller) *MockUpdater {
+	mock := &MockUpdater{ctrl: ctrl}
+	mock.recorder = &MockUpdaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpdater) EXPECT() *MockUpdaterMockRecorder {
+	return m.recorder
+}
+
+// RemoveComputedNodeLabels mocks base method.
+func (m *MockUpdater) RemoveComputedNodeLabels(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveComputedNodeLabels", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveComputedNodeLabels indicates an expected call of RemoveComputedNodeLabels.
+func (mr *MockUpdaterMockRecorder) RemoveComputedNodeLabels(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveComputedNodeLabels", reflect.TypeOf((*MockUpdater)(nil).RemoveComputedNodeLabels), ctx, cr)
+}
+
+// SyncComputedNodeLabels mocks base method.
+func (m *MockUpdater) SyncComputedNodeLabels(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncComputedNodeLabels", ctx, cr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SyncComputedNodeLabels indicates an expected call of SyncComputedNodeLabels.
+func (mr *MockUpdaterMockRecorder) SyncComputedNodeLabels(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncComputedNodeLabels", reflect.TypeOf((*MockUpdater)(nil).SyncComputedNodeLabels), ctx, cr)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodelabels
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+//go:generate mockgen -source=nodelabels.go -package=nodelabels -destination=mock_nodelabels.go
+
+// Updater manages the computed node labels which bridge the set-based DeviceConfig node
+// selectors to the map-based KMM Module selector, and the node group labels which assign the
+// selected nodes to the Module of their node group.
+type Updater interface {
+	SyncComputedNodeLabels(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	RemoveComputedNodeLabels(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+}
+
+type updater struct {
+	client client.Client
+}
+
+func NewUpdater(c client.Client) Updater {
+	return &updater{client: c}
+}
+
+// SyncComputedNodeLabels sets the computed node label on the nodes matching a set-based
+// selector and the node group label on the selected nodes of a DeviceConfig with node groups,
+// and removes them from the other nodes.
+func (u *updater) SyncComputedNodeLabels(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "nodelabels.SyncComputedNodeLabels", cr)
+	defer tracing.End(span, &err)
+
+	spec := cr.GetSpec()
+	if !cr.HasSetBasedSelector() && len(spec.NodeGroups) == 0 {
+		return u.RemoveComputedNodeLabels(ctx, cr)
+	}
+
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return fmt.Errorf("invalid node selector for %s: %w", cr.GetName(), err)
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := u.client.List(ctx, nodeList); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	computedKey := cr.GetComputedNodeLabel()
+	groupKey := cr.GetNodeGroupLabel()
+	for i := range nodeList.Items {
+		n := &nodeList.Items[i]
+
+		// An empty value removes the label.
+		desired := map[string]string{computedKey: "", groupKey: ""}
+		if selector.Matches(labels.Set(n.Labels)) {
+			if cr.HasSetBasedSelector() {
+				desired[computedKey] = examplecomv1alpha1.ComputedNodeLabelValue
+			}
+			if len(spec.NodeGroups) > 0 {
+				desired[groupKey] = spec.GetNodeGroup(labels.Set(n.Labels))
+			}
+		}
+
+		if err := u.patchNodeLabels(ctx, n, desired); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveComputedNodeLabels removes the computed node label and the node group label of the
+// given DeviceConfig from every node.
+func (u *updater) RemoveComputedNodeLabels(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "nodelabels.RemoveComputedNodeLabels", cr)
+	defer tracing.End(span, &err)
+
+	for _, key := range []string{cr.GetComputedNodeLabel(), cr.GetNodeGroupLabel()} {
+		nodeList := &v1.NodeList{}
+		if err := u.client.List(ctx, nodeList, client.HasLabels{key}); err != nil {
+			return fmt.Errorf("failed to list nodes: %w", err)
+		}
+
+		for i := range nodeList.Items {
+			if err := u.patchNodeLabels(ctx, &nodeList.Items[i], map[string]string{key: ""}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// patchNodeLabels sets the given labels on the node, removing the ones with an empty value.
+// The node is only patched if any of its labels differs.
+func (u *updater) patchNodeLabels(ctx context.Context, n *v1.Node, desired map[string]string) error {
+	patch := client.MergeFrom(n.DeepCopy())
+	changed := map[string]string{}
+	for key, value := range desired {
+		current, labelled := n.Labels[key]
+		switch {
+		case value == "" && labelled:
+			delete(n.Labels, key)
+		case value != "" && (!labelled || current != value):
+			if n.Labels == nil {
+				n.Labels = make(map[string]string)
+			}
+			n.Labels[key] = value
+		default:
+			continue
+		}
+		changed[key] = value
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := u.client.Patch(ctx, n, patch); err != nil {
+		return fmt.Errorf("failed to update the labels of node %s: %w", n.Name, err)
+	}
+
+	log.FromContext(ctx).Info("Updated computed node labels", "node", n.Name, "labels", changed)
+
+	return nil
+}
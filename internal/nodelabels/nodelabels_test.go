@@ -0,0 +1,179 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodelabels
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gomock "github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	mockClient "github.com/mresvanis/he-sample-operator/internal/client"
+)
+
+var _ = Describe("NodeLabelsUpdater", func() {
+	var (
+		ctx      context.Context
+		dc       *examplecomv1alpha1.DeviceConfig
+		labelKey string
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				NodeLabelSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpIn, Values: []string{"amd64", "arm64"}},
+						{Key: "maintenance", Operator: metav1.LabelSelectorOpDoesNotExist},
+					},
+				},
+			},
+		}
+		labelKey = dc.GetComputedNodeLabel()
+	})
+
+	Describe("SyncComputedNodeLabels", func() {
+		Context("with a set-based node selector", func() {
+			It("should label the matching nodes and unlabel the others", func() {
+				amd64 := makeTestNode("amd64", map[string]string{"kubernetes.io/arch": "amd64"})
+				arm64 := makeTestNode("arm64", map[string]string{"kubernetes.io/arch": "arm64"})
+				s390x := makeTestNode("s390x", map[string]string{"kubernetes.io/arch": "s390x", labelKey: "true"})
+				maintenance := makeTestNode("maintenance", map[string]string{
+					"kubernetes.io/arch": "amd64",
+					"maintenance":        "true",
+					labelKey:             "true",
+				})
+
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(amd64, arm64, s390x, maintenance).Build()
+				u := NewUpdater(c)
+
+				Expect(u.SyncComputedNodeLabels(ctx, dc)).To(Succeed())
+
+				Expect(getNodeLabels(c, "amd64")).To(HaveKeyWithValue(labelKey, "true"))
+				Expect(getNodeLabels(c, "arm64")).To(HaveKeyWithValue(labelKey, "true"))
+				Expect(getNodeLabels(c, "s390x")).ToNot(HaveKey(labelKey))
+				Expect(getNodeLabels(c, "maintenance")).ToNot(HaveKey(labelKey))
+			})
+		})
+
+		Context("with a map-based node selector", func() {
+			It("should remove any previously computed labels", func() {
+				dc.Spec.NodeLabelSelector = nil
+				dc.Spec.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+				amd64 := makeTestNode("amd64", map[string]string{"kubernetes.io/arch": "amd64", labelKey: "true"})
+
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(amd64).Build()
+				u := NewUpdater(c)
+
+				Expect(u.SyncComputedNodeLabels(ctx, dc)).To(Succeed())
+
+				Expect(getNodeLabels(c, "amd64")).ToNot(HaveKey(labelKey))
+			})
+		})
+
+		Context("with node groups", func() {
+			It("should label the selected nodes with the first node group they match", func() {
+				dc.Spec.NodeLabelSelector = nil
+				dc.Spec.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+				dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+					{Name: "sriov", NodeSelector: map[string]string{"sriov": "true"}},
+					{Name: "large", NodeSelector: map[string]string{"size": "large"}},
+				}
+				groupKey := dc.GetNodeGroupLabel()
+
+				sriov := makeTestNode("sriov", map[string]string{"kubernetes.io/arch": "amd64", "sriov": "true", "size": "large"})
+				large := makeTestNode("large", map[string]string{"kubernetes.io/arch": "amd64", "size": "large", groupKey: "sriov"})
+				other := makeTestNode("other", map[string]string{"kubernetes.io/arch": "amd64"})
+				arm64 := makeTestNode("arm64", map[string]string{"kubernetes.io/arch": "arm64", "sriov": "true", groupKey: "sriov"})
+
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(sriov, large, other, arm64).Build()
+				u := NewUpdater(c)
+
+				Expect(u.SyncComputedNodeLabels(ctx, dc)).To(Succeed())
+
+				Expect(getNodeLabels(c, "sriov")).To(HaveKeyWithValue(groupKey, "sriov"))
+				Expect(getNodeLabels(c, "large")).To(HaveKeyWithValue(groupKey, "large"))
+				Expect(getNodeLabels(c, "other")).To(HaveKeyWithValue(groupKey, examplecomv1alpha1.DefaultNodeGroup))
+				Expect(getNodeLabels(c, "arm64")).ToNot(HaveKey(groupKey))
+				Expect(getNodeLabels(c, "sriov")).ToNot(HaveKey(labelKey))
+			})
+		})
+
+		Context("with an invalid set-based node selector", func() {
+			It("should return an error", func() {
+				dc.Spec.NodeLabelSelector.MatchExpressions[0].Operator = "Unknown"
+
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+				u := NewUpdater(c)
+
+				Expect(u.SyncComputedNodeLabels(ctx, dc)).ToNot(Succeed())
+			})
+		})
+
+		Context("with a client List error", func() {
+			It("should return an error", func() {
+				c := mockClient.NewMockClient(gomock.NewController(GinkgoT()))
+				c.EXPECT().List(ctx, gomock.Any()).Return(apierrors.NewServiceUnavailable("Service unavailable"))
+				u := NewUpdater(c)
+
+				err := u.SyncComputedNodeLabels(ctx, dc)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Service unavailable"))
+			})
+		})
+	})
+
+	Describe("RemoveComputedNodeLabels", func() {
+		It("should only remove the DeviceConfig computed labels", func() {
+			otherKey := examplecomv1alpha1.ComputedNodeLabelPrefix + "other"
+			n := makeTestNode("node", map[string]string{labelKey: "true", dc.GetNodeGroupLabel(): "sriov", otherKey: "true"})
+
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(n).Build()
+			u := NewUpdater(c)
+
+			Expect(u.RemoveComputedNodeLabels(ctx, dc)).To(Succeed())
+
+			nodeLabels := getNodeLabels(c, "node")
+			Expect(nodeLabels).ToNot(HaveKey(labelKey))
+			Expect(nodeLabels).ToNot(HaveKey(dc.GetNodeGroupLabel()))
+			Expect(nodeLabels).To(HaveKey(otherKey))
+		})
+	})
+})
+
+func makeTestNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func getNodeLabels(c client.Client, name string) map[string]string {
+	n := &corev1.Node{}
+	ExpectWithOffset(1, c.Get(context.TODO(), client.ObjectKey{Name: name}, n)).To(Succeed())
+	return n.Labels
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeselector
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// Index keeps track of the nodes selected by each DeviceConfig and ClusterDeviceConfig. It is
+// fed by the Node, DeviceConfig and ClusterDeviceConfig informers, so that every event only
+// updates the entries it affects and conflict lookups never need to list any resources.
+//
+// ClusterDeviceConfigs are keyed by their name with an empty namespace, which no DeviceConfig
+// can have, so both kinds share the same key space.
+type Index struct {
+	mu sync.RWMutex
+
+	// nodes maps a node name to its labels.
+	nodes map[string]labels.Set
+	// configs maps a DeviceConfig to its selector, driver identity and selected nodes.
+	configs map[types.NamespacedName]*indexedConfig
+	// nodeConfigs maps a node name to the DeviceConfigs selecting it.
+	nodeConfigs map[string]map[types.NamespacedName]struct{}
+	// reportedConflicts maps a DeviceConfig to the peers it was last reported conflicting
+	// with, so that it can be requeued once one of them changes or is deleted.
+	reportedConflicts map[types.NamespacedName]map[types.NamespacedName]struct{}
+
+	synced []toolscache.InformerSynced
+}
+
+type indexedConfig struct {
+	// object is the last indexed DeviceConfig, whose selector is evaluated again by Resync.
+	object   examplecomv1alpha1.DeviceConfigObject
+	selector labels.Selector
+	// selectorKey identifies the selector, telling apart the Nothing and Everything
+	// selectors which have the same string representation.
+	selectorKey string
+	identity    []string
+	nodes       sets.String
+}
+
+func NewIndex() *Index {
+	return &Index{
+		nodes:       make(map[string]labels.Set),
+		configs:     make(map[types.NamespacedName]*indexedConfig),
+		nodeConfigs: make(map[string]map[types.NamespacedName]struct{}),
+
+		reportedConflicts: make(map[types.NamespacedName]map[types.NamespacedName]struct{}),
+	}
+}
+
+// SetupWithInformers registers the Index event handlers with the Node, DeviceConfig and
+// ClusterDeviceConfig informers of the given cache.
+func (idx *Index) SetupWithInformers(ctx context.Context, c cache.Cache) error {
+	nodeInformer, err := c.GetInformer(ctx, &v1.Node{})
+	if err != nil {
+		return err
+	}
+	nodeInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { idx.onNode(obj) },
+		UpdateFunc: func(_, obj interface{}) { idx.onNode(obj) },
+		DeleteFunc: func(obj interface{}) { idx.onNodeDeleted(obj) },
+	})
+
+	synced := []toolscache.InformerSynced{nodeInformer.HasSynced}
+	for _, kind := range []client.Object{&examplecomv1alpha1.DeviceConfig{}, &examplecomv1alpha1.ClusterDeviceConfig{}} {
+		dcInformer, err := c.GetInformer(ctx, kind)
+		if err != nil {
+			return err
+		}
+		dcInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { idx.onDeviceConfig(obj) },
+			UpdateFunc: func(_, obj interface{}) { idx.onDeviceConfig(obj) },
+			DeleteFunc: func(obj interface{}) { idx.onDeviceConfigDeleted(obj) },
+		})
+		synced = append(synced, dcInformer.HasSynced)
+	}
+
+	idx.mu.Lock()
+	idx.synced = append(idx.synced, synced...)
+	idx.mu.Unlock()
+
+	return nil
+}
+
+// HasSynced returns true once all the informers feeding the Index have synced.
+func (idx *Index) HasSynced() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, synced := range idx.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *Index) onNode(obj interface{}) {
+	if n, ok := obj.(*v1.Node); ok {
+		idx.UpsertNode(n)
+	}
+}
+
+func (idx *Index) onNodeDeleted(obj interface{}) {
+	if d, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	if n, ok := obj.(*v1.Node); ok {
+		idx.DeleteNode(n.Name)
+	}
+}
+
+func (idx *Index) onDeviceConfig(obj interface{}) {
+	if dc, ok := obj.(examplecomv1alpha1.DeviceConfigObject); ok {
+		idx.UpsertDeviceConfig(dc)
+	}
+}
+
+func (idx *Index) onDeviceConfigDeleted(obj interface{}) {
+	if d, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	if dc, ok := obj.(examplecomv1alpha1.DeviceConfigObject); ok {
+		idx.DeleteDeviceConfig(client.ObjectKeyFromObject(dc))
+	}
+}
+
+// UpsertNode re-evaluates the DeviceConfig selectors against the labels of the given node.
+func (idx *Index) UpsertNode(n *v1.Node) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	set := labels.Set(n.Labels)
+	if old, exists := idx.nodes[n.Name]; exists && labels.Equals(old, set) {
+		return
+	}
+	idx.nodes[n.Name] = set
+
+	for key, ic := range idx.configs {
+		if ic.selector.Matches(set) {
+			idx.link(key, n.Name)
+		} else {
+			idx.unlink(key, n.Name)
+		}
+	}
+}
+
+// DeleteNode removes the given node from the Index.
+func (idx *Index) DeleteNode(name string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for key := range idx.nodeConfigs[name] {
+		idx.configs[key].nodes.Delete(name)
+	}
+	delete(idx.nodeConfigs, name)
+	delete(idx.nodes, name)
+}
+
+// UpsertDeviceConfig re-evaluates the nodes selected by the given DeviceConfig.
+func (idx *Index) UpsertDeviceConfig(dc examplecomv1alpha1.DeviceConfigObject) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.upsertDeviceConfig(dc.DeepCopyObject().(examplecomv1alpha1.DeviceConfigObject))
+}
+
+// Resync re-evaluates the nodes selected by every indexed DeviceConfig, e.g. once the default
+// PCI vendor of the DeviceConfigs without a node selector has been reloaded.
+func (idx *Index) Resync() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, ic := range idx.configs {
+		idx.upsertDeviceConfig(ic.object)
+	}
+}
+
+func (idx *Index) upsertDeviceConfig(dc examplecomv1alpha1.DeviceConfigObject) {
+	key := client.ObjectKeyFromObject(dc)
+	selector, err := dc.GetLabelSelector()
+	selectorKey := "nothing"
+	if err != nil {
+		// An invalid selector does not select any node; the validator reports the error.
+		selector = labels.Nothing()
+	} else {
+		selectorKey = "selector:" + selector.String()
+	}
+	identity := dc.GetDriverIdentity()
+
+	ic, exists := idx.configs[key]
+	if exists && ic.selectorKey == selectorKey {
+		ic.object = dc
+		ic.identity = identity
+		return
+	}
+	if !exists {
+		ic = &indexedConfig{nodes: sets.NewString()}
+		idx.configs[key] = ic
+	}
+	ic.object = dc
+	ic.selector = selector
+	ic.selectorKey = selectorKey
+	ic.identity = identity
+
+	for name, set := range idx.nodes {
+		if selector.Matches(set) {
+			idx.link(key, name)
+		} else {
+			idx.unlink(key, name)
+		}
+	}
+}
+
+// DeleteDeviceConfig removes the given DeviceConfig from the Index.
+func (idx *Index) DeleteDeviceConfig(key types.NamespacedName) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	ic, exists := idx.configs[key]
+	if !exists {
+		return
+	}
+	for name := range ic.nodes {
+		delete(idx.nodeConfigs[name], key)
+	}
+	delete(idx.configs, key)
+	delete(idx.reportedConflicts, key)
+}
+
+// Conflicts returns the nodes shared by the given DeviceConfig with every other indexed
+// DeviceConfig which manages the same driver, keyed by the conflicting DeviceConfig.
+func (idx *Index) Conflicts(key types.NamespacedName) map[types.NamespacedName][]string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	ic, exists := idx.configs[key]
+	if !exists {
+		return nil
+	}
+
+	conflicts := make(map[types.NamespacedName][]string)
+	for _, name := range ic.nodes.List() {
+		for peer := range idx.nodeConfigs[name] {
+			if peer == key || !sharesDriverIdentity(ic.identity, idx.configs[peer].identity) {
+				continue
+			}
+			conflicts[peer] = append(conflicts[peer], name)
+		}
+	}
+	return conflicts
+}
+
+// SetReportedConflicts records the peers the given DeviceConfig has been reported conflicting
+// with, replacing the previously recorded ones.
+func (idx *Index) SetReportedConflicts(key types.NamespacedName, peers []types.NamespacedName) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if len(peers) == 0 {
+		delete(idx.reportedConflicts, key)
+		return
+	}
+	reported := make(map[types.NamespacedName]struct{}, len(peers))
+	for _, peer := range peers {
+		reported[peer] = struct{}{}
+	}
+	idx.reportedConflicts[key] = reported
+}
+
+// GetConflictingPeers returns the DeviceConfigs which have been reported conflicting with the
+// given one, sorted by key.
+func (idx *Index) GetConflictingPeers(key types.NamespacedName) []types.NamespacedName {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var peers []types.NamespacedName
+	for dc, reported := range idx.reportedConflicts {
+		if _, ok := reported[key]; ok {
+			peers = append(peers, dc)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].String() < peers[j].String()
+	})
+	return peers
+}
+
+func (idx *Index) link(key types.NamespacedName, node string) {
+	idx.configs[key].nodes.Insert(node)
+	if idx.nodeConfigs[node] == nil {
+		idx.nodeConfigs[node] = make(map[types.NamespacedName]struct{})
+	}
+	idx.nodeConfigs[node][key] = struct{}{}
+}
+
+func (idx *Index) unlink(key types.NamespacedName, node string) {
+	idx.configs[key].nodes.Delete(node)
+	delete(idx.nodeConfigs[node], key)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeselector
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+var _ = Describe("Index", func() {
+	var (
+		idx    *Index
+		nodeA  *corev1.Node
+		nodeB  *corev1.Node
+		dc     *examplecomv1alpha1.DeviceConfig
+		peer   *examplecomv1alpha1.DeviceConfig
+		dcKey  types.NamespacedName
+		peerNN types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		nodeA = makeTestNode(nodeNamed("node-a"), labelled(map[string]string{"pool": "a"}))
+		nodeB = makeTestNode(nodeNamed("node-b"), labelled(map[string]string{"pool": "b"}))
+		dc = makeTestDeviceConfig(named("dc"), nodeSelector(map[string]string{"pool": "a"}))
+		peer = makeTestDeviceConfig(named("peer"), nodeSelector(map[string]string{"pool": "a"}))
+		dcKey = types.NamespacedName{Name: "dc"}
+		peerNN = types.NamespacedName{Name: "peer"}
+
+		idx = makeTestIndex([]*corev1.Node{nodeA, nodeB}, dc, peer)
+	})
+
+	Describe("Conflicts", func() {
+		It("should return the overlapping nodes per conflicting DeviceConfig", func() {
+			Expect(idx.Conflicts(dcKey)).To(Equal(map[types.NamespacedName][]string{peerNN: {"node-a"}}))
+		})
+
+		It("should return nothing for a DeviceConfig that is not indexed", func() {
+			Expect(idx.Conflicts(types.NamespacedName{Name: "unknown"})).To(BeEmpty())
+		})
+	})
+
+	Describe("GetConflictingPeers", func() {
+		It("should return the DeviceConfigs last reported conflicting with the given one", func() {
+			idx.SetReportedConflicts(dcKey, []types.NamespacedName{peerNN})
+			Expect(idx.GetConflictingPeers(peerNN)).To(Equal([]types.NamespacedName{dcKey}))
+
+			idx.SetReportedConflicts(dcKey, nil)
+			Expect(idx.GetConflictingPeers(peerNN)).To(BeEmpty())
+		})
+
+		It("should forget the conflicts reported by a deleted DeviceConfig", func() {
+			idx.SetReportedConflicts(dcKey, []types.NamespacedName{peerNN})
+			idx.DeleteDeviceConfig(dcKey)
+			Expect(idx.GetConflictingPeers(peerNN)).To(BeEmpty())
+		})
+	})
+
+	Context("when a node is relabelled", func() {
+		It("should update the selected nodes incrementally", func() {
+			relabelled := nodeB.DeepCopy()
+			relabelled.Labels = map[string]string{"pool": "a"}
+			idx.UpsertNode(relabelled)
+
+			Expect(idx.Conflicts(dcKey)).To(Equal(map[types.NamespacedName][]string{peerNN: {"node-a", "node-b"}}))
+
+			relabelled = nodeA.DeepCopy()
+			relabelled.Labels = map[string]string{"pool": "b"}
+			idx.UpsertNode(relabelled)
+
+			Expect(idx.Conflicts(dcKey)).To(Equal(map[types.NamespacedName][]string{peerNN: {"node-b"}}))
+		})
+	})
+
+	Context("when a node is deleted", func() {
+		It("should no longer report it", func() {
+			idx.DeleteNode(nodeA.Name)
+
+			Expect(idx.Conflicts(dcKey)).To(BeEmpty())
+		})
+	})
+
+	Context("when a DeviceConfig selector changes", func() {
+		It("should re-evaluate its selected nodes", func() {
+			updated := peer.DeepCopy()
+			updated.Spec.NodeSelector = map[string]string{"pool": "b"}
+			idx.UpsertDeviceConfig(updated)
+
+			Expect(idx.Conflicts(dcKey)).To(BeEmpty())
+		})
+	})
+
+	Context("when the default PCI vendor changes", func() {
+		AfterEach(func() {
+			examplecomv1alpha1.DefaultPCIVendorID = func() string {
+				return examplecomv1alpha1.ExamplePCIVendorID
+			}
+		})
+
+		It("should re-evaluate the DeviceConfigs without a node selector on resync", func() {
+			nvidia := map[string]string{"feature.node.kubernetes.io/pci-10de.present": "true"}
+			idx.UpsertNode(makeTestNode(nodeNamed("node-c"), labelled(nvidia)))
+			idx.UpsertDeviceConfig(makeTestDeviceConfig(named("dc")))
+			idx.UpsertDeviceConfig(makeTestDeviceConfig(named("peer")))
+			Expect(idx.Conflicts(dcKey)).To(BeEmpty())
+
+			examplecomv1alpha1.DefaultPCIVendorID = func() string { return "10de" }
+			idx.Resync()
+
+			Expect(idx.Conflicts(dcKey)).To(Equal(map[types.NamespacedName][]string{peerNN: {"node-c"}}))
+		})
+	})
+
+	Context("when a DeviceConfig is deleted", func() {
+		It("should no longer report it as a conflict", func() {
+			idx.DeleteDeviceConfig(peerNN)
+
+			Expect(idx.Conflicts(dcKey)).To(BeEmpty())
+		})
+	})
+
+	Context("with a ClusterDeviceConfig", func() {
+		It("should report conflicts across both kinds", func() {
+			nsDC := makeTestDeviceConfig(named("dc"), nodeSelector(map[string]string{"pool": "b"}))
+			nsDC.Namespace = "a-namespace"
+			idx.UpsertDeviceConfig(nsDC)
+			cdc := &examplecomv1alpha1.ClusterDeviceConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       examplecomv1alpha1.DeviceConfigSpec{NodeSelector: map[string]string{"pool": "b"}},
+			}
+			idx.UpsertDeviceConfig(cdc)
+
+			nsKey := types.NamespacedName{Namespace: "a-namespace", Name: "dc"}
+			cdcKey := types.NamespacedName{Name: "cluster"}
+			Expect(idx.Conflicts(nsKey)).To(Equal(map[types.NamespacedName][]string{cdcKey: {"node-b"}}))
+			Expect(idx.Conflicts(cdcKey)).To(Equal(map[types.NamespacedName][]string{nsKey: {"node-b"}}))
+		})
+	})
+})
+
+const (
+	benchmarkNodes   = 2000
+	benchmarkConfigs = 200
+)
+
+func makeBenchmarkCluster() ([]*corev1.Node, []*examplecomv1alpha1.DeviceConfig) {
+	nodes := make([]*corev1.Node, 0, benchmarkNodes)
+	for i := 0; i < benchmarkNodes; i++ {
+		nodes = append(nodes, makeTestNode(
+			nodeNamed(fmt.Sprintf("node-%d", i)),
+			labelled(map[string]string{"pool": fmt.Sprintf("pool-%d", i%benchmarkConfigs)}),
+		))
+	}
+
+	dcs := make([]*examplecomv1alpha1.DeviceConfig, 0, benchmarkConfigs)
+	for i := 0; i < benchmarkConfigs; i++ {
+		dcs = append(dcs, makeTestDeviceConfig(
+			named(fmt.Sprintf("dc-%d", i)),
+			nodeSelector(map[string]string{"pool": fmt.Sprintf("pool-%d", i)}),
+		))
+	}
+
+	return nodes, dcs
+}
+
+// fullScanConflicts evaluates every DeviceConfig selector against every node, the way the
+// validator used to for each reconcile.
+func fullScanConflicts(nodes []*corev1.Node, dcs []*examplecomv1alpha1.DeviceConfig) bool {
+	seen := make(map[string]bool)
+	for _, dc := range dcs {
+		selector := labels.SelectorFromSet(dc.GetNodeSelector())
+		for _, n := range nodes {
+			if !selector.Matches(labels.Set(n.Labels)) {
+				continue
+			}
+			if seen[n.Name] {
+				return true
+			}
+			seen[n.Name] = true
+		}
+	}
+	return false
+}
+
+func BenchmarkFullScanConflicts(b *testing.B) {
+	nodes, dcs := makeBenchmarkCluster()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fullScanConflicts(nodes, dcs)
+	}
+}
+
+func BenchmarkIndexConflicts(b *testing.B) {
+	nodes, dcs := makeBenchmarkCluster()
+	idx := makeTestIndex(nodes, dcs...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dc := dcs[i%benchmarkConfigs]
+		idx.UpsertDeviceConfig(dc)
+		idx.Conflicts(types.NamespacedName{Namespace: dc.Namespace, Name: dc.Name})
+	}
+}
+
+func BenchmarkIndexNodeUpdate(b *testing.B) {
+	nodes, dcs := makeBenchmarkCluster()
+	idx := makeTestIndex(nodes, dcs...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Every pass over the nodes moves each of them to the next pool, so that every update
+		// re-keys the node instead of being skipped as unchanged.
+		n := nodes[i%benchmarkNodes].DeepCopy()
+		n.Labels = map[string]string{"pool": fmt.Sprintf("pool-%d", (i+i/benchmarkNodes+1)%benchmarkConfigs)}
+		idx.UpsertNode(n)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	types "k8s.io/apimachinery/pkg/types"
 )
 
 // MockValidator is a mock of Validator interface.
@@ -36,7 +37,7 @@ func (m *MockValidator) EXPECT() *MockValidatorMockRecorder {
 }
 
 // CheckDeviceConfigForConflictingNodeSelector mocks base method.
-func (m *MockValidator) CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr *v1alpha1.DeviceConfig) error {
+func (m *MockValidator) CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr v1alpha1.DeviceConfigObject) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CheckDeviceConfigForConflictingNodeSelector", ctx, cr)
 	ret0, _ := ret[0].(error)
@@ -48,3 +49,17 @@ func (mr *MockValidatorMockRecorder) CheckDeviceConfigForConflictingNodeSelector
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDeviceConfigForConflictingNodeSelector", reflect.TypeOf((*MockValidator)(nil).CheckDeviceConfigForConflictingNodeSelector), ctx, cr)
 }
+
+// GetConflictingPeers mocks base method.
+func (m *MockValidator) GetConflictingPeers(key types.NamespacedName) []types.NamespacedName {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConflictingPeers", key)
+	ret0, _ := ret[0].([]types.NamespacedName)
+	return ret0
+}
+
+// GetConflictingPeers indicates an expected call of GetConflictingPeers.
+func (mr *MockValidatorMockRecorder) GetConflictingPeers(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConflictingPeers", reflect.TypeOf((*MockValidator)(nil).GetConflictingPeers), key)
+}
@@ -15,79 +15,142 @@ package nodeselector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
+const (
+	// MaxReportedConflicts is the maximum number of conflicting DeviceConfigs reported.
+	MaxReportedConflicts = 10
+	// MaxReportedConflictNodes is the maximum number of overlapping nodes reported per
+	// conflicting DeviceConfig.
+	MaxReportedConflictNodes = 10
+)
+
+// ErrIndexNotSynced is returned while the informers feeding the Index are still syncing.
+var ErrIndexNotSynced = errors.New("node selector index has not synced yet")
+
 //go:generate mockgen -source=nodeselector.go -package=nodeselector -destination=mock_nodeselector.go
 
 type Validator interface {
-	CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error
+	CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) error
+	GetConflictingPeers(key types.NamespacedName) []types.NamespacedName
 }
 
 type validator struct {
-	client client.Client
+	index *Index
 }
 
-func NewValidator(c client.Client) *validator {
-	return &validator{client: c}
+func NewValidator(idx *Index) *validator {
+	return &validator{index: idx}
 }
 
-func (nsv *validator) CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) error {
-	dcs := &examplecomv1alpha1.DeviceConfigList{}
-	err := nsv.client.List(ctx, dcs)
-	if err != nil {
-		return err
+func (nsv *validator) CheckDeviceConfigForConflictingNodeSelector(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (err error) {
+	ctx, span := tracing.Start(ctx, "nodeselector.CheckDeviceConfigForConflictingNodeSelector", cr)
+	defer tracing.End(span, &err)
+
+	if !nsv.index.HasSynced() {
+		return ErrIndexNotSynced
 	}
 
-	names := []string{}
-	for _, dc := range dcs.Items {
-		nodeList, err := nsv.getDeviceConfigSelectedNodes(ctx, &dc)
-		if err != nil {
-			return err
-		}
+	if _, err := cr.GetLabelSelector(); err != nil {
+		return fmt.Errorf("invalid node selector for resource %s: %w", cr.GetName(), err)
+	}
 
-		for _, n := range nodeList.Items {
-			names = append(names, n.Name)
-		}
+	// The informers may lag behind the DeviceConfig being reconciled, so make sure the
+	// index reflects its latest spec.
+	nsv.index.UpsertDeviceConfig(cr)
+
+	key := types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}
+	conflicts := nsv.index.Conflicts(key)
+
+	peers := make([]types.NamespacedName, 0, len(conflicts))
+	for peer := range conflicts {
+		peers = append(peers, peer)
 	}
+	nsv.index.SetReportedConflicts(key, peers)
 
-	if containsDuplicates(names) {
-		return fmt.Errorf("conflicting DeviceConfig NodeSelectors found for resource: %s", cr.Name)
+	if len(conflicts) > 0 {
+		return newConflictError(cr, conflicts)
 	}
 
 	return nil
 }
 
-func (nsv *validator) getDeviceConfigSelectedNodes(ctx context.Context, cr *examplecomv1alpha1.DeviceConfig) (*v1.NodeList, error) {
-	nodeList := &v1.NodeList{}
-
-	if cr.Spec.NodeSelector == nil {
-		cr.Spec.NodeSelector = cr.GetNodeSelector()
-	}
+// GetConflictingPeers returns the DeviceConfigs last reported conflicting with the given one,
+// which must be validated again once it changes or is deleted.
+func (nsv *validator) GetConflictingPeers(key types.NamespacedName) []types.NamespacedName {
+	return nsv.index.GetConflictingPeers(key)
+}
 
-	selector := labels.Set(cr.Spec.NodeSelector).AsSelector()
+// ConflictError is returned when a DeviceConfig selects nodes which are already selected by
+// other DeviceConfigs managing the same driver.
+type ConflictError struct {
+	Name string
+	// Conflicts lists the conflicting DeviceConfigs, truncated to MaxReportedConflicts
+	// entries of at most MaxReportedConflictNodes nodes each.
+	Conflicts []examplecomv1alpha1.DeviceConfigConflict
+}
 
-	opts := []client.ListOption{
-		client.MatchingLabelsSelector{Selector: selector},
+func newConflictError(cr examplecomv1alpha1.DeviceConfigObject, conflicts map[types.NamespacedName][]string) *ConflictError {
+	peers := make([]types.NamespacedName, 0, len(conflicts))
+	for peer := range conflicts {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].String() < peers[j].String()
+	})
+	if len(peers) > MaxReportedConflicts {
+		peers = peers[:MaxReportedConflicts]
 	}
-	err := nsv.client.List(ctx, nodeList, opts...)
 
-	return nodeList, err
+	e := &ConflictError{Name: cr.GetName()}
+	for _, peer := range peers {
+		nodes := conflicts[peer]
+		c := examplecomv1alpha1.DeviceConfigConflict{
+			Namespace: peer.Namespace,
+			Name:      peer.Name,
+			NodeCount: len(nodes),
+		}
+		if len(nodes) > MaxReportedConflictNodes {
+			nodes = nodes[:MaxReportedConflictNodes]
+		}
+		c.Nodes = nodes
+		e.Conflicts = append(e.Conflicts, c)
+	}
+	return e
 }
 
-func containsDuplicates(arr []string) bool {
-	visited := make(map[string]bool, 0)
-	for _, e := range arr {
-		if _, exists := visited[e]; exists {
-			return true
+func (e *ConflictError) Error() string {
+	peers := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		peer := c.Name
+		if c.Namespace != "" {
+			peer = c.Namespace + "/" + c.Name
+		}
+		nodes := strings.Join(c.Nodes, ", ")
+		if more := c.NodeCount - len(c.Nodes); more > 0 {
+			nodes = fmt.Sprintf("%s and %d more", nodes, more)
 		}
-		visited[e] = true
+		peers = append(peers, fmt.Sprintf("%s on nodes %s", peer, nodes))
+	}
+	return fmt.Sprintf("conflicting DeviceConfig NodeSelectors found for resource: %s: %s", e.Name, strings.Join(peers, "; "))
+}
+
+// sharesDriverIdentity returns true if both driver identities refer to the same kernel module
+// or device. A DeviceConfig which declares neither is considered to share its identity with
+// every other DeviceConfig.
+func sharesDriverIdentity(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
 	}
-	return false
+	return sets.NewString(a...).HasAny(b...)
 }
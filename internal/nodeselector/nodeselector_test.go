@@ -15,19 +15,20 @@ package nodeselector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes/scheme"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
-	gomock "github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
-	"github.com/mresvanis/he-sample-operator/internal/client"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 )
 
 const (
@@ -40,117 +41,236 @@ var _ = Describe("NodeSelectorValidator", func() {
 		dc := makeTestDeviceConfig(nodeSelector(node.Labels))
 		ctx := context.TODO()
 
-		Context("with a client listing error", func() {
-			var (
-				gCtrl *gomock.Controller
-				c     *client.MockClient
-				nsv   *validator
-			)
+		Context("with an index that has not synced yet", func() {
+			It("should return an error", func() {
+				idx := NewIndex()
+				idx.synced = append(idx.synced, func() bool { return false })
+				nsv := NewValidator(idx)
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, dc)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("has not synced yet"))
+			})
+		})
 
-			nonconflictingDC := makeTestDeviceConfig(named("nonconflictingDC"))
+		Context("with an invalid/conflicting nodeSelector", func() {
+			It("should return an error", func() {
+				conflictingDC := makeTestDeviceConfig(named("conflictingDC"), nodeSelector(node.Labels))
 
-			s := scheme.Scheme
-			Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, dc, conflictingDC))
 
-			BeforeEach(func() {
-				gCtrl = gomock.NewController(GinkgoT())
-				c = client.NewMockClient(gCtrl)
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, conflictingDC)
+				Expect(err).To(HaveOccurred())
+			})
+		})
 
-				nsv = NewValidator(c)
+		Context("with a conflicting nodeSelector and tracing enabled", func() {
+			var exporter *tracetest.InMemoryExporter
 
-				gomock.InOrder(
-					c.EXPECT().
-						List(ctx, gomock.Any()).
-						Return(apierrors.NewServiceUnavailable("Service unavailable")),
-				)
+			BeforeEach(func() {
+				exporter = tracetest.NewInMemoryExporter()
+				tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
 			})
 
-			It("should not requeue or return an error", func() {
-				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, nonconflictingDC)
+			AfterEach(func() {
+				tracing.SetTracerProvider(nil)
+			})
+
+			It("should record the conflict on its span", func() {
+				conflictingDC := makeTestDeviceConfig(named("conflictingDC"), nodeSelector(node.Labels))
+
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, dc, conflictingDC))
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, conflictingDC)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("Service unavailable"))
+
+				spans := exporter.GetSpans()
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Name).To(Equal("nodeselector.CheckDeviceConfigForConflictingNodeSelector"))
+				Expect(spans[0].Status.Code).To(Equal(codes.Error))
 			})
 		})
 
-		Context("with an invalid/conflicting nodeSelector", func() {
-			It("should return an error", func() {
+		Context("with a conflicting nodeSelector", func() {
+			It("should return a ConflictError naming the peers and overlapping nodes", func() {
+				nsDC := makeTestDeviceConfig(named("nic"), nodeSelector(node.Labels))
+				nsDC.Namespace = "other-namespace"
 				conflictingDC := makeTestDeviceConfig(named("conflictingDC"), nodeSelector(node.Labels))
 
-				s := scheme.Scheme
-				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, dc, nsDC, conflictingDC))
 
-				c := fake.
-					NewClientBuilder().
-					WithScheme(s).
-					WithObjects(node, dc, conflictingDC).
-					Build()
-				nsv := NewValidator(c)
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, conflictingDC)
 
-				err := nsv.CheckDeviceConfigForConflictingNodeSelector(context.TODO(), conflictingDC)
-				Expect(err).To(HaveOccurred())
+				var conflictErr *ConflictError
+				Expect(errors.As(err, &conflictErr)).To(BeTrue())
+				Expect(conflictErr.Conflicts).To(Equal([]examplecomv1alpha1.DeviceConfigConflict{
+					{Name: "test", Nodes: []string{testNodeName}, NodeCount: 1},
+					{Namespace: "other-namespace", Name: "nic", Nodes: []string{testNodeName}, NodeCount: 1},
+				}))
+				Expect(err.Error()).To(ContainSubstring("other-namespace/nic on nodes test-node"))
 			})
 		})
 
-		Context("with a valid nodeSelector", func() {
-			It("should not return an error", func() {
-				nonconflictingDC := makeTestDeviceConfig(named("nonconflictingDC"))
+		Context("with more conflicts than can be reported", func() {
+			It("should truncate the reported conflicts and nodes", func() {
+				nodes := []*corev1.Node{}
+				for i := 0; i < MaxReportedConflictNodes+5; i++ {
+					nodes = append(nodes, makeTestNode(nodeNamed(fmt.Sprintf("node-%02d", i)), labelled(node.Labels)))
+				}
+				dcs := []*examplecomv1alpha1.DeviceConfig{}
+				for i := 0; i < MaxReportedConflicts+5; i++ {
+					dcs = append(dcs, makeTestDeviceConfig(named(fmt.Sprintf("dc-%02d", i)), nodeSelector(node.Labels)))
+				}
+
+				nsv := NewValidator(makeTestIndex(nodes, dcs...))
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, dcs[0])
+
+				var conflictErr *ConflictError
+				Expect(errors.As(err, &conflictErr)).To(BeTrue())
+				Expect(conflictErr.Conflicts).To(HaveLen(MaxReportedConflicts))
+				Expect(conflictErr.Conflicts[0].Nodes).To(HaveLen(MaxReportedConflictNodes))
+				Expect(conflictErr.Conflicts[0].NodeCount).To(Equal(MaxReportedConflictNodes + 5))
+				Expect(err.Error()).To(ContainSubstring("and 5 more"))
+			})
+		})
 
-				s := scheme.Scheme
-				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+		Context("with a nodeSelector overlapping a DeviceConfig of a different kernel module", func() {
+			It("should not return an error", func() {
+				nicDC := makeTestDeviceConfig(named("nic"), nodeSelector(node.Labels), moduleName("nic"))
+				accelDC := makeTestDeviceConfig(named("accel"), nodeSelector(node.Labels), moduleName("accel"))
 
-				c := fake.
-					NewClientBuilder().
-					WithScheme(s).
-					WithObjects(node, dc, nonconflictingDC).
-					Build()
-				nsv := NewValidator(c)
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, nicDC, accelDC))
 
-				err := nsv.CheckDeviceConfigForConflictingNodeSelector(context.TODO(), nonconflictingDC)
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, accelDC)
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
-	})
 
-	Describe("getDeviceConfigSelectedNodes", func() {
+		Context("with a nodeSelector overlapping a DeviceConfig of the same kernel module", func() {
+			It("should return an error", func() {
+				nicDC := makeTestDeviceConfig(named("nic"), nodeSelector(node.Labels), moduleName("nic"))
+				otherNicDC := makeTestDeviceConfig(named("other-nic"), nodeSelector(node.Labels), moduleName("nic"))
+
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, nicDC, otherNicDC))
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, otherNicDC)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with set-based nodeSelectors", func() {
+			var nodes []*corev1.Node
+
+			BeforeEach(func() {
+				nodes = []*corev1.Node{
+					makeTestNode(nodeNamed("amd64"), labelled(map[string]string{"kubernetes.io/arch": "amd64"})),
+					makeTestNode(nodeNamed("arm64"), labelled(map[string]string{"kubernetes.io/arch": "arm64"})),
+				}
+			})
+
+			It("should not return an error for disjoint expressions", func() {
+				amd64DC := makeTestDeviceConfig(named("amd64"), nodeExpressions(metav1.LabelSelectorRequirement{
+					Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpIn, Values: []string{"amd64"},
+				}))
+				otherDC := makeTestDeviceConfig(named("other"), nodeExpressions(metav1.LabelSelectorRequirement{
+					Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"amd64"},
+				}))
+
+				nsv := NewValidator(makeTestIndex(nodes, amd64DC, otherDC))
+
+				Expect(nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, otherDC)).To(Succeed())
+			})
+
+			It("should return an error for overlapping expressions", func() {
+				amd64DC := makeTestDeviceConfig(named("amd64"), nodeExpressions(metav1.LabelSelectorRequirement{
+					Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpIn, Values: []string{"amd64"},
+				}))
+				anyDC := makeTestDeviceConfig(named("any"), nodeExpressions(metav1.LabelSelectorRequirement{
+					Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpExists,
+				}))
+
+				nsv := NewValidator(makeTestIndex(nodes, amd64DC, anyDC))
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, anyDC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("amd64 on nodes amd64"))
+			})
+
+			It("should return an error for an invalid expression", func() {
+				invalidDC := makeTestDeviceConfig(named("invalid"), nodeExpressions(metav1.LabelSelectorRequirement{
+					Key: "kubernetes.io/arch", Operator: metav1.LabelSelectorOpIn,
+				}))
+
+				nsv := NewValidator(makeTestIndex(nodes))
+
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, invalidDC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid node selector"))
+			})
+		})
+
 		Context("with a valid nodeSelector", func() {
-			It("should returned the selected nodes", func() {
-				node := makeTestNode(labelled(map[string]string{"matching": "label"}))
-				dc := makeTestDeviceConfig(nodeSelector(node.Labels))
+			It("should not return an error", func() {
+				nonconflictingDC := makeTestDeviceConfig(named("nonconflictingDC"))
+
+				nsv := NewValidator(makeTestIndex([]*corev1.Node{node}, dc, nonconflictingDC))
 
-				s := scheme.Scheme
-				Expect(examplecomv1alpha1.AddToScheme(s)).ToNot(HaveOccurred())
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, nonconflictingDC)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
 
-				c := fake.
-					NewClientBuilder().
-					WithScheme(s).
-					WithObjects(node, dc).
-					Build()
-				nsv := NewValidator(c)
+		Context("with a DeviceConfig whose nodeSelector changed since it was indexed", func() {
+			It("should validate its latest nodeSelector", func() {
+				conflictingDC := makeTestDeviceConfig(named("conflictingDC"), nodeSelector(node.Labels))
+				idx := makeTestIndex([]*corev1.Node{node}, dc, conflictingDC)
+				nsv := NewValidator(idx)
 
-				nodeList, err := nsv.getDeviceConfigSelectedNodes(context.TODO(), dc)
+				updated := conflictingDC.DeepCopy()
+				updated.Spec.NodeSelector = map[string]string{"other": "label"}
 
+				err := nsv.CheckDeviceConfigForConflictingNodeSelector(ctx, updated)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(nodeList.Items).To(HaveLen(1))
-				Expect(testNodeName).To(Equal(nodeList.Items[0].Name))
 			})
 		})
 	})
 })
 
-var _ = Describe("ContainsDuplicates", func() {
-	Context("with a list without duplicates", func() {
+var _ = Describe("SharesDriverIdentity", func() {
+	Context("with DeviceConfigs managing different kernel modules", func() {
 		It("should return false", func() {
-			arr := []string{"apple", "orange"}
+			a := makeTestDeviceConfig(moduleName("nic"))
+			b := makeTestDeviceConfig(moduleName("accel"))
+
+			Expect(sharesDriverIdentity(a.GetDriverIdentity(), b.GetDriverIdentity())).To(BeFalse())
+		})
+	})
+
+	Context("with DeviceConfigs managing the same kernel module", func() {
+		It("should return true", func() {
+			a := makeTestDeviceConfig(moduleName("nic"))
+			b := makeTestDeviceConfig(moduleName("nic"))
 
-			Expect(containsDuplicates(arr)).To(BeFalse())
+			Expect(sharesDriverIdentity(a.GetDriverIdentity(), b.GetDriverIdentity())).To(BeTrue())
 		})
 	})
 
-	Context("with a list with duplicates", func() {
+	Context("with DeviceConfigs declaring overlapping device IDs", func() {
 		It("should return true", func() {
-			arr := []string{"apple", "apple"}
+			a := makeTestDeviceConfig(moduleName("nic"), deviceIDs("1da3:0001"))
+			b := makeTestDeviceConfig(moduleName("nic-ng"), deviceIDs("1DA3:0001", "1da3:0002"))
 
-			Expect(containsDuplicates(arr)).To(BeTrue())
+			Expect(sharesDriverIdentity(a.GetDriverIdentity(), b.GetDriverIdentity())).To(BeTrue())
+		})
+	})
+
+	Context("with a DeviceConfig that declares no driver identity", func() {
+		It("should return true", func() {
+			a := makeTestDeviceConfig()
+			b := makeTestDeviceConfig(moduleName("accel"))
+
+			Expect(sharesDriverIdentity(a.GetDriverIdentity(), b.GetDriverIdentity())).To(BeTrue())
 		})
 	})
 })
@@ -161,6 +281,12 @@ func labelled(labels map[string]string) nodeOptions {
 	}
 }
 
+func nodeNamed(name string) nodeOptions {
+	return func(n *corev1.Node) {
+		n.ObjectMeta.Name = name
+	}
+}
+
 type nodeOptions func(*corev1.Node)
 
 func makeTestNode(opts ...nodeOptions) *corev1.Node {
@@ -187,6 +313,24 @@ func nodeSelector(labels map[string]string) deviceConfigOptions {
 	}
 }
 
+func nodeExpressions(requirements ...metav1.LabelSelectorRequirement) deviceConfigOptions {
+	return func(c *examplecomv1alpha1.DeviceConfig) {
+		c.Spec.NodeLabelSelector = &metav1.LabelSelector{MatchExpressions: requirements}
+	}
+}
+
+func moduleName(name string) deviceConfigOptions {
+	return func(c *examplecomv1alpha1.DeviceConfig) {
+		c.Spec.ModuleName = name
+	}
+}
+
+func deviceIDs(ids ...string) deviceConfigOptions {
+	return func(c *examplecomv1alpha1.DeviceConfig) {
+		c.Spec.DeviceIDs = ids
+	}
+}
+
 type deviceConfigOptions func(*examplecomv1alpha1.DeviceConfig)
 
 func makeTestDeviceConfig(opts ...deviceConfigOptions) *examplecomv1alpha1.DeviceConfig {
@@ -204,3 +348,14 @@ func makeTestDeviceConfig(opts ...deviceConfigOptions) *examplecomv1alpha1.Devic
 	}
 	return c
 }
+
+func makeTestIndex(nodes []*corev1.Node, dcs ...*examplecomv1alpha1.DeviceConfig) *Index {
+	idx := NewIndex()
+	for _, n := range nodes {
+		idx.UpsertNode(n)
+	}
+	for _, dc := range dcs {
+		idx.UpsertDeviceConfig(dc)
+	}
+	return idx
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: preflight.go
+
+// Package preflight is a generated GoMock package.
+package preflight
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockValidator is a mock of Validator interface.
+type MockValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockValidatorMockRecorder
+}
+
+// MockValidatorMockRecorder is the mock recorder for MockValidator.
+type MockValidatorMockRecorder struct {
+	mock *MockValidator
+}
+
+// NewMockValidator creates a new mock instance.
+func NewMockValidator(ctrl *gomock.Controller) *MockValidator {
+	mock := &MockValidator{ctrl: ctrl}
+	mock.recorder = &MockValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValidator) EXPECT() *MockValidatorMockRecorder {
+	return m.recorder
+}
+
+// ValidateDriverImages mocks base method.
+func (m *MockValidator) ValidateDriverImages(ctx context.Context, cr v1alpha1.DeviceConfigObject) (*v1alpha1.PreflightStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateDriverImages", ctx, cr)
+	ret0, _ := ret[0].(*v1alpha1.PreflightStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateDriverImages indicates an expected call of ValidateDriverImages.
+func (mr *MockValidatorMockRecorder) ValidateDriverImages(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateDriverImages", reflect.TypeOf((*MockValidator)(nil).ValidateDriverImages), ctx, cr)
+}
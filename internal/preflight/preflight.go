@@ -0,0 +1,130 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/registry"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+//go:generate mockgen -source=preflight.go -package=preflight -destination=mock_preflight.go
+
+// Validator validates that the driver image of a DeviceConfig is available for every kernel
+// of its selected nodes.
+type Validator interface {
+	ValidateDriverImages(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (*examplecomv1alpha1.PreflightStatus, error)
+}
+
+type validator struct {
+	client   client.Client
+	registry registry.Registry
+}
+
+func NewValidator(c client.Client, r registry.Registry) Validator {
+	return &validator{client: c, registry: r}
+}
+
+// ValidateDriverImages looks up the driver image of every distinct kernel among the selected
+// nodes which is matched by a kernel mapping. A passed validation is reused as long as the
+// driver image, version and kernels do not change, so that the registry is not queried on
+// every reconciliation.
+func (v *validator) ValidateDriverImages(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ *examplecomv1alpha1.PreflightStatus, err error) {
+	ctx, span := tracing.Start(ctx, "preflight.ValidateDriverImages", cr)
+	defer tracing.End(span, &err)
+
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector for %s: %w", cr.GetName(), err)
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := v.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	images := make(map[string]string)
+	for _, n := range nodeList.Items {
+		kernel := n.Status.NodeInfo.KernelVersion
+		if kernel == "" || !selector.Matches(labels.Set(n.Labels)) {
+			continue
+		}
+		if image, ok := module.GetKernelDriverImage(cr, kernel); ok {
+			images[kernel] = image
+		}
+	}
+
+	spec := cr.GetSpec()
+	if previous := cr.GetStatus().Preflight; previous != nil && previous.Passed &&
+		previous.DriverImage == spec.DriverImage && previous.DriverVersion == spec.DriverVersion &&
+		sameKernels(previous, images) {
+		return previous, nil
+	}
+
+	status := &examplecomv1alpha1.PreflightStatus{
+		DriverImage:   spec.DriverImage,
+		DriverVersion: spec.DriverVersion,
+		Passed:        true,
+	}
+	for _, kernel := range sets.StringKeySet(images).List() {
+		kp := examplecomv1alpha1.KernelPreflight{KernelVersion: kernel, Image: images[kernel]}
+
+		exists, err := v.registry.ImageExists(ctx, kp.Image)
+		switch {
+		case err != nil:
+			kp.Message = err.Error()
+		case !exists:
+			kp.Message = "image not found"
+		default:
+			kp.Available = true
+		}
+
+		status.Passed = status.Passed && kp.Available
+		status.Kernels = append(status.Kernels, kp)
+	}
+	return status, nil
+}
+
+func sameKernels(status *examplecomv1alpha1.PreflightStatus, images map[string]string) bool {
+	if len(status.Kernels) != len(images) {
+		return false
+	}
+	for _, kp := range status.Kernels {
+		if image, ok := images[kp.KernelVersion]; !ok || image != kp.Image {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatFailures describes the kernels whose driver image is not available.
+func FormatFailures(status *examplecomv1alpha1.PreflightStatus) string {
+	failures := []string{}
+	for _, kp := range status.Kernels {
+		if !kp.Available {
+			failures = append(failures, fmt.Sprintf("%s (%s: %s)", kp.KernelVersion, kp.Image, kp.Message))
+		}
+	}
+	return fmt.Sprintf("driver version %s is not available for kernel(s) %s", status.DriverVersion, strings.Join(failures, ", "))
+}
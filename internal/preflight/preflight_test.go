@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gomock "github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/registry"
+)
+
+const (
+	testKernel         = "5.14.0-70.13.1.el9_0.x86_64"
+	testOtherKernel    = "5.14.0-162.6.1.el9_1.x86_64"
+	testUnmappedKernel = "5.15.0-48-generic"
+)
+
+var _ = Describe("PreflightValidator", func() {
+	var (
+		ctx   context.Context
+		dc    *examplecomv1alpha1.DeviceConfig
+		nodes []*corev1.Node
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace"},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				DriverVersion: "1.0",
+				NodeSelector:  map[string]string{"pool": "a"},
+			},
+		}
+		nodes = []*corev1.Node{
+			makeTestNode("node-a", "a", testKernel),
+			makeTestNode("node-b", "a", testKernel),
+			makeTestNode("node-c", "a", testOtherKernel),
+			makeTestNode("node-d", "a", testUnmappedKernel),
+			makeTestNode("node-e", "b", "5.14.0-1.el9.x86_64"),
+		}
+	})
+
+	Describe("ValidateDriverImages", func() {
+		Context("with a registry stand-in", func() {
+			var srv *httptest.Server
+
+			BeforeEach(func() {
+				srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/v2/org/driver/manifests/1.0-"+testKernel {
+						w.WriteHeader(http.StatusOK)
+						return
+					}
+					w.WriteHeader(http.StatusNotFound)
+				}))
+				dc.Spec.DriverImage = strings.TrimPrefix(srv.URL, "https://") + "/org/driver"
+			})
+
+			AfterEach(func() {
+				srv.Close()
+			})
+
+			It("should check the driver image of every distinct mapped kernel of the selected nodes", func() {
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodes[0], nodes[1], nodes[2], nodes[3], nodes[4]).Build()
+				v := NewValidator(c, registry.NewRegistry(srv.Client()))
+
+				status, err := v.ValidateDriverImages(ctx, dc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Passed).To(BeFalse())
+				Expect(status.DriverVersion).To(Equal("1.0"))
+				Expect(status.Kernels).To(Equal([]examplecomv1alpha1.KernelPreflight{
+					{KernelVersion: testOtherKernel, Image: dc.Spec.DriverImage + ":1.0-" + testOtherKernel, Message: "image not found"},
+					{KernelVersion: testKernel, Image: dc.Spec.DriverImage + ":1.0-" + testKernel, Available: true},
+				}))
+				Expect(FormatFailures(status)).To(Equal(
+					"driver version 1.0 is not available for kernel(s) " + testOtherKernel +
+						" (" + dc.Spec.DriverImage + ":1.0-" + testOtherKernel + ": image not found)",
+				))
+			})
+
+			It("should pass when every driver image is available", func() {
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodes[0], nodes[1]).Build()
+				v := NewValidator(c, registry.NewRegistry(srv.Client()))
+
+				status, err := v.ValidateDriverImages(ctx, dc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Passed).To(BeTrue())
+			})
+		})
+
+		Context("with a previously passed validation", func() {
+			var (
+				r *registry.MockRegistry
+				v Validator
+			)
+
+			BeforeEach(func() {
+				r = registry.NewMockRegistry(gomock.NewController(GinkgoT()))
+				c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodes[0]).Build()
+				v = NewValidator(c, r)
+
+				dc.Spec.DriverImage = "quay.io/org/driver"
+				dc.Status.Preflight = &examplecomv1alpha1.PreflightStatus{
+					DriverImage:   dc.Spec.DriverImage,
+					DriverVersion: dc.Spec.DriverVersion,
+					Passed:        true,
+					Kernels: []examplecomv1alpha1.KernelPreflight{
+						{KernelVersion: testKernel, Image: "quay.io/org/driver:1.0-" + testKernel, Available: true},
+					},
+				}
+			})
+
+			It("should not query the registry again", func() {
+				status, err := v.ValidateDriverImages(ctx, dc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(dc.Status.Preflight))
+			})
+
+			It("should validate a new driver version", func() {
+				dc.Spec.DriverVersion = "2.0"
+				r.EXPECT().ImageExists(ctx, "quay.io/org/driver:2.0-"+testKernel).Return(false, nil)
+
+				status, err := v.ValidateDriverImages(ctx, dc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Passed).To(BeFalse())
+				Expect(status.DriverVersion).To(Equal("2.0"))
+			})
+		})
+	})
+})
+
+func makeTestNode(name, pool, kernel string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KernelVersion: kernel},
+		},
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+
+	manifestMediaTypes = "application/vnd.docker.distribution.manifest.v2+json," +
+		"application/vnd.docker.distribution.manifest.list.v2+json," +
+		"application/vnd.oci.image.manifest.v1+json," +
+		"application/vnd.oci.image.index.v1+json"
+)
+
+//go:generate mockgen -source=registry.go -package=registry -destination=mock_registry.go
+
+// Registry looks up images in container registries through the Docker Registry HTTP API V2.
+type Registry interface {
+	ImageExists(ctx context.Context, image string) (bool, error)
+}
+
+type registry struct {
+	httpClient *http.Client
+}
+
+func NewRegistry(httpClient *http.Client) Registry {
+	return &registry{httpClient: httpClient}
+}
+
+// ImageExists returns true if the manifest of the given image reference can be found in its
+// registry. Registries requiring a token are accessed anonymously.
+func (r *registry) ImageExists(ctx context.Context, image string) (bool, error) {
+	host, repo, tag, err := parseReference(image)
+	if err != nil {
+		return false, err
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repo, tag)
+
+	res, err := r.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return false, err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		token, err := r.getToken(ctx, res.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return false, fmt.Errorf("failed to authenticate to %s: %w", host, err)
+		}
+		if res, err = r.headManifest(ctx, manifestURL, token); err != nil {
+			return false, err
+		}
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %q looking up image %s", res.Status, image)
+	}
+}
+
+func (r *registry) headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestMediaTypes)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest %s: %w", manifestURL, err)
+	}
+	res.Body.Close()
+	return res, nil
+}
+
+// getToken requests an anonymous token from the realm of the given Bearer challenge.
+func (r *registry) getToken(ctx context.Context, challenge string) (string, error) {
+	params := parseChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+	q := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			q.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %q requesting a token", res.Status)
+	}
+
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge returns the parameters of a Bearer WWW-Authenticate challenge.
+func parseChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	scheme, rest, found := strings.Cut(challenge, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+	for _, param := range strings.Split(rest, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if found {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+// parseReference splits an image reference into its registry host, repository and tag or
+// digest, applying the Docker Hub defaults.
+func parseReference(image string) (string, string, string, error) {
+	name, reference := image, "latest"
+	if i := strings.Index(image, "@"); i >= 0 {
+		name, reference = image[:i], image[i+1:]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, reference = image[:i], image[i+1:]
+	}
+	if name == "" || reference == "" {
+		return "", "", "", fmt.Errorf("invalid image reference %q", image)
+	}
+
+	host, repo := dockerHubRegistry, name
+	if first, rest, found := strings.Cut(name, "/"); found &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repo = first, rest
+	}
+	if host == dockerHubRegistry && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return host, repo, reference, nil
+}
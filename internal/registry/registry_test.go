@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testToken = "a-token"
+
+// newTestRegistry starts a registry stand-in serving the manifests of the given tags of the
+// given repository. With requireToken, manifests are only served with an anonymous token.
+func newTestRegistry(repo string, tags []string, requireToken bool) *httptest.Server {
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token": %q}`, testToken)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if requireToken && r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:%s:pull"`, srv.URL, repo))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		for _, tag := range tags {
+			if r.URL.Path == fmt.Sprintf("/v2/%s/manifests/%s", repo, tag) {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	srv = httptest.NewTLSServer(mux)
+	return srv
+}
+
+var _ = Describe("Registry", func() {
+	Describe("ImageExists", func() {
+		var (
+			ctx context.Context
+			srv *httptest.Server
+		)
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		imageRef := func(tag string) string {
+			return fmt.Sprintf("%s/org/driver:%s", strings.TrimPrefix(srv.URL, "https://"), tag)
+		}
+
+		Context("with an anonymous registry", func() {
+			BeforeEach(func() {
+				ctx = context.TODO()
+				srv = newTestRegistry("org/driver", []string{"1.0-5.14.0"}, false)
+			})
+
+			It("should find an existing tag", func() {
+				exists, err := NewRegistry(srv.Client()).ImageExists(ctx, imageRef("1.0-5.14.0"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeTrue())
+			})
+
+			It("should not find a missing tag", func() {
+				exists, err := NewRegistry(srv.Client()).ImageExists(ctx, imageRef("1.0-4.18.0"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeFalse())
+			})
+		})
+
+		Context("with a registry requiring a token", func() {
+			BeforeEach(func() {
+				ctx = context.TODO()
+				srv = newTestRegistry("org/driver", []string{"1.0-5.14.0"}, true)
+			})
+
+			It("should request an anonymous token", func() {
+				exists, err := NewRegistry(srv.Client()).ImageExists(ctx, imageRef("1.0-5.14.0"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeTrue())
+			})
+		})
+
+		Context("with an unreachable registry", func() {
+			BeforeEach(func() {
+				ctx = context.TODO()
+				srv = newTestRegistry("org/driver", nil, false)
+			})
+
+			It("should return an error", func() {
+				ref := imageRef("1.0-5.14.0")
+				srv.Close()
+
+				_, err := NewRegistry(srv.Client()).ImageExists(ctx, ref)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
+
+var _ = DescribeTable("parseReference",
+	func(image, host, repo, reference string) {
+		h, r, ref, err := parseReference(image)
+		Expect(err).ToNot(HaveOccurred())
+		Expect([]string{h, r, ref}).To(Equal([]string{host, repo, reference}))
+	},
+	Entry("a fully qualified image", "quay.io/org/driver:1.0", "quay.io", "org/driver", "1.0"),
+	Entry("a registry with a port", "localhost:5000/driver:1.0", "localhost:5000", "driver", "1.0"),
+	Entry("a Docker Hub library image", "driver:1.0", dockerHubRegistry, "library/driver", "1.0"),
+	Entry("a Docker Hub image", "org/driver", dockerHubRegistry, "org/driver", "latest"),
+	Entry("a digest", "quay.io/org/driver@sha256:abc", "quay.io", "org/driver", "sha256:abc"),
+)
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: resources.go
+
+// Package resources is a generated GoMock package.
+package resources
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+)
+
+// MockVerifier is a mock of Verifier interface.
+type MockVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockVerifierMockRecorder
+}
+
+// MockVerifierMockRecorder is the mock recorder for MockVerifier.
+type MockVerifierMockRecorder struct {
+	mock *MockVerifier
+}
+
+// NewMockVerifier creates a new mock instance.
+func NewMockVerifier(ctrl *gomock.Controller) *MockVerifier {
+	mock := &MockVerifier{ctrl: ctrl}
+	mock.recorder = &MockVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVerifier) EXPECT() *MockVerifierMockRecorder {
+	return m.recorder
+}
+
+// GetResourceShortfalls mocks base method.
+func (m *MockVerifier) GetResourceShortfalls(ctx context.Context, cr v1alpha1.DeviceConfigObject) ([]v1alpha1.NodeResourceShortfall, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResourceShortfalls", ctx, cr)
+	ret0, _ := ret[0].([]v1alpha1.NodeResourceShortfall)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResourceShortfalls indicates an expected call of GetResourceShortfalls.
+func (mr *MockVerifierMockRecorder) GetResourceShortfalls(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceShortfalls", reflect.TypeOf((*MockVerifier)(nil).GetResourceShortfalls), ctx, cr)
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+const (
+	// MaxReportedShortfalls is the maximum number of resource shortfalls reported in the
+	// DeviceConfig status.
+	MaxReportedShortfalls = 10
+
+	// ReasonNotRegistered reports a node which does not advertise the extended resource, i.e.
+	// whose device plugin has not registered with the kubelet.
+	ReasonNotRegistered = "DevicePluginNotRegistered"
+	// ReasonInsufficient reports a node advertising fewer extended resources than expected from
+	// its discovered devices.
+	ReasonInsufficient = "InsufficientDevices"
+)
+
+//go:generate mockgen -source=resources.go -package=resources -destination=mock_resources.go
+
+// Verifier verifies that the extended resource of a DeviceConfig is advertised on the nodes
+// its driver is available on.
+type Verifier interface {
+	GetResourceShortfalls(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) ([]examplecomv1alpha1.NodeResourceShortfall, error)
+}
+
+type verifier struct {
+	client          client.Client
+	driverNamespace string
+}
+
+func NewVerifier(c client.Client, driverNamespace string) Verifier {
+	return &verifier{client: c, driverNamespace: driverNamespace}
+}
+
+// GetResourceShortfalls compares the allocatable extended resource of the nodes with a ready
+// driver pod with the devices discovered on them, and returns the nodes advertising fewer
+// extended resources than expected, sorted by node name. The nodes whose driver is not
+// available yet are left to the rollout.
+func (v *verifier) GetResourceShortfalls(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ []examplecomv1alpha1.NodeResourceShortfall, err error) {
+	ctx, span := tracing.Start(ctx, "resources.GetResourceShortfalls", cr)
+	defer tracing.End(span, &err)
+
+	er := cr.GetSpec().ExtendedResource
+	if er == nil {
+		return nil, nil
+	}
+
+	namespace, err := module.GetModuleNamespace(cr, v.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := module.GetModuleLoaderSelector(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	podList := &v1.PodList{}
+	if err := v.client.List(ctx, podList, client.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list driver pods: %w", err)
+	}
+
+	available := make(map[string]bool)
+	for i := range podList.Items {
+		if p := &podList.Items[i]; p.Spec.NodeName != "" && isPodReady(p) {
+			available[p.Spec.NodeName] = true
+		}
+	}
+	if len(available) == 0 {
+		return nil, nil
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := v.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var shortfalls []examplecomv1alpha1.NodeResourceShortfall
+	for i := range nodeList.Items {
+		n := &nodeList.Items[i]
+		if !available[n.Name] {
+			continue
+		}
+
+		devices, ok := getDeviceCount(n, er.DeviceCountLabel)
+		if !ok {
+			// The expectation of a node without a valid device count is unknown.
+			continue
+		}
+		expected := devices * er.GetCountPerDevice()
+		var allocatable int64
+		if q, ok := n.Status.Allocatable[v1.ResourceName(er.Name)]; ok {
+			allocatable = q.Value()
+		}
+
+		switch {
+		case allocatable == 0 && expected > 0:
+			// The kubelet zeroes the allocatable resources of an unregistered device plugin.
+			shortfalls = append(shortfalls, examplecomv1alpha1.NodeResourceShortfall{
+				Node: n.Name, Reason: ReasonNotRegistered, Expected: expected,
+			})
+		case allocatable < expected:
+			shortfalls = append(shortfalls, examplecomv1alpha1.NodeResourceShortfall{
+				Node: n.Name, Reason: ReasonInsufficient, Expected: expected, Allocatable: allocatable,
+			})
+		}
+	}
+
+	sort.Slice(shortfalls, func(i, j int) bool {
+		return shortfalls[i].Node < shortfalls[j].Node
+	})
+	return shortfalls, nil
+}
+
+// getDeviceCount returns the number of devices discovered on the given node according to its
+// device count label, defaulting to a single device when no label is configured. It returns
+// false if the node lacks the configured label or its value is not a valid count.
+func getDeviceCount(n *v1.Node, label string) (int64, bool) {
+	if label == "" {
+		return 1, true
+	}
+	value, ok := n.Labels[label]
+	if !ok {
+		return 0, false
+	}
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || count < 0 {
+		return 0, false
+	}
+	return count, true
+}
+
+// CountByReason returns the number of resource shortfalls of every reason.
+func CountByReason(shortfalls []examplecomv1alpha1.NodeResourceShortfall) map[string]int {
+	counts := make(map[string]int)
+	for _, s := range shortfalls {
+		counts[s.Reason]++
+	}
+	return counts
+}
+
+// FormatShortfalls describes the given resource shortfalls of the given extended resource,
+// naming up to MaxReportedShortfalls nodes.
+func FormatShortfalls(resource string, shortfalls []examplecomv1alpha1.NodeResourceShortfall) string {
+	reported := shortfalls
+	if len(reported) > MaxReportedShortfalls {
+		reported = reported[:MaxReportedShortfalls]
+	}
+
+	nodes := make([]string, 0, len(reported))
+	for _, s := range reported {
+		if s.Reason == ReasonNotRegistered {
+			nodes = append(nodes, fmt.Sprintf("%s (%s)", s.Node, s.Reason))
+		} else {
+			nodes = append(nodes, fmt.Sprintf("%s (%s, %d/%d)", s.Node, s.Reason, s.Allocatable, s.Expected))
+		}
+	}
+
+	msg := fmt.Sprintf("%s not advertised as expected on %d node(s): %s", resource, len(shortfalls), strings.Join(nodes, ", "))
+	if more := len(shortfalls) - len(reported); more > 0 {
+		msg = fmt.Sprintf("%s and %d more", msg, more)
+	}
+	return msg
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const (
+	testNamespace       = "a-namespace"
+	testDriverNamespace = "driver-namespace"
+	testResource        = "example.com/device"
+	testCountLabel      = "feature.node.kubernetes.io/example-device.count"
+)
+
+var _ = Describe("Verifier", func() {
+	var (
+		ctx context.Context
+		dc  *examplecomv1alpha1.DeviceConfig
+	)
+
+	makeNode := func(name, devices string, allocatable ...string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if devices != "" {
+			n.Labels[testCountLabel] = devices
+		}
+		if len(allocatable) > 0 {
+			n.Status.Allocatable = corev1.ResourceList{testResource: resource.MustParse(allocatable[0])}
+		}
+		return n
+	}
+
+	makePod := func(node string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("driver-%s", node),
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					module.ModuleNameLabel: module.GetModuleName(dc),
+					module.RoleLabel:       module.ModuleLoaderRole,
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		countPerDevice := int32(4)
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				ExtendedResource: &examplecomv1alpha1.ExtendedResourceSpec{
+					Name:             testResource,
+					CountPerDevice:   &countPerDevice,
+					DeviceCountLabel: testCountLabel,
+				},
+			},
+		}
+	})
+
+	Describe("GetResourceShortfalls", func() {
+		It("should report the available nodes advertising fewer extended resources than expected", func() {
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					makeNode("node-a", "2", "8"),
+					makeNode("node-b", "2", "4"),
+					makeNode("node-c", "2"),
+					makeNode("node-d", "2", "0"),
+					makeNode("node-e", "", "4"),
+					makeNode("node-f", "2"),
+					makePod("node-a", true),
+					makePod("node-b", true),
+					makePod("node-c", true),
+					makePod("node-d", true),
+					makePod("node-e", true),
+					makePod("node-f", false),
+				).
+				Build()
+
+			shortfalls, err := NewVerifier(c, testDriverNamespace).GetResourceShortfalls(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shortfalls).To(Equal([]examplecomv1alpha1.NodeResourceShortfall{
+				{Node: "node-b", Reason: ReasonInsufficient, Expected: 8, Allocatable: 4},
+				{Node: "node-c", Reason: ReasonNotRegistered, Expected: 8},
+				{Node: "node-d", Reason: ReasonNotRegistered, Expected: 8},
+			}))
+		})
+
+		It("should not verify the nodes without a valid device count", func() {
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					makeNode("node-a", "", "1"),
+					makeNode("node-b", "garbage", "4"),
+					makeNode("node-c", "-2", "4"),
+					makePod("node-a", true),
+					makePod("node-b", true),
+					makePod("node-c", true),
+				).
+				Build()
+
+			shortfalls, err := NewVerifier(c, testDriverNamespace).GetResourceShortfalls(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shortfalls).To(BeEmpty())
+		})
+
+		It("should expect a single device on every node without a device count label", func() {
+			dc.Spec.ExtendedResource.DeviceCountLabel = ""
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					makeNode("node-a", "", "4"),
+					makeNode("node-b", "", "1"),
+					makePod("node-a", true),
+					makePod("node-b", true),
+				).
+				Build()
+
+			shortfalls, err := NewVerifier(c, testDriverNamespace).GetResourceShortfalls(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shortfalls).To(Equal([]examplecomv1alpha1.NodeResourceShortfall{
+				{Node: "node-b", Reason: ReasonInsufficient, Expected: 4, Allocatable: 1},
+			}))
+		})
+
+		It("should not verify a DeviceConfig without an extended resource", func() {
+			dc.Spec.ExtendedResource = nil
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(makeNode("node-a", "2"), makePod("node-a", true)).
+				Build()
+
+			shortfalls, err := NewVerifier(c, testDriverNamespace).GetResourceShortfalls(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shortfalls).To(BeEmpty())
+		})
+	})
+
+	Describe("FormatShortfalls", func() {
+		It("should name a limited number of nodes", func() {
+			var shortfalls []examplecomv1alpha1.NodeResourceShortfall
+			for i := 0; i < MaxReportedShortfalls+2; i++ {
+				shortfalls = append(shortfalls, examplecomv1alpha1.NodeResourceShortfall{
+					Node: fmt.Sprintf("node-%02d", i), Reason: ReasonInsufficient, Expected: 2, Allocatable: 1,
+				})
+			}
+
+			msg := FormatShortfalls(testResource, shortfalls)
+			Expect(msg).To(HavePrefix("example.com/device not advertised as expected on 12 node(s): node-00 (InsufficientDevices, 1/2)"))
+			Expect(msg).To(HaveSuffix("and 2 more"))
+		})
+	})
+})
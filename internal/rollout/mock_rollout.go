@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rollout.go
+
+// Package rollout is a generated GoMock package.
+package rollout
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	types "k8s.io/apimachinery/pkg/types"
+)
+
+// MockTracker is a mock of Tracker interface.
+type MockTracker struct {
+	ctrl     *gomock.Controller
+	recorder *MockTrackerMockRecorder
+}
+
+// MockTrackerMockRecorder is the mock recorder for MockTracker.
+type MockTrackerMockRecorder struct {
+	mock *MockTracker
+}
+
+// NewMockTracker creates a new mock instance.
+func NewMockTracker(ctrl *gomock.Controller) *MockTracker {
+	mock := &MockTracker{ctrl: ctrl}
+	mock.recorder = &MockTrackerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTracker) EXPECT() *MockTrackerMockRecorder {
+	return m.recorder
+}
+
+// Forget mocks base method.
+func (m *MockTracker) Forget(key types.NamespacedName) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Forget", key)
+}
+
+// Forget indicates an expected call of Forget.
+func (mr *MockTrackerMockRecorder) Forget(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Forget", reflect.TypeOf((*MockTracker)(nil).Forget), key)
+}
+
+// GetProgress mocks base method.
+func (m *MockTracker) GetProgress(ctx context.Context, cr v1alpha1.DeviceConfigObject) (*Progress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProgress", ctx, cr)
+	ret0, _ := ret[0].(*Progress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProgress indicates an expected call of GetProgress.
+func (mr *MockTrackerMockRecorder) GetProgress(ctx, cr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProgress", reflect.TypeOf((*MockTracker)(nil).GetProgress), ctx, cr)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
+)
+
+//go:generate mockgen -source=rollout.go -package=rollout -destination=mock_rollout.go
+
+// Progress is the rollout progress of the driver of a DeviceConfig.
+type Progress struct {
+	// SelectedNodes is the number of nodes selected by the DeviceConfig.
+	SelectedNodes int
+	// DesiredNodes is the number of nodes which should run a ModuleLoader pod.
+	DesiredNodes int
+	// AvailableNodes is the number of nodes running an available ModuleLoader pod.
+	AvailableNodes int
+	// UpdatedNodes is the number of nodes running a ModuleLoader pod of the current Module.
+	UpdatedNodes int
+	// MatchToAvailable holds, for every node whose driver became available since the
+	// previous call, the time elapsed since the node was first seen selected.
+	MatchToAvailable []time.Duration
+	// NodeGroups is the rollout progress of every node group, in the order of the spec.
+	NodeGroups []examplecomv1alpha1.NodeGroupStatus
+}
+
+// UpgradeRatio returns the ratio of the desired nodes running the current driver.
+func (p *Progress) UpgradeRatio() float64 {
+	if p.DesiredNodes == 0 {
+		return 1
+	}
+	return float64(p.UpdatedNodes) / float64(p.DesiredNodes)
+}
+
+// Tracker tracks the rollout of the driver of every DeviceConfig.
+type Tracker interface {
+	GetProgress(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (*Progress, error)
+	Forget(key types.NamespacedName)
+}
+
+type tracker struct {
+	client          client.Client
+	driverNamespace string
+	now             func() time.Time
+
+	mu sync.Mutex
+	// matched maps a DeviceConfig to the time each of its selected nodes was first seen
+	// without an available driver. Nodes whose driver has been available have a zero time.
+	matched map[types.NamespacedName]map[string]time.Time
+}
+
+func NewTracker(c client.Client, driverNamespace string) Tracker {
+	return &tracker{
+		client:          c,
+		driverNamespace: driverNamespace,
+		now:             time.Now,
+		matched:         make(map[types.NamespacedName]map[string]time.Time),
+	}
+}
+
+// GetProgress returns the rollout progress of the given DeviceConfig, based on its selected
+// nodes and on the KMM ModuleLoader DaemonSets and pods of all its Modules.
+func (t *tracker) GetProgress(ctx context.Context, cr examplecomv1alpha1.DeviceConfigObject) (_ *Progress, err error) {
+	ctx, span := tracing.Start(ctx, "rollout.GetProgress", cr)
+	defer tracing.End(span, &err)
+
+	selector, err := cr.GetLabelSelector()
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector for %s: %w", cr.GetName(), err)
+	}
+	namespace, err := module.GetModuleNamespace(cr, t.driverNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := t.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	moduleLoaderLabels, err := module.GetModuleLoaderSelector(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	dsList := &appsv1.DaemonSetList{}
+	if err := t.client.List(ctx, dsList, client.InNamespace(namespace), moduleLoaderLabels); err != nil {
+		return nil, fmt.Errorf("failed to list driver DaemonSets: %w", err)
+	}
+
+	podList := &v1.PodList{}
+	if err := t.client.List(ctx, podList, client.InNamespace(namespace), moduleLoaderLabels); err != nil {
+		return nil, fmt.Errorf("failed to list driver pods: %w", err)
+	}
+
+	progress := &Progress{}
+	for _, ds := range dsList.Items {
+		progress.DesiredNodes += int(ds.Status.DesiredNumberScheduled)
+		progress.AvailableNodes += int(ds.Status.NumberAvailable)
+		// The updated pods of a DaemonSet are only meaningful once its latest spec has
+		// been observed.
+		if ds.Status.ObservedGeneration >= ds.Generation {
+			progress.UpdatedNodes += int(ds.Status.UpdatedNumberScheduled)
+		}
+	}
+
+	available := make(map[string]bool)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != "" && isPodReady(&pod) {
+			available[pod.Spec.NodeName] = true
+		}
+	}
+
+	var selected []string
+	nodeGroups := make(map[string]int)
+	for _, g := range cr.GetSpec().NodeGroups {
+		nodeGroups[g.Name] = len(progress.NodeGroups)
+		progress.NodeGroups = append(progress.NodeGroups, examplecomv1alpha1.NodeGroupStatus{
+			Name:   g.Name,
+			Module: module.GetNodeGroupModuleName(cr, g.Name),
+		})
+	}
+	for _, n := range nodeList.Items {
+		if !selector.Matches(labels.Set(n.Labels)) {
+			continue
+		}
+		selected = append(selected, n.Name)
+
+		// The nodes are counted in the node group they are labelled with, i.e. the group of
+		// the Module selecting them.
+		if i, ok := nodeGroups[n.Labels[cr.GetNodeGroupLabel()]]; ok {
+			progress.NodeGroups[i].SelectedNodes++
+			if available[n.Name] {
+				progress.NodeGroups[i].AvailableNodes++
+			}
+		}
+	}
+	progress.SelectedNodes = len(selected)
+	progress.MatchToAvailable = t.observe(client.ObjectKeyFromObject(cr), selected, available)
+
+	return progress, nil
+}
+
+// observe records the time the given selected nodes were first seen without an available
+// driver and returns the time it took for the driver of the others to become available.
+func (t *tracker) observe(key types.NamespacedName, selected []string, available map[string]bool) []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	previous := t.matched[key]
+	current := make(map[string]time.Time, len(selected))
+
+	var durations []time.Duration
+	for _, node := range selected {
+		matchedAt, seen := previous[node]
+		switch {
+		case available[node]:
+			if seen && !matchedAt.IsZero() {
+				durations = append(durations, now.Sub(matchedAt))
+			}
+			current[node] = time.Time{}
+		case seen:
+			// A driver which becomes unavailable again, e.g. during an upgrade, is not
+			// timed a second time.
+			current[node] = matchedAt
+		default:
+			current[node] = now
+		}
+	}
+	t.matched[key] = current
+
+	return durations
+}
+
+// Forget stops tracking the nodes of the given DeviceConfig.
+func (t *tracker) Forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.matched, key)
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
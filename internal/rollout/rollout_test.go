@@ -0,0 +1,209 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
+	"github.com/mresvanis/he-sample-operator/internal/module"
+)
+
+const testNamespace = "a-namespace"
+
+var _ = Describe("Tracker", func() {
+	var (
+		ctx context.Context
+		dc  *examplecomv1alpha1.DeviceConfig
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		dc = &examplecomv1alpha1.DeviceConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-device-config", Namespace: testNamespace},
+			Spec: examplecomv1alpha1.DeviceConfigSpec{
+				NodeSelector: map[string]string{"pool": "a"},
+			},
+		}
+		now = time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	newTestTracker := func(objs ...client.Object) *tracker {
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build()
+		t := NewTracker(c, "").(*tracker)
+		t.now = func() time.Time { return now }
+		return t
+	}
+
+	Describe("GetProgress", func() {
+		It("should count the selected, desired, available and updated nodes", func() {
+			ds := makeTestDaemonSet(dc, 2, 1, 1)
+			stale := makeTestDaemonSet(dc, 1, 1, 1)
+			stale.Name = "stale"
+			stale.Generation = 2
+
+			t := newTestTracker(
+				makeTestNode("node-a", "a"),
+				makeTestNode("node-b", "a"),
+				makeTestNode("node-c", "a"),
+				makeTestNode("node-d", "b"),
+				ds,
+				stale,
+			)
+
+			progress, err := t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.SelectedNodes).To(Equal(3))
+			Expect(progress.DesiredNodes).To(Equal(3))
+			Expect(progress.AvailableNodes).To(Equal(2))
+			Expect(progress.UpdatedNodes).To(Equal(1))
+			Expect(progress.UpgradeRatio()).To(BeNumerically("~", 1.0/3))
+		})
+
+		It("should aggregate the Modules of the node groups", func() {
+			dc.Spec.NodeGroups = []examplecomv1alpha1.NodeGroup{
+				{Name: "sriov", NodeSelector: map[string]string{"sriov": "true"}},
+			}
+			groupLabel := dc.GetNodeGroupLabel()
+
+			nodeA := makeTestNode("node-a", "a")
+			nodeA.Labels[groupLabel] = examplecomv1alpha1.DefaultNodeGroup
+			nodeB := makeTestNode("node-b", "a")
+			nodeB.Labels[groupLabel] = "sriov"
+			nodeC := makeTestNode("node-c", "a")
+			nodeC.Labels[groupLabel] = "sriov"
+
+			ds := makeTestDaemonSet(dc, 1, 1, 1)
+			groupDS := makeTestDaemonSet(dc, 2, 1, 2)
+			groupDS.Name = "a-group-daemonset"
+			groupDS.Labels[module.ModuleNameLabel] = module.GetNodeGroupModuleName(dc, "sriov")
+			groupPod := makeTestPod(dc, "node-b", true)
+			groupPod.Labels[module.ModuleNameLabel] = module.GetNodeGroupModuleName(dc, "sriov")
+
+			t := newTestTracker(nodeA, nodeB, nodeC, ds, groupDS, makeTestPod(dc, "node-a", true), groupPod)
+
+			progress, err := t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.SelectedNodes).To(Equal(3))
+			Expect(progress.DesiredNodes).To(Equal(3))
+			Expect(progress.AvailableNodes).To(Equal(2))
+			Expect(progress.UpdatedNodes).To(Equal(3))
+			Expect(progress.NodeGroups).To(Equal([]examplecomv1alpha1.NodeGroupStatus{
+				{Name: "sriov", Module: "a-device-config-module-sriov", SelectedNodes: 2, AvailableNodes: 1},
+			}))
+		})
+
+		It("should report the time from node match to driver available once", func() {
+			t := newTestTracker(makeTestNode("node-a", "a"))
+
+			progress, err := t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.MatchToAvailable).To(BeEmpty())
+
+			now = now.Add(time.Minute)
+			Expect(t.client.Create(ctx, makeTestPod(dc, "node-a", true))).To(Succeed())
+
+			progress, err = t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.MatchToAvailable).To(Equal([]time.Duration{time.Minute}))
+
+			now = now.Add(time.Minute)
+			progress, err = t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.MatchToAvailable).To(BeEmpty())
+		})
+
+		It("should not time the nodes whose driver is already available", func() {
+			t := newTestTracker(makeTestNode("node-a", "a"), makeTestPod(dc, "node-a", true))
+
+			progress, err := t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.MatchToAvailable).To(BeEmpty())
+			Expect(t.matched).To(HaveKeyWithValue(client.ObjectKeyFromObject(dc), map[string]time.Time{"node-a": {}}))
+		})
+	})
+
+	Describe("Forget", func() {
+		It("should stop tracking the nodes of the DeviceConfig", func() {
+			t := newTestTracker(makeTestNode("node-a", "a"))
+
+			_, err := t.GetProgress(ctx, dc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t.matched).To(HaveLen(1))
+
+			t.Forget(client.ObjectKeyFromObject(dc))
+			Expect(t.matched).To(BeEmpty())
+		})
+	})
+})
+
+func makeTestNode(name, pool string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}},
+	}
+}
+
+func moduleLoaderLabels(dc examplecomv1alpha1.DeviceConfigObject) map[string]string {
+	return map[string]string{
+		module.ModuleNameLabel: module.GetModuleName(dc),
+		module.RoleLabel:       module.ModuleLoaderRole,
+	}
+}
+
+func makeTestDaemonSet(dc examplecomv1alpha1.DeviceConfigObject, desired, available, updated int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "a-daemonset",
+			Namespace:  dc.GetNamespace(),
+			Labels:     moduleLoaderLabels(dc),
+			Generation: 1,
+		},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     1,
+			DesiredNumberScheduled: desired,
+			NumberAvailable:        available,
+			UpdatedNumberScheduled: updated,
+		},
+	}
+}
+
+func makeTestPod(dc examplecomv1alpha1.DeviceConfigObject, node string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-" + node,
+			Namespace: dc.GetNamespace(),
+			Labels:    moduleLoaderLabels(dc),
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
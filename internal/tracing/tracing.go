@@ -0,0 +1,163 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterStdout writes the spans to the standard output in JSON.
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends the spans to an OTLP/HTTP endpoint, e.g. an OpenTelemetry collector.
+	ExporterOTLP = "otlp"
+
+	// DefaultOTLPEndpoint is the default OTLP/HTTP endpoint of the OpenTelemetry collector.
+	DefaultOTLPEndpoint = "http://localhost:4318"
+
+	// otlpTracesPath is the path of the traces of an OTLP/HTTP endpoint.
+	otlpTracesPath = "v1/traces"
+
+	serviceName = "he-sample-operator"
+	tracerName  = "github.com/mresvanis/he-sample-operator"
+)
+
+const (
+	// DeviceConfigNameKey, DeviceConfigNamespaceKey and DeviceConfigGenerationKey are the
+	// attributes identifying the DeviceConfig or ClusterDeviceConfig of a span.
+	DeviceConfigNameKey       = attribute.Key("deviceconfig.name")
+	DeviceConfigNamespaceKey  = attribute.Key("deviceconfig.namespace")
+	DeviceConfigGenerationKey = attribute.Key("deviceconfig.generation")
+)
+
+// tracer is the operator tracer, which is unset while tracing is disabled.
+var tracer trace.Tracer
+
+// Start starts a span of the operator tracer, tagged with the identity of the given DeviceConfig
+// or ClusterDeviceConfig. While tracing is disabled, the context is returned unchanged along with
+// a no-op span.
+func Start(ctx context.Context, name string, cr metav1.Object) (context.Context, trace.Span) {
+	if tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		DeviceConfigNameKey.String(cr.GetName()),
+		DeviceConfigNamespaceKey.String(cr.GetNamespace()),
+		DeviceConfigGenerationKey.Int64(cr.GetGeneration()),
+	))
+}
+
+// End records the error the given pointer refers to, if any, and ends the span. It is deferred
+// with the named error result of the traced function, e.g. defer tracing.End(span, &err).
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// Setup enables tracing with a tracer provider exporting the spans with the given exporter,
+// unless the exporter is none. The returned function flushes the pending spans and must be called
+// before the operator exits.
+func Setup(exporter, endpoint string) (func(context.Context) error, error) {
+	var (
+		e   sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		e, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		e, err = newOTLPExporter(endpoint)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected one of %s, %s or %s",
+			exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tracing exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(e),
+		sdktrace.WithResource(res),
+	)
+	SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// SetTracerProvider enables tracing with the given tracer provider, which is also installed as
+// the global one, or disables it if the provider is nil. It must be called before the manager is
+// started.
+func SetTracerProvider(tp trace.TracerProvider) {
+	if tp == nil {
+		tracer = nil
+		return
+	}
+
+	otel.SetTracerProvider(tp)
+	tracer = tp.Tracer(tracerName)
+}
+
+// newOTLPExporter returns an exporter sending the spans to the traces path of the given
+// OTLP/HTTP endpoint, e.g. http://otel-collector:4318.
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path.Join("/", u.Path, otlpTracesPath)),
+	}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: the scheme must be http or https", endpoint)
+	}
+
+	return otlptracehttp.New(context.Background(), opts...)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2022.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		ctx      context.Context
+		cr       *metav1.ObjectMeta
+		exporter *tracetest.InMemoryExporter
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		cr = &metav1.ObjectMeta{Name: "a-device-config", Namespace: "a-namespace", Generation: 3}
+		exporter = tracetest.NewInMemoryExporter()
+	})
+
+	AfterEach(func() {
+		SetTracerProvider(nil)
+	})
+
+	enable := func() {
+		SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	}
+
+	Describe("Start", func() {
+		It("should return the context unchanged while tracing is disabled", func() {
+			spanCtx, span := Start(ctx, "a-span", cr)
+			span.End()
+
+			Expect(spanCtx).To(Equal(ctx))
+			Expect(span.IsRecording()).To(BeFalse())
+		})
+
+		It("should tag the spans with the DeviceConfig identity", func() {
+			enable()
+
+			spanCtx, parent := Start(ctx, "parent", cr)
+			_, child := Start(spanCtx, "child", cr)
+			child.End()
+			parent.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name).To(Equal("child"))
+			Expect(spans[0].Parent.SpanID()).To(Equal(spans[1].SpanContext.SpanID()))
+			Expect(spans[1].Attributes).To(ConsistOf(
+				DeviceConfigNameKey.String("a-device-config"),
+				DeviceConfigNamespaceKey.String("a-namespace"),
+				DeviceConfigGenerationKey.Int64(3),
+			))
+		})
+	})
+
+	Describe("End", func() {
+		It("should record the error of the span", func() {
+			enable()
+
+			err := errors.New("something went wrong")
+			_, span := Start(ctx, "a-span", cr)
+			End(span, &err)
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status.Code).To(Equal(codes.Error))
+			Expect(spans[0].Status.Description).To(Equal("something went wrong"))
+			Expect(spans[0].Events).To(HaveLen(1))
+		})
+
+		It("should not record an error when the traced function succeeds", func() {
+			enable()
+
+			var err error
+			_, span := Start(ctx, "a-span", cr)
+			End(span, &err)
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status.Code).To(Equal(codes.Unset))
+			Expect(spans[0].Events).To(BeEmpty())
+		})
+	})
+
+	Describe("Setup", func() {
+		It("should reject an unknown exporter", func() {
+			_, err := Setup("jaeger", "")
+			Expect(err).To(MatchError(ContainSubstring(`unknown tracing exporter "jaeger"`)))
+		})
+
+		It("should not enable tracing with the none exporter", func() {
+			shutdown, err := Setup(ExporterNone, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shutdown(ctx)).To(Succeed())
+
+			_, span := Start(ctx, "a-span", cr)
+			Expect(span.IsRecording()).To(BeFalse())
+		})
+	})
+
+	Describe("Exporters", func() {
+		var spans []sdktrace.ReadOnlySpan
+
+		BeforeEach(func() {
+			enable()
+
+			err := errors.New("something went wrong")
+			_, span := Start(ctx, "a-span", cr)
+			End(span, &err)
+			spans = exporter.GetSpans().Snapshots()
+		})
+
+		It("should send the spans to the traces path of the OTLP/HTTP endpoint", func() {
+			req := &coltracepb.ExportTraceServiceRequest{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/otlp/v1/traces"))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(proto.Unmarshal(body, req)).To(Succeed())
+			}))
+			defer server.Close()
+
+			e, err := newOTLPExporter(server.URL + "/otlp/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.ExportSpans(ctx, spans)).To(Succeed())
+			Expect(e.Shutdown(ctx)).To(Succeed())
+
+			Expect(req.ResourceSpans).To(HaveLen(1))
+			Expect(req.ResourceSpans[0].ScopeSpans).To(HaveLen(1))
+			s := req.ResourceSpans[0].ScopeSpans[0].Spans
+			Expect(s).To(HaveLen(1))
+			Expect(s[0].Name).To(Equal("a-span"))
+			traceID := spans[0].SpanContext().TraceID()
+			Expect(s[0].TraceId).To(Equal(traceID[:]))
+			Expect(s[0].Status.Code).To(Equal(tracepb.Status_STATUS_CODE_ERROR))
+			Expect(s[0].Status.Message).To(Equal("something went wrong"))
+		})
+
+		It("should reject an OTLP endpoint without an HTTP scheme", func() {
+			_, err := newOTLPExporter("otel-collector:4318")
+			Expect(err).To(MatchError(ContainSubstring("the scheme must be http or https")))
+		})
+	})
+})
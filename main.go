@@ -17,37 +17,85 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
+	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	examplecomv1alpha1 "github.com/mresvanis/he-sample-operator/api/v1alpha1"
 	"github.com/mresvanis/he-sample-operator/controllers"
+	"github.com/mresvanis/he-sample-operator/internal/alerts"
+	"github.com/mresvanis/he-sample-operator/internal/backend"
 	"github.com/mresvanis/he-sample-operator/internal/conditions"
+	"github.com/mresvanis/he-sample-operator/internal/config"
+	"github.com/mresvanis/he-sample-operator/internal/dependencies"
+	"github.com/mresvanis/he-sample-operator/internal/driverpods"
 	"github.com/mresvanis/he-sample-operator/internal/finalizers"
+	"github.com/mresvanis/he-sample-operator/internal/firmware"
+	"github.com/mresvanis/he-sample-operator/internal/intree"
+	"github.com/mresvanis/he-sample-operator/internal/kernels"
 	"github.com/mresvanis/he-sample-operator/internal/module"
+	"github.com/mresvanis/he-sample-operator/internal/namespaces"
+	"github.com/mresvanis/he-sample-operator/internal/native"
+	"github.com/mresvanis/he-sample-operator/internal/nodelabels"
 	"github.com/mresvanis/he-sample-operator/internal/nodeselector"
+	"github.com/mresvanis/he-sample-operator/internal/preflight"
+	"github.com/mresvanis/he-sample-operator/internal/registry"
+	"github.com/mresvanis/he-sample-operator/internal/resources"
+	"github.com/mresvanis/he-sample-operator/internal/rollout"
+	"github.com/mresvanis/he-sample-operator/internal/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	// leaderElectionID is the default name of the leader election lease.
+	leaderElectionID = "e436e148.example.com"
+
+	// registryTimeout bounds the driver image lookups of the preflight validation.
+	registryTimeout = 30 * time.Second
+
+	// kmmPollInterval is the interval of the lookups of the KMM Module CRD until it is
+	// installed.
+	kmmPollInterval = 10 * time.Second
+
+	// moduleGCInterval is the interval of the garbage collection of the stale Modules.
+	moduleGCInterval = 10 * time.Minute
+
+	// configPollInterval is the interval of the lookups of the changes of the operator
+	// configuration file.
+	configPollInterval = 10 * time.Second
+)
+
 var (
 	scheme = runtime.NewScheme()
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kmmv1beta1.AddToScheme(scheme))
 
 	utilruntime.Must(examplecomv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
@@ -57,11 +105,28 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var driverNamespace string
+	var defaultBackend string
+	var configFile string
+	var tracingExporter string
+	var tracingEndpoint string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&driverNamespace, "driver-namespace", os.Getenv("OPERATOR_NAMESPACE"),
+		"The namespace of the KMM Modules created for ClusterDeviceConfigs. "+
+			"Defaults to the namespace of the operator.")
+	flag.StringVar(&defaultBackend, "default-backend", backend.KMM,
+		"The backend deploying the driver of the DeviceConfigs which do not set one, i.e. KMM or Native.")
+	flag.StringVar(&configFile, "config", "",
+		"The path of the operator configuration file, e.g. mounted from a ConfigMap. "+
+			"Its manager options replace the manager flags, and its defaults are reloaded whenever it changes.")
+	flag.StringVar(&tracingExporter, "tracing-exporter", tracing.ExporterNone,
+		"The exporter of the reconcile traces, i.e. none, stdout or otlp.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", tracing.DefaultOTLPEndpoint),
+		"The OTLP/HTTP endpoint of the otlp tracing exporter.")
 
 	klog.InitFlags(flag.CommandLine)
 
@@ -73,39 +138,184 @@ func main() {
 
 	setupLogger := logger.WithName("setup")
 
+	shutdownTracing, err := tracing.Setup(tracingExporter, tracingEndpoint)
+	if err != nil {
+		setupLogger.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	watchNamespace, err := getWatchNamespace()
 	if err != nil {
 		setupLogger.Error(err, "unable to get WatchNamespace, "+
 			"the manager will watch and manage resources in all namespaces")
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "e436e148.example.com",
-		Namespace:              watchNamespace,
-	})
+	roleRequirement, err := labels.NewRequirement(module.RoleLabel, selection.In, []string{module.ModuleLoaderRole, firmware.Role, intree.Role})
+	if err != nil {
+		setupLogger.Error(err, "unable to create the driver pods selector")
+		os.Exit(1)
+	}
+	moduleLoaderSelector := labels.NewSelector().Add(*roleRequirement)
+
+	// Only the KMM ModuleLoader, native backend, firmware and in-tree DaemonSets and pods, which
+	// share their role label, are watched, so there is no need to cache every DaemonSet and pod
+	// of the cluster.
+	cacheOptions := cache.Options{
+		SelectorsByObject: cache.SelectorsByObject{
+			&appsv1.DaemonSet{}: {Label: moduleLoaderSelector},
+			&corev1.Pod{}:       {Label: moduleLoaderSelector},
+		},
+	}
+
+	options := ctrl.Options{
+		Scheme:    scheme,
+		NewClient: newClient,
+	}
+
+	var operatorConfig *config.OperatorConfig
+	if configFile == "" {
+		options.MetricsBindAddress = metricsAddr
+		options.Port = 9443
+		options.HealthProbeBindAddress = probeAddr
+		options.LeaderElection = enableLeaderElection
+		options.LeaderElectionID = leaderElectionID
+	} else {
+		operatorConfig, err = config.Load(configFile)
+		if err != nil {
+			setupLogger.Error(err, "unable to load the operator configuration")
+			os.Exit(1)
+		}
+		options, err = options.AndFrom(operatorConfig)
+		if err != nil {
+			setupLogger.Error(err, "unable to apply the operator configuration")
+			os.Exit(1)
+		}
+		if operatorConfig.DriverNamespace != "" {
+			driverNamespace = operatorConfig.DriverNamespace
+		}
+		if operatorConfig.DefaultBackend != "" {
+			defaultBackend = operatorConfig.DefaultBackend
+		}
+		if options.LeaderElectionID == "" {
+			options.LeaderElectionID = leaderElectionID
+		}
+		config.SetDefaults(operatorConfig.Defaults)
+	}
+	// The selectors of the DeviceConfigs without a node selector follow the reloaded defaults.
+	examplecomv1alpha1.DefaultPCIVendorID = func() string {
+		return config.GetDefaults().PCIVendorID
+	}
+
+	// The ClusterDeviceConfig Modules and the driver pods live in the driver namespace, so it
+	// is watched too when the watched namespaces are restricted.
+	watchNamespaces := namespaces.Parse(watchNamespace)
+	if len(watchNamespaces) > 0 {
+		watchNamespaces = namespaces.Parse(append(watchNamespaces, driverNamespace)...)
+		setupLogger.Info("restricting the manager to namespaces", "namespaces", watchNamespaces)
+	}
+	options.NewCache = namespaces.NewCache(watchNamespaces, cacheOptions)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		setupLogger.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+
+	if err := namespaces.Validate(ctx, mgr.GetAPIReader(), watchNamespaces); err != nil {
+		setupLogger.Error(err, "invalid WatchNamespace")
+		os.Exit(1)
+	}
+
 	c := mgr.GetClient()
 	s := mgr.GetScheme()
 
-	mr := module.NewReconciler(c, s)
+	idx := nodeselector.NewIndex()
+	if err := idx.SetupWithInformers(ctx, mgr.GetCache()); err != nil {
+		setupLogger.Error(err, "unable to set up node selector index")
+		os.Exit(1)
+	}
+
+	be, err := backend.NewSelector(defaultBackend, map[string]backend.Backend{
+		backend.KMM:    module.NewReconciler(c, s, mgr.GetEventRecorderFor("module-reconciler"), driverNamespace),
+		backend.Native: native.NewBackend(c, s, driverNamespace),
+	})
+	if err != nil {
+		setupLogger.Error(err, "unable to set up driver backends")
+		os.Exit(1)
+	}
 	fu := finalizers.NewUpdater(c)
-	cu := conditions.NewUpdater(c)
-	nsv := nodeselector.NewValidator(c)
-	dcc := controllers.NewDeviceConfigReconciler(c, s, mgr.GetEventRecorderFor("deviceconfig-controller"), mr, fu, cu, nsv)
+	cu := conditions.NewUpdater(c.Status())
+	nsv := nodeselector.NewValidator(idx)
+	nlu := nodelabels.NewUpdater(c)
+	dpi := driverpods.NewInspector(c, driverNamespace)
+	pv := preflight.NewValidator(c, registry.NewRegistry(&http.Client{Timeout: registryTimeout}))
+	kcr := kernels.NewCoverageReporter(c)
+	rt := rollout.NewTracker(c, driverNamespace)
+	ar := alerts.NewReconciler(c, s, driverNamespace)
+	fr := firmware.NewReconciler(c, s, driverNamespace)
+	itr := intree.NewReconciler(c, s, driverNamespace)
+	rv := resources.NewVerifier(c, driverNamespace)
+	dcc := controllers.NewDeviceConfigReconciler(c, s, mgr.GetEventRecorderFor("deviceconfig-controller"), be, fu, cu, nsv, nlu, dpi, pv, kcr, rt, ar, fr, itr, rv)
 
-	if err = dcc.SetupWithManager(mgr); err != nil {
+	cdcc := controllers.NewClusterDeviceConfigReconciler(c, s, mgr.GetEventRecorderFor("clusterdeviceconfig-controller"), be, fu, cu, nsv, nlu, dpi, pv, kcr, rt, ar, fr, itr, rv)
+
+	if err := dcc.SetupWithManager(ctx, mgr); err != nil {
 		setupLogger.Error(err, "unable to create controller", "controller", "DeviceConfig")
 		os.Exit(1)
 	}
+	if err := cdcc.SetupWithManager(ctx, mgr); err != nil {
+		setupLogger.Error(err, "unable to create controller", "controller", "ClusterDeviceConfig")
+		os.Exit(1)
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLogger.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+
+	// The KMM Modules are only watched once the KMM Module CRD is installed, which may happen
+	// after the operator has started or never when only the native backend is used.
+	kmmWatcher := dependencies.NewKMMWatcher(dc, kmmPollInterval)
+	kmmWatcher.OnAvailable(func() error {
+		if err := dcc.WatchModules(); err != nil {
+			return fmt.Errorf("unable to watch DeviceConfig Modules: %w", err)
+		}
+		return nil
+	})
+	kmmWatcher.OnAvailable(func() error {
+		if err := cdcc.WatchModules(ctx); err != nil {
+			return fmt.Errorf("unable to watch ClusterDeviceConfig Modules: %w", err)
+		}
+		return nil
+	})
+	if err := mgr.Add(kmmWatcher); err != nil {
+		setupLogger.Error(err, "unable to set up KMM watcher")
+		os.Exit(1)
+	}
+	if err := mgr.Add(dependencies.NewKMMStatusReporter(kmmWatcher, c, kmmPollInterval)); err != nil {
+		setupLogger.Error(err, "unable to set up KMM status reporter")
+		os.Exit(1)
+	}
+	if err := mgr.Add(module.NewGarbageCollector(c, driverNamespace, moduleGCInterval)); err != nil {
+		setupLogger.Error(err, "unable to set up Module garbage collector")
+		os.Exit(1)
+	}
+
+	if operatorConfig != nil {
+		// The Modules and DaemonSets are rendered with the new defaults once they are reloaded,
+		// after the selectors of the indexed DeviceConfigs have followed them.
+		configWatcher := config.NewWatcher(configFile, operatorConfig, configPollInterval)
+		configWatcher.OnChange(idx.Resync)
+		configWatcher.OnChange(dcc.Resync)
+		configWatcher.OnChange(cdcc.Resync)
+		if err := mgr.Add(configWatcher); err != nil {
+			setupLogger.Error(err, "unable to set up operator configuration watcher")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -116,17 +326,53 @@ func main() {
 		setupLogger.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// The operator does not depend on KMM when the native backend is the default one.
+	if defaultBackend == backend.KMM {
+		if err := mgr.AddReadyzCheck("kmm", kmmWatcher.ReadyzCheck); err != nil {
+			setupLogger.Error(err, "unable to set up KMM ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLogger.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctx)
+	// The pending spans are flushed even if the manager failed.
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLogger.Error(err, "unable to flush traces")
+	}
+	if err != nil {
 		setupLogger.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
+// newClient returns a client reading the unstructured objects from the cache too, since the
+// KMM Modules are handled as unstructured objects to support several KMM API versions.
+func newClient(c cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+	cl, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader:       c,
+		Client:            cl,
+		UncachedObjects:   uncachedObjects,
+		CacheUnstructured: true,
+	})
+}
+
+// getEnv returns the value of an environment variable, or the given default if it is unset.
+func getEnv(key, defaultValue string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return defaultValue
+}
+
 func getWatchNamespace() (string, error) {
 	// WatchNamespaceEnvVar si the contant for env variable WATCH_NAMESPACE
-	// which specifies the Namespace to watch.
+	// which specifies the comma-separated list of Namespaces to watch.
 	// An empty value means the operator is running with cluster scope.
 	var watchNamespaceEnvVar = "WATCH_NAMESPACE"
 